@@ -0,0 +1,58 @@
+package cache
+
+import (
+	"errors"
+	"fmt"
+	"github.com/gbdubs/ecology/manifests/ecology_manifest"
+	"github.com/gbdubs/ecology/util/flag_validation"
+	"github.com/gbdubs/ecology/util/output"
+	"time"
+)
+
+type CacheCommand struct {
+	EcologyManifest ecology_manifest.EcologyManifest
+	// Only "prune" for now.
+	Action     string
+	MaxAgeDays int
+	MaxSizeMb  int
+}
+
+func (cc CacheCommand) Execute(o *output.Output) (err error) {
+	em := &cc.EcologyManifest
+	if cc.Action != "prune" {
+		err = errors.New(fmt.Sprintf("Unknown cache action %q, usage: ecology cache prune [--max_age_days=N] [--max_size_mb=N]", cc.Action))
+		o.Error(err)
+		return
+	}
+	err = flag_validation.ValidateAll(
+		flag_validation.MaxAgeDays(cc.MaxAgeDays),
+		flag_validation.MaxSizeMb(cc.MaxSizeMb))
+	if err != nil {
+		o.Error(err)
+		return
+	}
+	c := em.BuildCache()
+	if c == nil {
+		err = errors.New("No ecology home, so there is no build cache")
+		o.Error(err)
+		return
+	}
+
+	o.Info("CacheCommand - Pruning %s", c.Dir).Indent()
+	maxAge := time.Duration(cc.MaxAgeDays) * 24 * time.Hour
+	maxBytes := int64(cc.MaxSizeMb) * 1024 * 1024
+	removed, err := c.Prune(maxAge, maxBytes)
+	if err != nil {
+		o.Error(err)
+		return
+	}
+	var freed int64
+	for _, e := range removed {
+		o.Debug("Removed %s, last used %s", e.Key, e.LastUsed.Format(time.RFC3339))
+		freed += e.Size
+	}
+	o.Result("Removed", removed)
+	o.Success("Removed %d builds, freeing %d bytes.", len(removed), freed)
+	o.Dedent().Done()
+	return nil
+}
@@ -0,0 +1,167 @@
+package cache_test
+
+import (
+	"github.com/gbdubs/ecology/commands/cache"
+	"github.com/gbdubs/ecology/commands/command_testing"
+	"github.com/gbdubs/ecology/commands/create_project"
+	"github.com/gbdubs/ecology/commands/push_project"
+	"github.com/gbdubs/ecology/manifests/ecology_manifest"
+	"github.com/gbdubs/ecology/platforms/fake_platform"
+	"github.com/gbdubs/ecology/util/build_cache"
+	"github.com/gbdubs/ecology/util/output"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// age makes every build in the cache look unused for the duration.
+func age(t *testing.T, c *build_cache.Cache, d time.Duration) {
+	paths, _ := filepath.Glob(filepath.Join(c.Dir, "*", "*.zip"))
+	then := time.Now().Add(-d)
+	for _, path := range paths {
+		if err := os.Chtimes(path, then, then); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestPrune(t *testing.T) {
+	h := command_testing.New(t)
+	h.CreateProject("P", "A", "B")
+	h.Push("P")
+	em := h.EcologyManifest()
+	c := em.BuildCache()
+	age(t, c, 48*time.Hour)
+
+	// Nothing is removed without a limit.
+	o := output.NewForTesting()
+	if err := (cache.CacheCommand{EcologyManifest: em, Action: "prune"}).Execute(o); err != nil {
+		t.Fatal(err)
+	}
+	command_testing.AssertOutput(t, o, "success", "Removed 0 builds, freeing 0 bytes.")
+
+	o = output.NewForTesting()
+	if err := (cache.CacheCommand{EcologyManifest: em, Action: "prune", MaxAgeDays: 3}).Execute(o); err != nil {
+		t.Fatal(err)
+	}
+	if removed := o.Results()["Removed"].([]build_cache.Entry); len(removed) != 0 {
+		t.Errorf("Removed result = %v, want builds younger than 3 days kept", removed)
+	}
+
+	o = output.NewForTesting()
+	if err := (cache.CacheCommand{EcologyManifest: em, Action: "prune", MaxAgeDays: 1}).Execute(o); err != nil {
+		t.Fatal(err)
+	}
+	removed := o.Results()["Removed"].([]build_cache.Entry)
+	if len(removed) != 2 {
+		t.Fatalf("Removed result = %v, want both builds", removed)
+	}
+	command_testing.AssertOutput(t, o, "success", "Removed 2 builds")
+	if entries, _ := c.Entries(); len(entries) != 0 {
+		t.Errorf("cache still has %v", entries)
+	}
+}
+
+func TestPruneFailures(t *testing.T) {
+	h := command_testing.New(t)
+	tests := map[string]cache.CacheCommand{
+		`Unknown cache action "clear"`:     {EcologyManifest: h.EcologyManifest(), Action: "clear"},
+		"--max_age_days can't be negative": {EcologyManifest: h.EcologyManifest(), Action: "prune", MaxAgeDays: -1},
+		"--max_size_mb can't be negative":  {EcologyManifest: h.EcologyManifest(), Action: "prune", MaxSizeMb: -1},
+		"No ecology home":                  {EcologyManifest: ecology_manifest.EcologyManifest{}, Action: "prune"},
+	}
+	for message, cc := range tests {
+		o := output.NewForTesting()
+		command_testing.AssertError(t, o, cc.Execute(o), message)
+	}
+}
+
+// built reports whether the push built a lambda rather than reusing a cached
+// build.
+func built(o *output.Output) bool {
+	for _, e := range o.Events() {
+		if e.Message == "LambdaManifest - packageToDeploy - Build" {
+			return true
+		}
+	}
+	return false
+}
+
+func TestPushesReuseCachedBuilds(t *testing.T) {
+	h := command_testing.New(t)
+	h.CreateProject("P", "A")
+	h.CreateProject("Q", "A")
+	h.Push("P")
+	em := h.EcologyManifest()
+	c := em.BuildCache()
+	entries, err := c.Entries()
+	if err != nil || len(entries) != 1 {
+		t.Fatalf("cache has %v, %v, want the build of P's A", entries, err)
+	}
+
+	// The same code in another project is built once.
+	o := output.NewForTesting()
+	err = push_project.PushProjectCommand{
+		EcologyManifest: h.EcologyManifest(),
+		Project:         "Q",
+		Platform:        h.Platform,
+	}.Execute(o)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if built(o) {
+		t.Error("Q's A was built again")
+	}
+	command_testing.AssertOutput(t, o, "success", "Reusing Cached Build "+entries[0].Key)
+	if p, q := h.Platform.Functions["P-A"], h.Platform.Functions["Q-A"]; p.Config.CodeSha256 != q.Config.CodeSha256 {
+		t.Errorf("Q-A's CodeSha256 %s != P-A's %s", q.Config.CodeSha256, p.Config.CodeSha256)
+	}
+
+	// And in another region.
+	err = (&create_project.CreateProjectCommand{
+		EcologyManifest: h.EcologyManifest(),
+		Platform:        "AWS",
+		Region:          "us-east-2",
+		Project:         "R",
+		Path:            h.ProjectPath("R"),
+	}).Execute(output.NewForTesting())
+	if err != nil {
+		t.Fatal(err)
+	}
+	h.CreateLambda("R", "A")
+	o = output.NewForTesting()
+	err = push_project.PushProjectCommand{
+		EcologyManifest: h.EcologyManifest(),
+		Project:         "R",
+		Platform:        fake_platform.New("us-east-2"),
+	}.Execute(o)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if built(o) {
+		t.Error("R's A was built again for us-east-2")
+	}
+	if entries, _ = c.Entries(); len(entries) != 1 {
+		t.Errorf("cache has %v, want only one build", entries)
+	}
+
+	// Changed code is built, and cached alongside.
+	h.WriteSource("Q", "A", strings.Replace(command_testing.LambdaSource, "request.Input=", "Input=", 1))
+	o = output.NewForTesting()
+	err = push_project.PushProjectCommand{
+		EcologyManifest: h.EcologyManifest(),
+		Project:         "Q",
+		Platform:        h.Platform,
+	}.Execute(o)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !built(o) {
+		t.Error("changed code wasn't built")
+	}
+	if entries, _ = c.Entries(); len(entries) != 2 {
+		t.Errorf("cache has %v, want both builds", entries)
+	}
+}
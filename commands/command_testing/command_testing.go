@@ -0,0 +1,201 @@
+package command_testing
+
+import (
+	"github.com/gbdubs/ecology/commands/create_lambda"
+	"github.com/gbdubs/ecology/commands/create_project"
+	"github.com/gbdubs/ecology/commands/push_project"
+	"github.com/gbdubs/ecology/manifests/api_manifest"
+	"github.com/gbdubs/ecology/manifests/ecology_manifest"
+	"github.com/gbdubs/ecology/manifests/lambda_manifest"
+	"github.com/gbdubs/ecology/manifests/project_manifest"
+	"github.com/gbdubs/ecology/platforms/fake_platform"
+	"github.com/gbdubs/ecology/util/output"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const Region = "us-west-2"
+
+// LambdaSource replaces the source that create_lambda writes, which imports
+// aws-lambda-go, so that lambdas build without downloading anything. Its main
+// calls HandleRequest so that changes to it change the built lambda.
+const LambdaSource = `package main
+
+import (
+	"context"
+)
+
+type Request struct {
+	Input string
+}
+
+func HandleRequest(ctx context.Context, request Request) (string, error) {
+	return "request.Input=" + request.Input, nil
+}
+
+func main() {
+	HandleRequest(context.Background(), Request{})
+}
+`
+
+// Home is an ecology home in a temporary directory, whose projects are pushed
+// to a fake platform, for testing commands end to end.
+type Home struct {
+	Dir      string
+	Platform *fake_platform.FakePlatform
+	t        *testing.T
+}
+
+func New(t *testing.T) *Home {
+	return &Home{
+		Dir:      t.TempDir(),
+		Platform: fake_platform.New(Region),
+		t:        t,
+	}
+}
+
+// EcologyManifest reads the ecology manifest as it was last saved, as each
+// command invocation does.
+func (h *Home) EcologyManifest() ecology_manifest.EcologyManifest {
+	h.t.Helper()
+	em, err := ecology_manifest.Get(h.Dir, output.NewForTesting())
+	if err != nil {
+		h.t.Fatal(err)
+	}
+	return em
+}
+
+// ProjectPath is where CreateProject puts the project.
+func (h *Home) ProjectPath(project string) string {
+	return filepath.Join(h.Dir, "projects", project)
+}
+
+// CreateProject creates an AWS project with the given lambdas.
+func (h *Home) CreateProject(project string, lambdas ...string) {
+	h.t.Helper()
+	cpc := &create_project.CreateProjectCommand{
+		EcologyManifest: h.EcologyManifest(),
+		Platform:        "AWS",
+		Region:          Region,
+		Project:         project,
+		Path:            h.ProjectPath(project),
+	}
+	if err := cpc.Execute(output.NewForTesting()); err != nil {
+		h.t.Fatal(err)
+	}
+	for _, lambda := range lambdas {
+		h.CreateLambda(project, lambda)
+	}
+}
+
+// CreateLambda creates a lambda whose source is LambdaSource.
+func (h *Home) CreateLambda(project string, lambda string) {
+	h.t.Helper()
+	clc := create_lambda.CreateLambdaCommand{
+		EcologyManifest: h.EcologyManifest(),
+		Project:         project,
+		Lambda:          lambda,
+	}
+	if err := clc.Execute(output.NewForTesting()); err != nil {
+		h.t.Fatal(err)
+	}
+	h.WriteSource(project, lambda, LambdaSource)
+}
+
+// WriteSource replaces the source of a lambda.
+func (h *Home) WriteSource(project string, lambda string, source string) {
+	h.t.Helper()
+	lm := h.LambdaManifest(project, lambda)
+	if err := ioutil.WriteFile(lm.Config.CodePath, []byte(source), 0644); err != nil {
+		h.t.Fatal(err)
+	}
+}
+
+// Push pushes the project to the fake platform.
+func (h *Home) Push(project string) {
+	h.t.Helper()
+	ppc := push_project.PushProjectCommand{
+		EcologyManifest: h.EcologyManifest(),
+		Project:         project,
+		Platform:        h.Platform,
+	}
+	if err := ppc.Execute(output.NewForTesting()); err != nil {
+		h.t.Fatal(err)
+	}
+}
+
+// ProjectManifest reads the project manifest as it was last saved.
+func (h *Home) ProjectManifest(project string) *project_manifest.ProjectManifest {
+	h.t.Helper()
+	em := h.EcologyManifest()
+	pm, err := em.GetProjectManifest(project)
+	if err != nil {
+		h.t.Fatal(err)
+	}
+	return pm
+}
+
+// LambdaManifest reads the lambda's manifest as it was last saved.
+func (h *Home) LambdaManifest(project string, lambda string) *lambda_manifest.LambdaManifest {
+	h.t.Helper()
+	lm, err := h.ProjectManifest(project).GetLambdaManifest(lambda)
+	if err != nil {
+		h.t.Fatal(err)
+	}
+	return lm
+}
+
+// UpdateProjectManifest changes the project manifest and saves it, as a user
+// editing it would.
+func (h *Home) UpdateProjectManifest(project string, change func(pm *project_manifest.ProjectManifest)) {
+	h.t.Helper()
+	em := h.EcologyManifest()
+	pm, err := em.GetProjectManifestForUpdate(project)
+	if err != nil {
+		h.t.Fatal(err)
+	}
+	defer pm.Unlock()
+	change(pm)
+	if err = pm.Save(output.NewForTesting()); err != nil {
+		h.t.Fatal(err)
+	}
+}
+
+// AddRoute routes requests to the lambda through the project's API, which the
+// next push creates.
+func (h *Home) AddRoute(project string, method string, path string, lambda string) {
+	h.t.Helper()
+	h.UpdateProjectManifest(project, func(pm *project_manifest.ProjectManifest) {
+		pm.ApiManifest.Config.Routes = append(pm.ApiManifest.Config.Routes, api_manifest.ApiRouteConfig{
+			Method: method,
+			Path:   path,
+			Lambda: lambda,
+		})
+	})
+}
+
+// AssertOutput fails the test unless a line of the output at the level, such
+// as "success" or "failure", contains the message.
+func AssertOutput(t *testing.T, o *output.Output, level string, message string) {
+	t.Helper()
+	lines := make([]string, 0)
+	for _, e := range o.Events() {
+		if e.Level == level && strings.Contains(e.Message, message) {
+			return
+		}
+		lines = append(lines, e.Level+": "+e.Message)
+	}
+	t.Fatalf("no %s line containing %q in the output:\n%s", level, message, strings.Join(lines, "\n"))
+}
+
+// AssertError fails the test unless err contains the message, and the output
+// reported it as a failure.
+func AssertError(t *testing.T, o *output.Output, err error, message string) {
+	t.Helper()
+	if err == nil || !strings.Contains(err.Error(), message) {
+		t.Fatalf("got error %v, want one containing %q", err, message)
+	}
+	AssertOutput(t, o, "failure", message)
+}
@@ -15,16 +15,28 @@ type CreateLambdaCommand struct {
 	EcologyManifest ecology_manifest.EcologyManifest
 	Project         string
 	Lambda          string
+	// Default to lambda_manifest.DefaultRuntime and DefaultArchitecture.
+	Runtime         string
+	Architecture    string
 }
 
 func (clc CreateLambdaCommand) Execute(o *output.Output) error {
 	em := &clc.EcologyManifest
-	pm, err := em.GetProjectManifest(clc.Project)
+	pm, err := em.GetProjectManifestForUpdate(clc.Project)
+	defer pm.Unlock()
+	if clc.Runtime == "" {
+		clc.Runtime = lambda_manifest.DefaultRuntime
+	}
+	if clc.Architecture == "" {
+		clc.Architecture = lambda_manifest.DefaultArchitecture
+	}
 	err = flag_validation.ValidateAll(
 		flag_validation.Project(clc.Project),
 		flag_validation.ProjectExists(clc.Project, em),
 		flag_validation.Lambda(clc.Lambda),
 		flag_validation.LambdaDoesNotExist(clc.Lambda, pm),
+		flag_validation.Runtime(clc.Runtime),
+		flag_validation.Architecture(clc.Architecture),
 		err)
 	if err != nil {
 		o.Error(err)
@@ -39,6 +51,8 @@ func (clc CreateLambdaCommand) Execute(o *output.Output) error {
 		clc.Lambda,
 		pm.Deploy.Platform,
 		pm.Deploy.Region,
+		clc.Runtime,
+		clc.Architecture,
 		o)
 	if err != nil {
 		o.Error(err)
@@ -68,6 +82,7 @@ func (clc CreateLambdaCommand) Execute(o *output.Output) error {
 		return err
 	}
 	o.Dedent().Done()
+	o.Result("Lambda", lm.Summary())
 	return nil
 }
 
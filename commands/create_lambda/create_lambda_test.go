@@ -0,0 +1,60 @@
+package create_lambda_test
+
+import (
+	"github.com/gbdubs/ecology/commands/command_testing"
+	"github.com/gbdubs/ecology/commands/create_lambda"
+	"github.com/gbdubs/ecology/manifests/lambda_manifest"
+	"github.com/gbdubs/ecology/util/output"
+	"io/ioutil"
+	"strings"
+	"testing"
+)
+
+func TestCreateLambda(t *testing.T) {
+	h := command_testing.New(t)
+	h.CreateProject("P")
+	o := output.NewForTesting()
+	err := create_lambda.CreateLambdaCommand{
+		EcologyManifest: h.EcologyManifest(),
+		Project:         "P",
+		Lambda:          "A",
+		Architecture:    "arm64",
+	}.Execute(o)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lm := h.LambdaManifest("P", "A")
+	if lm.Config.FullyQualifiedName != "P-A" || lm.Config.Runtime != lambda_manifest.DefaultRuntime || lm.Config.Architecture != "arm64" || lm.IsDeployed() {
+		t.Errorf("saved lambda manifest = %+v", lm.Config)
+	}
+	source, err := ioutil.ReadFile(lm.Config.CodePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(source), "type ARequest struct") {
+		t.Errorf("source =\n%s", source)
+	}
+	if summary := o.Results()["Lambda"].(lambda_manifest.LambdaSummary); summary.FunctionName != "P-A" {
+		t.Errorf("Lambda result = %+v", summary)
+	}
+	command_testing.AssertOutput(t, o, "info", "CreateLambdaCommand - P.Save")
+}
+
+func TestCreateLambdaFailures(t *testing.T) {
+	h := command_testing.New(t)
+	h.CreateProject("P", "A")
+	tests := map[string]create_lambda.CreateLambdaCommand{
+		"--project=Q doesn't exist":  {Project: "Q", Lambda: "B"},
+		"--lambda=A already exists":  {Project: "P", Lambda: "A"},
+		"Must set --lambda":          {Project: "P"},
+		"--runtime should be one of": {Project: "P", Lambda: "B", Runtime: "go1.x"},
+	}
+	for message, clc := range tests {
+		o := output.NewForTesting()
+		clc.EcologyManifest = h.EcologyManifest()
+		command_testing.AssertError(t, o, clc.Execute(o), message)
+	}
+	if n := len(h.ProjectManifest("P").LambdaManifests); n != 1 {
+		t.Errorf("project has %d lambdas, want 1", n)
+	}
+}
@@ -1,11 +1,12 @@
 package create_project
 
 import (
-	"github.com/gbdubs/ecology/manifests/lambda_manifest"
 	"github.com/gbdubs/ecology/manifests/ecology_manifest"
+	"github.com/gbdubs/ecology/manifests/lambda_manifest"
 	"github.com/gbdubs/ecology/manifests/project_manifest"
 	"github.com/gbdubs/ecology/util/flag_validation"
 	"github.com/gbdubs/ecology/util/output"
+	"path/filepath"
 )
 
 type CreateProjectCommand struct {
@@ -18,9 +19,12 @@ type CreateProjectCommand struct {
 
 func (cpc *CreateProjectCommand) Execute(o *output.Output) (err error) {
 	em := &cpc.EcologyManifest
+	if cpc.Path == "" && em.Config.ProjectsDir != "" {
+		cpc.Path = filepath.Join(em.Config.ProjectsDir, cpc.Project)
+	}
 	err = flag_validation.ValidateAll(
 		flag_validation.Platform(cpc.Platform),
-		flag_validation.Region(cpc.Region),
+		flag_validation.Region(cpc.Platform, cpc.Region),
 		flag_validation.Project(cpc.Project),
 		flag_validation.ProjectDoesNotExist(cpc.Project, em),
 		flag_validation.Path(cpc.Path))
@@ -30,6 +34,7 @@ func (cpc *CreateProjectCommand) Execute(o *output.Output) (err error) {
 	}
 	o.Info("CreateProjectCommand").Indent()
 	manifest := project_manifest.ProjectManifest{
+		SchemaVersion: project_manifest.SchemaVersion,
 		Config: project_manifest.ProjectConfigInfo{
 			Name:         cpc.Project,
 			ManifestPath: cpc.Path + "/project.ecology.json",
@@ -40,17 +45,20 @@ func (cpc *CreateProjectCommand) Execute(o *output.Output) (err error) {
 		},
 		LambdaManifests: make([]lambda_manifest.LambdaManifest, 0),
 	}
+	manifest.SetLocker(em.Locker())
 	err = manifest.Save(o)
 	if err != nil {
 		o.Error(err)
 		return err
 	}
-	em.ProjectManifestPaths[cpc.Project] = manifest.Config.ManifestPath
-	em.Save(o)
+	err = em.Update(o, func(em *ecology_manifest.EcologyManifest) {
+		em.ProjectManifestPaths[cpc.Project] = manifest.Config.ManifestPath
+	})
 	if err != nil {
 		o.Error(err)
 		return err
 	}
 	o.Dedent().Done()
+	o.Result("Project", manifest.Summary())
 	return
 }
@@ -0,0 +1,75 @@
+package create_project_test
+
+import (
+	"github.com/gbdubs/ecology/commands/command_testing"
+	"github.com/gbdubs/ecology/commands/create_project"
+	"github.com/gbdubs/ecology/manifests/project_manifest"
+	"github.com/gbdubs/ecology/util/output"
+	"path/filepath"
+	"testing"
+)
+
+func TestCreateProject(t *testing.T) {
+	h := command_testing.New(t)
+	path := filepath.Join(h.Dir, "P")
+	o := output.NewForTesting()
+	cpc := &create_project.CreateProjectCommand{
+		EcologyManifest: h.EcologyManifest(),
+		Platform:        "AWS",
+		Region:          "us-east-1",
+		Project:         "P",
+		Path:            path,
+	}
+	if err := cpc.Execute(o); err != nil {
+		t.Fatal(err)
+	}
+	em := h.EcologyManifest()
+	if got := em.ProjectManifestPaths["P"]; got != filepath.Join(path, "project.ecology.json") {
+		t.Errorf("ecology manifest has P at %q", got)
+	}
+	pm := h.ProjectManifest("P")
+	if pm.Config.Name != "P" || pm.Deploy.Platform != "AWS" || pm.Deploy.Region != "us-east-1" || len(pm.LambdaManifests) != 0 {
+		t.Errorf("saved project manifest = %+v", pm)
+	}
+	if summary := o.Results()["Project"].(project_manifest.ProjectSummary); summary.Name != "P" || summary.Region != "us-east-1" {
+		t.Errorf("Project result = %+v", summary)
+	}
+	command_testing.AssertOutput(t, o, "success", "Done.")
+}
+
+func TestCreateProjectInProjectsDir(t *testing.T) {
+	h := command_testing.New(t)
+	em := h.EcologyManifest()
+	em.Config.ProjectsDir = filepath.Join(h.Dir, "projects")
+	cpc := &create_project.CreateProjectCommand{
+		EcologyManifest: em,
+		Platform:        "AWS",
+		Region:          "us-west-2",
+		Project:         "P",
+	}
+	if err := cpc.Execute(output.NewForTesting()); err != nil {
+		t.Fatal(err)
+	}
+	if got := h.ProjectManifest("P").Config.ManifestPath; got != filepath.Join(h.Dir, "projects", "P", "project.ecology.json") {
+		t.Errorf("project manifest saved at %s", got)
+	}
+}
+
+func TestCreateProjectFailures(t *testing.T) {
+	h := command_testing.New(t)
+	h.CreateProject("P")
+	tests := map[string]create_project.CreateProjectCommand{
+		"--project=P already exists": {Platform: "AWS", Region: "us-west-2", Project: "P", Path: filepath.Join(h.Dir, "other")},
+		"Folder already exists":      {Platform: "AWS", Region: "us-west-2", Project: "Q", Path: h.Dir},
+		"Must set --path":            {Platform: "AWS", Region: "us-west-2", Project: "Q"},
+		"--project can only contain": {Platform: "AWS", Region: "us-west-2", Project: "Q-1", Path: filepath.Join(h.Dir, "Q")},
+	}
+	for message, cpc := range tests {
+		o := output.NewForTesting()
+		cpc.EcologyManifest = h.EcologyManifest()
+		command_testing.AssertError(t, o, cpc.Execute(o), message)
+	}
+	if em := h.EcologyManifest(); len(em.ProjectManifestPaths) != 1 {
+		t.Errorf("projects = %v, want only P", em.ProjectManifestPaths)
+	}
+}
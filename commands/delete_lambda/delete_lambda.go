@@ -1,20 +1,27 @@
 package delete_lambda
 
 import (
+	"errors"
+	"fmt"
 	"github.com/gbdubs/ecology/manifests/ecology_manifest"
+	"github.com/gbdubs/ecology/platforms/platform"
+	"github.com/gbdubs/ecology/platforms/platform_factory"
 	"github.com/gbdubs/ecology/util/flag_validation"
 	"github.com/gbdubs/ecology/util/output"
+	"strings"
 )
 
 type DeleteLambdaCommand struct {
 	EcologyManifest ecology_manifest.EcologyManifest
 	Project         string
 	Lambda          string
+	Platform        platform.Platform
 }
 
 func (dlc DeleteLambdaCommand) Execute(o *output.Output) (err error) {
 	em := &dlc.EcologyManifest
-	pm, err := em.GetProjectManifest(dlc.Project)
+	pm, err := em.GetProjectManifestForUpdate(dlc.Project)
+	defer pm.Unlock()
 	err = flag_validation.ValidateAll(
 		flag_validation.Project(dlc.Project),
 		flag_validation.ProjectExists(dlc.Project, em),
@@ -25,10 +32,23 @@ func (dlc DeleteLambdaCommand) Execute(o *output.Output) (err error) {
 		o.Error(err)
 		return err
 	}
+	if routeKeys := pm.ApiManifest.RoutesTo(dlc.Lambda); len(routeKeys) > 0 {
+		err = errors.New(fmt.Sprintf("--lambda=%s is still the target of routes %s", dlc.Lambda, strings.Join(routeKeys, ", ")))
+		o.Error(err)
+		return err
+	}
 	lm, err := pm.GetLambdaManifest(dlc.Lambda)
+	p := dlc.Platform
+	if p == nil {
+		p, err = platform_factory.New(pm.Deploy.Platform, pm.Deploy.Region)
+		if err != nil {
+			o.Error(err)
+			return
+		}
+	}
 
 	o.Info("DeleteLambdaCommand - %s.DeleteFromPlatform", dlc.Lambda).Indent()
-	err = lm.DeleteFromPlatform(o)
+	err = lm.DeleteFromPlatform(p, o)
 	if err != nil {
 		o.Error(err)
 		return
@@ -47,5 +67,6 @@ func (dlc DeleteLambdaCommand) Execute(o *output.Output) (err error) {
 		return
 	}
 	o.Dedent().Done()
+	o.Result("Lambda", dlc.Lambda)
 	return nil
 }
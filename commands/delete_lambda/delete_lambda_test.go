@@ -0,0 +1,99 @@
+package delete_lambda_test
+
+import (
+	"github.com/gbdubs/ecology/commands/command_testing"
+	"github.com/gbdubs/ecology/commands/delete_lambda"
+	"github.com/gbdubs/ecology/platforms/fake_platform"
+	"github.com/gbdubs/ecology/util/output"
+	"net/http"
+	"testing"
+)
+
+func TestDeleteLambda(t *testing.T) {
+	h := command_testing.New(t)
+	h.CreateProject("P", "A", "B")
+	h.Push("P")
+	o := output.NewForTesting()
+	err := delete_lambda.DeleteLambdaCommand{
+		EcologyManifest: h.EcologyManifest(),
+		Project:         "P",
+		Lambda:          "A",
+		Platform:        h.Platform,
+	}.Execute(o)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := h.Platform.Functions["P-A"]; ok {
+		t.Error("P-A is still on the platform")
+	}
+	if _, ok := h.Platform.Roles["P-A-executor"]; ok {
+		t.Error("P-A-executor is still on the platform")
+	}
+	if _, ok := h.Platform.Functions["P-B"]; !ok {
+		t.Error("P-B was deleted too")
+	}
+	pm := h.ProjectManifest("P")
+	if len(pm.LambdaManifests) != 1 || pm.LambdaManifests[0].Config.Name != "B" {
+		t.Errorf("saved lambdas = %+v, want only B", pm.LambdaManifests)
+	}
+	if got := o.Results()["Lambda"]; got != "A" {
+		t.Errorf("Lambda result = %v", got)
+	}
+	command_testing.AssertOutput(t, o, "success", "Deleted Successfully.")
+}
+
+func TestDeleteLambdaResumesAfterFailure(t *testing.T) {
+	h := command_testing.New(t)
+	h.CreateProject("P", "A")
+	h.Push("P")
+	h.Platform.FailNext("DeleteFunction", fake_platform.NewServiceError("ServiceException", http.StatusInternalServerError, "injected"))
+	dlc := delete_lambda.DeleteLambdaCommand{
+		EcologyManifest: h.EcologyManifest(),
+		Project:         "P",
+		Lambda:          "A",
+		Platform:        h.Platform,
+	}
+	o := output.NewForTesting()
+	command_testing.AssertError(t, o, dlc.Execute(o), "injected")
+	if _, ok := h.Platform.Functions["P-A"]; !ok {
+		t.Fatal("P-A was deleted")
+	}
+	if lm := h.LambdaManifest("P", "A"); !lm.IsDeployed() {
+		t.Errorf("saved manifest = %+v, want A still deployed", lm.Deploy)
+	}
+
+	// The role is already gone, which a rerun tolerates.
+	o = output.NewForTesting()
+	dlc.EcologyManifest = h.EcologyManifest()
+	if err := dlc.Execute(o); err != nil {
+		t.Fatal(err)
+	}
+	command_testing.AssertOutput(t, o, "warning", "Role P-A-executor was already gone from the platform.")
+	if len(h.Platform.Functions) != 0 || len(h.ProjectManifest("P").LambdaManifests) != 0 {
+		t.Error("A wasn't deleted by the rerun")
+	}
+}
+
+func TestDeleteLambdaFailures(t *testing.T) {
+	h := command_testing.New(t)
+	h.CreateProject("P", "A")
+	h.AddRoute("P", "GET", "/a", "A")
+	h.Push("P")
+	tests := map[string]string{
+		"--lambda=Z doesn't exist":                        "Z",
+		"--lambda=A is still the target of routes GET /a": "A",
+	}
+	for message, lambda := range tests {
+		o := output.NewForTesting()
+		err := delete_lambda.DeleteLambdaCommand{
+			EcologyManifest: h.EcologyManifest(),
+			Project:         "P",
+			Lambda:          lambda,
+			Platform:        h.Platform,
+		}.Execute(o)
+		command_testing.AssertError(t, o, err, message)
+	}
+	if _, ok := h.Platform.Functions["P-A"]; !ok || len(h.ProjectManifest("P").LambdaManifests) != 1 {
+		t.Error("A was deleted")
+	}
+}
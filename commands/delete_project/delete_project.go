@@ -2,6 +2,8 @@ package delete_project
 
 import (
 	"github.com/gbdubs/ecology/manifests/ecology_manifest"
+	"github.com/gbdubs/ecology/platforms/platform"
+	"github.com/gbdubs/ecology/platforms/platform_factory"
 	"github.com/gbdubs/ecology/util/flag_validation"
 	"github.com/gbdubs/ecology/util/output"
 )
@@ -9,6 +11,7 @@ import (
 type DeleteProjectCommand struct {
 	EcologyManifest ecology_manifest.EcologyManifest
 	Project         string
+	Platform        platform.Platform
 }
 
 func (dpc DeleteProjectCommand) Execute(o *output.Output) (err error) {
@@ -21,10 +24,23 @@ func (dpc DeleteProjectCommand) Execute(o *output.Output) (err error) {
 		o.Error(err)
 		return err
 	}
-	pm, err := em.GetProjectManifest(dpc.Project)
+	pm, err := em.GetProjectManifestForUpdate(dpc.Project)
+	if err != nil {
+		o.Error(err)
+		return
+	}
+	defer pm.Unlock()
+	p := dpc.Platform
+	if p == nil {
+		p, err = platform_factory.New(pm.Deploy.Platform, pm.Deploy.Region)
+		if err != nil {
+			o.Error(err)
+			return
+		}
+	}
 
 	o.Info("DeleteProjectCommand - %s.DeleteFromPlatform", dpc.Project).Indent()
-	err = pm.DeleteFromPlatform(o)
+	err = pm.DeleteFromPlatform(p, o)
 	if err != nil {
 		o.Error(err)
 		return
@@ -38,5 +54,6 @@ func (dpc DeleteProjectCommand) Execute(o *output.Output) (err error) {
 		return
 	}
 	o.Dedent().Done()
+	o.Result("Project", pm.Summary())
 	return nil
 }
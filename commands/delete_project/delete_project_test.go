@@ -0,0 +1,84 @@
+package delete_project_test
+
+import (
+	"github.com/gbdubs/ecology/commands/command_testing"
+	"github.com/gbdubs/ecology/commands/delete_project"
+	"github.com/gbdubs/ecology/manifests/project_manifest"
+	"github.com/gbdubs/ecology/platforms/fake_platform"
+	"github.com/gbdubs/ecology/util/output"
+	"net/http"
+	"testing"
+)
+
+func TestDeleteProject(t *testing.T) {
+	h := command_testing.New(t)
+	h.CreateProject("P", "A", "B")
+	h.AddRoute("P", "GET", "/a", "A")
+	h.Push("P")
+	o := output.NewForTesting()
+	err := delete_project.DeleteProjectCommand{
+		EcologyManifest: h.EcologyManifest(),
+		Project:         "P",
+		Platform:        h.Platform,
+	}.Execute(o)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(h.Platform.Functions) != 0 || len(h.Platform.Roles) != 0 || len(h.Platform.Apis) != 0 {
+		t.Errorf("left on the platform: %v %v %v", h.Platform.Functions, h.Platform.Roles, h.Platform.Apis)
+	}
+	pm := h.ProjectManifest("P")
+	if len(pm.LambdaManifests) != 0 || pm.ApiManifest.IsDeployed() {
+		t.Errorf("saved manifest = %+v, want nothing deployed", pm)
+	}
+	if summary := o.Results()["Project"].(project_manifest.ProjectSummary); len(summary.Lambdas) != 0 {
+		t.Errorf("Project result = %+v", summary)
+	}
+	command_testing.AssertOutput(t, o, "info", "DeleteProjectCommand - P.Save")
+}
+
+func TestDeleteProjectSavesProgress(t *testing.T) {
+	h := command_testing.New(t)
+	h.CreateProject("P", "A", "B")
+	h.Push("P")
+	// A is deleted, and then deleting B fails.
+	h.Platform.FailNext("DeleteFunction", nil)
+	h.Platform.FailNext("DeleteFunction", fake_platform.NewServiceError("ServiceException", http.StatusInternalServerError, "injected"))
+	o := output.NewForTesting()
+	err := delete_project.DeleteProjectCommand{
+		EcologyManifest: h.EcologyManifest(),
+		Project:         "P",
+		Platform:        h.Platform,
+	}.Execute(o)
+	command_testing.AssertError(t, o, err, "injected")
+	pm := h.ProjectManifest("P")
+	if len(pm.LambdaManifests) != 1 || pm.LambdaManifests[0].Config.Name != "B" {
+		t.Errorf("saved lambdas = %+v, want only B left", pm.LambdaManifests)
+	}
+}
+
+func TestDeleteProjectFailures(t *testing.T) {
+	h := command_testing.New(t)
+	h.CreateProject("P", "A")
+	h.AddRoute("P", "GET", "/a", "A")
+	h.Push("P")
+	o := output.NewForTesting()
+	err := delete_project.DeleteProjectCommand{
+		EcologyManifest: h.EcologyManifest(),
+		Project:         "Q",
+		Platform:        h.Platform,
+	}.Execute(o)
+	command_testing.AssertError(t, o, err, "--project=Q doesn't exist")
+
+	h.Platform.FailNext("DeleteApi", fake_platform.NewServiceError("TooManyRequestsException", http.StatusTooManyRequests, "injected"))
+	o = output.NewForTesting()
+	err = delete_project.DeleteProjectCommand{
+		EcologyManifest: h.EcologyManifest(),
+		Project:         "P",
+		Platform:        h.Platform,
+	}.Execute(o)
+	command_testing.AssertError(t, o, err, "injected")
+	if pm := h.ProjectManifest("P"); !pm.ApiManifest.IsDeployed() || len(pm.LambdaManifests) != 1 {
+		t.Errorf("saved manifest = %+v, want nothing deleted", pm)
+	}
+}
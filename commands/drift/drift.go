@@ -0,0 +1,60 @@
+package drift
+
+import (
+	"errors"
+	"fmt"
+	"github.com/gbdubs/ecology/manifests/ecology_manifest"
+	"github.com/gbdubs/ecology/platforms/platform"
+	"github.com/gbdubs/ecology/platforms/platform_factory"
+	"github.com/gbdubs/ecology/util/flag_validation"
+	"github.com/gbdubs/ecology/util/output"
+)
+
+type DriftCommand struct {
+	EcologyManifest ecology_manifest.EcologyManifest
+	Project         string
+	Platform        platform.Platform
+}
+
+// Execute returns an error if any drift was found, so that callers (and CI
+// jobs, through the exit code) can tell drift apart from a clean project.
+func (dc DriftCommand) Execute(o *output.Output) (err error) {
+	em := &dc.EcologyManifest
+	err = flag_validation.ValidateAll(
+		flag_validation.Project(dc.Project),
+		flag_validation.ProjectExists(dc.Project, em),
+		err)
+	if err != nil {
+		o.Error(err)
+		return err
+	}
+	pm, err := em.GetProjectManifest(dc.Project)
+	p := dc.Platform
+	if p == nil {
+		p, err = platform_factory.New(pm.Deploy.Platform, pm.Deploy.Region)
+		if err != nil {
+			o.Error(err)
+			return
+		}
+	}
+
+	o.Info("DriftCommand - %s.Drift", dc.Project).Indent()
+	drift, err := pm.Drift(p, o)
+	if err != nil {
+		o.Error(err)
+		return
+	}
+	o.Dedent().Done()
+	o.Result("Drift", drift)
+
+	if len(drift) == 0 {
+		o.Success("DriftCommand - Project %s matches the platform.", dc.Project)
+		return nil
+	}
+	o.Failure("DriftCommand - %d out-of-band changes found:", len(drift)).Indent()
+	for _, d := range drift {
+		o.Failure("%s", d)
+	}
+	o.Dedent()
+	return errors.New(fmt.Sprintf("Project %s has drifted from its manifest", dc.Project))
+}
@@ -0,0 +1,91 @@
+package drift_test
+
+import (
+	"github.com/gbdubs/ecology/commands/command_testing"
+	"github.com/gbdubs/ecology/commands/drift"
+	"github.com/gbdubs/ecology/platforms/fake_platform"
+	"github.com/gbdubs/ecology/util/output"
+	"net/http"
+	"testing"
+)
+
+func TestNoDrift(t *testing.T) {
+	h := command_testing.New(t)
+	h.CreateProject("P", "A")
+	h.Push("P")
+	o := output.NewForTesting()
+	err := drift.DriftCommand{
+		EcologyManifest: h.EcologyManifest(),
+		Project:         "P",
+		Platform:        h.Platform,
+	}.Execute(o)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := o.Results()["Drift"].([]string); len(got) != 0 {
+		t.Errorf("Drift result = %v", got)
+	}
+	command_testing.AssertOutput(t, o, "success", "DriftCommand - Project P matches the platform.")
+}
+
+func TestDrift(t *testing.T) {
+	h := command_testing.New(t)
+	h.CreateProject("P", "A", "B")
+	h.Push("P")
+	h.Platform.Functions["P-A"].Config.Handler = "changed"
+	delete(h.Platform.Functions, "P-B")
+	before := h.ProjectManifest("P")
+	o := output.NewForTesting()
+	err := drift.DriftCommand{
+		EcologyManifest: h.EcologyManifest(),
+		Project:         "P",
+		Platform:        h.Platform,
+	}.Execute(o)
+	if err == nil || err.Error() != "Project P has drifted from its manifest" {
+		t.Fatalf("got error %v, want the project to have drifted", err)
+	}
+	command_testing.AssertOutput(t, o, "failure", "DriftCommand - 2 out-of-band changes found:")
+	want := []string{
+		`Lambda P-A handler is "changed", but "bootstrap" was deployed`,
+		"Lambda P-B was deleted from the platform",
+	}
+	got := o.Results()["Drift"].([]string)
+	if len(got) != len(want) {
+		t.Fatalf("Drift result = %q, want %q", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("Drift result = %q, want %q", got, want)
+		}
+		command_testing.AssertOutput(t, o, "failure", want[i])
+	}
+	// Drift is only reported, never saved.
+	if after := h.ProjectManifest("P"); after.LambdaManifests[1].Deploy.Arn != before.LambdaManifests[1].Deploy.Arn {
+		t.Error("drift changed the saved manifest")
+	}
+}
+
+func TestDriftFailures(t *testing.T) {
+	h := command_testing.New(t)
+	h.CreateProject("P", "A")
+	h.Push("P")
+	o := output.NewForTesting()
+	err := drift.DriftCommand{
+		EcologyManifest: h.EcologyManifest(),
+		Project:         "Q",
+		Platform:        h.Platform,
+	}.Execute(o)
+	command_testing.AssertError(t, o, err, "--project=Q doesn't exist")
+
+	h.Platform.FailNext("GetFunction", fake_platform.NewServiceError("ServiceException", http.StatusInternalServerError, "injected"))
+	o = output.NewForTesting()
+	err = drift.DriftCommand{
+		EcologyManifest: h.EcologyManifest(),
+		Project:         "P",
+		Platform:        h.Platform,
+	}.Execute(o)
+	command_testing.AssertError(t, o, err, "injected")
+	if _, ok := o.Results()["Drift"]; ok {
+		t.Error("Drift result set on failure")
+	}
+}
@@ -0,0 +1,47 @@
+package history
+
+import (
+	"github.com/gbdubs/ecology/manifests/ecology_manifest"
+	"github.com/gbdubs/ecology/util/flag_validation"
+	"github.com/gbdubs/ecology/util/manifest_history"
+	"github.com/gbdubs/ecology/util/output"
+	"time"
+)
+
+type HistoryCommand struct {
+	EcologyManifest ecology_manifest.EcologyManifest
+	Project         string
+}
+
+func (hc HistoryCommand) Execute(o *output.Output) (err error) {
+	em := &hc.EcologyManifest
+	err = flag_validation.ValidateAll(
+		flag_validation.Project(hc.Project),
+		flag_validation.ProjectExists(hc.Project, em),
+		err)
+	if err != nil {
+		o.Error(err)
+		return err
+	}
+	pm, err := em.GetProjectManifest(hc.Project)
+	if err != nil {
+		o.Error(err)
+		return
+	}
+	snapshots, err := manifest_history.List(pm.HistoryDir())
+	if err != nil {
+		o.Error(err)
+		return
+	}
+	if len(snapshots) == 0 {
+		o.Warning("No snapshots of Project %s in %s", hc.Project, pm.HistoryDir())
+		return nil
+	}
+	o.Info("Snapshots of Project %s, newest first:", hc.Project).Indent()
+	for _, s := range snapshots {
+		o.Success("%s - saved %s, %d bytes", s.Id, s.Time.Local().Format(time.RFC1123), s.Size)
+	}
+	o.Dedent().Done()
+	o.Result("Snapshots", snapshots)
+	return nil
+}
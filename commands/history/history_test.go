@@ -0,0 +1,66 @@
+package history_test
+
+import (
+	"github.com/gbdubs/ecology/commands/command_testing"
+	"github.com/gbdubs/ecology/commands/history"
+	"github.com/gbdubs/ecology/util/manifest_history"
+	"github.com/gbdubs/ecology/util/output"
+	"os"
+	"testing"
+)
+
+func TestHistory(t *testing.T) {
+	h := command_testing.New(t)
+	h.CreateProject("P", "A")
+	h.Push("P")
+	o := output.NewForTesting()
+	err := history.HistoryCommand{
+		EcologyManifest: h.EcologyManifest(),
+		Project:         "P",
+	}.Execute(o)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Saved by create_project, create_lambda and push_project.
+	snapshots := o.Results()["Snapshots"].([]manifest_history.Snapshot)
+	if len(snapshots) != 3 {
+		t.Fatalf("Snapshots result = %+v, want 3", snapshots)
+	}
+	if snapshots[0].Id <= snapshots[2].Id {
+		t.Errorf("Snapshots result = %+v, want newest first", snapshots)
+	}
+	for _, s := range snapshots {
+		command_testing.AssertOutput(t, o, "success", s.Id+" - saved ")
+	}
+}
+
+func TestHistoryWithoutSnapshots(t *testing.T) {
+	h := command_testing.New(t)
+	h.CreateProject("P")
+	historyDir := h.ProjectManifest("P").HistoryDir()
+	if err := os.RemoveAll(historyDir); err != nil {
+		t.Fatal(err)
+	}
+	o := output.NewForTesting()
+	err := history.HistoryCommand{
+		EcologyManifest: h.EcologyManifest(),
+		Project:         "P",
+	}.Execute(o)
+	if err != nil {
+		t.Fatal(err)
+	}
+	command_testing.AssertOutput(t, o, "warning", "No snapshots of Project P in "+historyDir)
+	if _, ok := o.Results()["Snapshots"]; ok {
+		t.Error("Snapshots result set without snapshots")
+	}
+}
+
+func TestHistoryFailures(t *testing.T) {
+	h := command_testing.New(t)
+	o := output.NewForTesting()
+	err := history.HistoryCommand{
+		EcologyManifest: h.EcologyManifest(),
+		Project:         "Q",
+	}.Execute(o)
+	command_testing.AssertError(t, o, err, "--project=Q doesn't exist")
+}
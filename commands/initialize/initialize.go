@@ -0,0 +1,155 @@
+package initialize
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/gbdubs/ecology/manifests/ecology_manifest"
+	"github.com/gbdubs/ecology/util/flag_validation"
+	"github.com/gbdubs/ecology/util/output"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+var gitHosts = []string{"github", "gitlab", "none"}
+
+type InitializeCommand struct {
+	EcologyManifest ecology_manifest.EcologyManifest
+	// When set, answers are read from this JSON file instead of prompting.
+	AnswersFile string
+	In          io.Reader
+}
+
+func (ic InitializeCommand) Execute(o *output.Output) (err error) {
+	em := &ic.EcologyManifest
+	config := em.Config
+	if config.ProjectsDir == "" {
+		home, _ := os.UserHomeDir()
+		config.ProjectsDir = filepath.Join(home, "ecology")
+	}
+	config.DefaultPlatform = em.DefaultPlatform()
+	config.DefaultRegion = em.DefaultRegion()
+	if config.GitHost == "" {
+		config.GitHost = "github"
+	}
+
+	if ic.AnswersFile != "" {
+		o.Info("InitializeCommand - Reading Answers from %s", ic.AnswersFile).Indent()
+		err = readAnswers(ic.AnswersFile, &config)
+	} else {
+		o.Info("InitializeCommand - Prompting for Configuration").Indent()
+		in := ic.In
+		if in == nil {
+			in = os.Stdin
+		}
+		err = prompt(bufio.NewReader(in), &config, o)
+	}
+	if err != nil {
+		o.Error(err)
+		return err
+	}
+	o.Dedent().Done()
+
+	config.ProjectsDir, err = expandHome(config.ProjectsDir)
+	if err == nil {
+		err = flag_validation.ValidateAll(
+			flag_validation.Platform(config.DefaultPlatform),
+			flag_validation.Region(config.DefaultPlatform, config.DefaultRegion),
+			gitHost(config.GitHost))
+	}
+	if err != nil {
+		o.Error(err)
+		return err
+	}
+
+	o.Info("InitializeCommand - EcologyManifest.Update").Indent()
+	err = em.Update(o, func(em *ecology_manifest.EcologyManifest) {
+		em.Config = config
+	})
+	if err != nil {
+		o.Error(err)
+		return err
+	}
+	o.Dedent().Done()
+	o.Result("Config", em.Config)
+	return nil
+}
+
+func readAnswers(answersFile string, config *ecology_manifest.EcologyConfig) error {
+	data, err := ioutil.ReadFile(answersFile)
+	if err != nil {
+		return err
+	}
+	// Fields missing from the answers file keep their current values.
+	return json.Unmarshal(data, config)
+}
+
+func prompt(in *bufio.Reader, config *ecology_manifest.EcologyConfig, o *output.Output) (err error) {
+	questions := []struct {
+		question string
+		answer   *string
+	}{
+		{"Parent directory for ecology projects", &config.ProjectsDir},
+		{"Default platform (AWS or GCP)", &config.DefaultPlatform},
+		{"Default region", &config.DefaultRegion},
+		{"AWS credential profile (blank for the SDK default)", &config.AwsProfile},
+		{"gcloud configuration (blank for the active one)", &config.GcpConfiguration},
+		{fmt.Sprintf("Git host (%s)", strings.Join(gitHosts, ", ")), &config.GitHost},
+		{"Git user or organization", &config.GitUser},
+	}
+	for _, q := range questions {
+		if *q.answer, err = ask(in, q.question, *q.answer, o); err != nil {
+			return
+		}
+	}
+	privateRepos, err := ask(in, "Create private git repositories? (y/n)", yesNo(config.GitPrivateRepos), o)
+	if err != nil {
+		return
+	}
+	config.GitPrivateRepos = strings.HasPrefix(strings.ToLower(privateRepos), "y")
+	return nil
+}
+
+// ask prompts for a single answer, keeping the current value on a blank line.
+func ask(in *bufio.Reader, question string, current string, o *output.Output) (string, error) {
+	o.Info("%s [%s]:", question, current)
+	line, err := in.ReadString('\n')
+	if err != nil && !(err == io.EOF && line != "") {
+		return current, errors.New(fmt.Sprintf("No answer given for %q", question))
+	}
+	if answer := strings.TrimSpace(line); answer != "" {
+		return answer, nil
+	}
+	return current, nil
+}
+
+func yesNo(b bool) string {
+	if b {
+		return "y"
+	}
+	return "n"
+}
+
+func expandHome(path string) (string, error) {
+	if path != "~" && !strings.HasPrefix(path, "~/") {
+		return path, nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return path, err
+	}
+	return filepath.Join(home, path[1:]), nil
+}
+
+func gitHost(host string) error {
+	for _, h := range gitHosts {
+		if h == host {
+			return nil
+		}
+	}
+	return errors.New(fmt.Sprintf("Git host should be one of %s", strings.Join(gitHosts, ", ")))
+}
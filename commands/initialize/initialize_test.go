@@ -0,0 +1,104 @@
+package initialize_test
+
+import (
+	"github.com/gbdubs/ecology/commands/command_testing"
+	"github.com/gbdubs/ecology/commands/initialize"
+	"github.com/gbdubs/ecology/manifests/ecology_manifest"
+	"github.com/gbdubs/ecology/util/output"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestInitializeFromPrompts(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	h := command_testing.New(t)
+	// Blank answers keep the defaults.
+	answers := strings.Join([]string{"~/code", "", "us-east-1", "work", "", "gitlab", "me", "y"}, "\n")
+	o := output.NewForTesting()
+	err := initialize.InitializeCommand{
+		EcologyManifest: h.EcologyManifest(),
+		In:              strings.NewReader(answers),
+	}.Execute(o)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := ecology_manifest.EcologyConfig{
+		ProjectsDir:     filepath.Join(home, "code"),
+		DefaultPlatform: "AWS",
+		DefaultRegion:   "us-east-1",
+		AwsProfile:      "work",
+		GitHost:         "gitlab",
+		GitUser:         "me",
+		GitPrivateRepos: true,
+	}
+	if got := h.EcologyManifest().Config; !reflect.DeepEqual(got, want) {
+		t.Errorf("saved config = %+v, want %+v", got, want)
+	}
+	if got := o.Results()["Config"]; !reflect.DeepEqual(got, want) {
+		t.Errorf("Config result = %+v", got)
+	}
+	command_testing.AssertOutput(t, o, "info", "Default region [us-west-2]:")
+}
+
+func TestInitializeFromAnswersFile(t *testing.T) {
+	h := command_testing.New(t)
+	h.CreateProject("P")
+	answersFile := filepath.Join(t.TempDir(), "answers.json")
+	answers := `{"ProjectsDir": "/projects", "DefaultRegion": "us-east-2", "GitHost": "none"}`
+	if err := ioutil.WriteFile(answersFile, []byte(answers), 0644); err != nil {
+		t.Fatal(err)
+	}
+	o := output.NewForTesting()
+	err := initialize.InitializeCommand{
+		EcologyManifest: h.EcologyManifest(),
+		AnswersFile:     answersFile,
+	}.Execute(o)
+	if err != nil {
+		t.Fatal(err)
+	}
+	em := h.EcologyManifest()
+	want := ecology_manifest.EcologyConfig{
+		ProjectsDir:     "/projects",
+		DefaultPlatform: "AWS",
+		DefaultRegion:   "us-east-2",
+		GitHost:         "none",
+	}
+	if !reflect.DeepEqual(em.Config, want) {
+		t.Errorf("saved config = %+v, want %+v", em.Config, want)
+	}
+	if em.ProjectManifestPaths["P"] == "" {
+		t.Error("initializing forgot project P")
+	}
+	command_testing.AssertOutput(t, o, "info", "InitializeCommand - Reading Answers from "+answersFile)
+}
+
+func TestInitializeFailures(t *testing.T) {
+	h := command_testing.New(t)
+	tests := map[string]string{
+		"Git host should be one of github, gitlab, none": "/projects\n\n\n\n\nbitbucket\n\nn\n",
+		"--region was not recognized for --platform=AWS": "/projects\n\nmars-1\n\n\n\n\nn\n",
+		`No answer given for "Default region"`:           "/projects\nAWS\n",
+	}
+	for message, answers := range tests {
+		o := output.NewForTesting()
+		err := initialize.InitializeCommand{
+			EcologyManifest: h.EcologyManifest(),
+			In:              strings.NewReader(answers),
+		}.Execute(o)
+		command_testing.AssertError(t, o, err, message)
+	}
+
+	o := output.NewForTesting()
+	err := initialize.InitializeCommand{
+		EcologyManifest: h.EcologyManifest(),
+		AnswersFile:     filepath.Join(t.TempDir(), "missing.json"),
+	}.Execute(o)
+	command_testing.AssertError(t, o, err, "no such file or directory")
+	if em := h.EcologyManifest(); !reflect.DeepEqual(em.Config, ecology_manifest.EcologyConfig{}) {
+		t.Errorf("saved config = %+v after failures", em.Config)
+	}
+}
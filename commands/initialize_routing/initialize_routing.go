@@ -0,0 +1,82 @@
+package initialize_routing
+
+import (
+	"github.com/gbdubs/ecology/dns_providers/dns_provider"
+	"github.com/gbdubs/ecology/dns_providers/dns_provider_factory"
+	"github.com/gbdubs/ecology/manifests/ecology_manifest"
+	"github.com/gbdubs/ecology/manifests/routing_manifest"
+	"github.com/gbdubs/ecology/platforms/platform"
+	"github.com/gbdubs/ecology/platforms/platform_factory"
+	"github.com/gbdubs/ecology/util/flag_validation"
+	"github.com/gbdubs/ecology/util/output"
+)
+
+type InitializeRoutingCommand struct {
+	EcologyManifest ecology_manifest.EcologyManifest
+	Project         string
+	Domain          string
+	Registrar       string
+	Zone            string
+	DnsServer       string
+	ZoneFile        string
+	Platform        platform.Platform
+	DnsProvider     dns_provider.DnsProvider
+}
+
+func (irc InitializeRoutingCommand) Execute(o *output.Output) (err error) {
+	em := &irc.EcologyManifest
+	err = flag_validation.ValidateAll(
+		flag_validation.Project(irc.Project),
+		flag_validation.ProjectExists(irc.Project, em),
+		flag_validation.Domain(irc.Domain),
+		flag_validation.Registrar(irc.Registrar),
+		err)
+	if err != nil {
+		o.Error(err)
+		return err
+	}
+	pm, err := em.GetProjectManifestForUpdate(irc.Project)
+	if err != nil {
+		o.Error(err)
+		return
+	}
+	defer pm.Unlock()
+	p := irc.Platform
+	if p == nil {
+		p, err = platform_factory.New(pm.Deploy.Platform, pm.Deploy.Region)
+		if err != nil {
+			o.Error(err)
+			return
+		}
+	}
+	dp := irc.DnsProvider
+	if dp == nil {
+		dp, err = dns_provider_factory.New(irc.Registrar, irc.DnsServer, irc.ZoneFile)
+		if err != nil {
+			o.Error(err)
+			return
+		}
+	}
+
+	o.Info("InitializeRoutingCommand - RoutingManifest.New").Indent()
+	pm.RoutingManifest = routing_manifest.New(irc.Domain, irc.Zone, irc.Registrar, irc.DnsServer, irc.ZoneFile)
+	o.Dedent().Done()
+
+	o.Info("InitializeRoutingCommand - %s.UpdateDomainRecords", irc.Project).Indent()
+	err = pm.UpdateDomainRecords(p, dp, o)
+	if err != nil {
+		o.Error(err)
+		return
+	}
+	o.Dedent().Done()
+
+	o.Info("InitializeRoutingCommand - %s.Save", irc.Project).Indent()
+	err = pm.Save(o)
+	if err != nil {
+		o.Error(err)
+		return
+	}
+	o.Dedent().Done()
+	o.Result("Records", pm.RoutingManifest.Deploy.Records)
+	return nil
+}
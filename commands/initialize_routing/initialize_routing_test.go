@@ -0,0 +1,104 @@
+package initialize_routing_test
+
+import (
+	"github.com/gbdubs/ecology/commands/command_testing"
+	"github.com/gbdubs/ecology/commands/initialize_routing"
+	"github.com/gbdubs/ecology/dns_providers/dns_provider"
+	"github.com/gbdubs/ecology/platforms/fake_platform"
+	"github.com/gbdubs/ecology/util/output"
+	"io/ioutil"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestInitializeRouting(t *testing.T) {
+	h := command_testing.New(t)
+	h.CreateProject("P", "A")
+	h.AddRoute("P", "GET", "/a", "A")
+	h.Push("P")
+	zoneFile := filepath.Join(t.TempDir(), "example.com.zone")
+	o := output.NewForTesting()
+	err := initialize_routing.InitializeRoutingCommand{
+		EcologyManifest: h.EcologyManifest(),
+		Project:         "P",
+		Domain:          "api.example.com",
+		Registrar:       "zone_file",
+		ZoneFile:        zoneFile,
+		Platform:        h.Platform,
+	}.Execute(o)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pm := h.ProjectManifest("P")
+	host := strings.TrimPrefix(pm.ApiManifest.Deploy.Endpoint, "https://")
+	want := dns_provider.Record{Name: "api.example.com", Type: "CNAME", Ttl: 300, Value: host}
+	if rm := pm.RoutingManifest; rm.Config.Zone != "example.com" || len(rm.Deploy.Records) != 1 || rm.Deploy.Records[0] != want {
+		t.Errorf("saved routing = %+v, want api.example.com pointed at %s", rm, host)
+	}
+	zone, _ := ioutil.ReadFile(zoneFile)
+	if !strings.Contains(string(zone), "api.example.com. 300 IN CNAME "+host+".") {
+		t.Errorf("zone file is\n%s", zone)
+	}
+	if records := o.Results()["Records"].([]dns_provider.Record); len(records) != 1 || records[0] != want {
+		t.Errorf("Records result = %+v", records)
+	}
+	command_testing.AssertOutput(t, o, "info", "Upserting CNAME api.example.com -> "+host)
+}
+
+func TestInitializeRoutingWithoutApi(t *testing.T) {
+	h := command_testing.New(t)
+	h.CreateProject("P", "A")
+	o := output.NewForTesting()
+	err := initialize_routing.InitializeRoutingCommand{
+		EcologyManifest: h.EcologyManifest(),
+		Project:         "P",
+		Domain:          "api.example.com",
+		Registrar:       "zone_file",
+		ZoneFile:        filepath.Join(t.TempDir(), "example.com.zone"),
+		Platform:        h.Platform,
+	}.Execute(o)
+	if err != nil {
+		t.Fatal(err)
+	}
+	command_testing.AssertOutput(t, o, "warning", "Project P has no deployed API to route to yet.")
+	if rm := h.ProjectManifest("P").RoutingManifest; !rm.IsInitialized() || len(rm.Deploy.Records) != 0 {
+		t.Errorf("saved routing = %+v, want it initialized without records", rm)
+	}
+}
+
+func TestInitializeRoutingFailures(t *testing.T) {
+	h := command_testing.New(t)
+	h.CreateProject("P", "A")
+	h.AddRoute("P", "GET", "/a", "A")
+	h.Push("P")
+	zoneFile := filepath.Join(t.TempDir(), "example.com.zone")
+	tests := map[string]initialize_routing.InitializeRoutingCommand{
+		"Must set --domain":                     {Registrar: "zone_file", ZoneFile: zoneFile},
+		"--registrar should be one of":          {Domain: "api.example.com", Registrar: "bind"},
+		"Can't point the zone apex example.com": {Domain: "example.com", Zone: "example.com", Registrar: "zone_file", ZoneFile: zoneFile},
+	}
+	for message, irc := range tests {
+		o := output.NewForTesting()
+		irc.EcologyManifest = h.EcologyManifest()
+		irc.Project = "P"
+		irc.Platform = h.Platform
+		command_testing.AssertError(t, o, irc.Execute(o), message)
+	}
+
+	h.Platform.FailNext("GetApi", fake_platform.NewServiceError("TooManyRequestsException", http.StatusTooManyRequests, "injected"))
+	o := output.NewForTesting()
+	err := initialize_routing.InitializeRoutingCommand{
+		EcologyManifest: h.EcologyManifest(),
+		Project:         "P",
+		Domain:          "api.example.com",
+		Registrar:       "zone_file",
+		ZoneFile:        zoneFile,
+		Platform:        h.Platform,
+	}.Execute(o)
+	command_testing.AssertError(t, o, err, "injected")
+	if h.ProjectManifest("P").RoutingManifest.IsInitialized() {
+		t.Error("failed initialization saved the routing")
+	}
+}
@@ -14,11 +14,12 @@ func (lpc ListProjectCommand) Execute(o *output.Output) error {
 	o.Info("Available Projects:").Indent()
 	for project, projectPath := range lpc.EcologyManifest.ProjectManifestPaths {
 		if lpc.Verbose {
-			o.Success(project)
+			o.Success("%s", project)
 		} else {
-			o.Success(project + " - " + projectPath)
+			o.Success("%s - %s", project, projectPath)
 		}
 	}
 	o.Dedent().Done()
+	o.Result("Projects", lpc.EcologyManifest.ProjectManifestPaths)
 	return nil
 }
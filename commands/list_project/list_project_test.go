@@ -0,0 +1,38 @@
+package list_project_test
+
+import (
+	"github.com/gbdubs/ecology/commands/command_testing"
+	"github.com/gbdubs/ecology/commands/list_project"
+	"github.com/gbdubs/ecology/util/output"
+	"reflect"
+	"testing"
+)
+
+func TestListProject(t *testing.T) {
+	h := command_testing.New(t)
+	h.CreateProject("P")
+	h.CreateProject("Q")
+	o := output.NewForTesting()
+	em := h.EcologyManifest()
+	if err := (list_project.ListProjectCommand{EcologyManifest: em}).Execute(o); err != nil {
+		t.Fatal(err)
+	}
+	if got := o.Results()["Projects"]; !reflect.DeepEqual(got, em.ProjectManifestPaths) || len(em.ProjectManifestPaths) != 2 {
+		t.Errorf("Projects result = %v", got)
+	}
+	command_testing.AssertOutput(t, o, "success", "P - "+em.ProjectManifestPaths["P"])
+	command_testing.AssertOutput(t, o, "success", "Q - "+em.ProjectManifestPaths["Q"])
+}
+
+func TestListNoProjects(t *testing.T) {
+	h := command_testing.New(t)
+	o := output.NewForTesting()
+	if err := (list_project.ListProjectCommand{EcologyManifest: h.EcologyManifest()}).Execute(o); err != nil {
+		t.Fatal(err)
+	}
+	for _, e := range o.Events() {
+		if e.Level != "info" && e.Message != "Done." {
+			t.Errorf("listed %q", e.Message)
+		}
+	}
+}
@@ -0,0 +1,79 @@
+package migrate
+
+import (
+	"github.com/gbdubs/ecology/manifests/ecology_manifest"
+	"github.com/gbdubs/ecology/util/atomic_file"
+	"github.com/gbdubs/ecology/util/flag_validation"
+	"github.com/gbdubs/ecology/util/output"
+	"io/ioutil"
+	"sort"
+	"time"
+)
+
+type MigrateCommand struct {
+	EcologyManifest ecology_manifest.EcologyManifest
+	// When blank, every project is migrated.
+	Project string
+}
+
+func (mc MigrateCommand) Execute(o *output.Output) (err error) {
+	em := &mc.EcologyManifest
+	projects := []string{mc.Project}
+	if mc.Project == "" {
+		projects = make([]string, 0)
+		for project, _ := range em.ProjectManifestPaths {
+			projects = append(projects, project)
+		}
+		sort.Strings(projects)
+	} else {
+		err = flag_validation.ValidateAll(
+			flag_validation.Project(mc.Project),
+			flag_validation.ProjectExists(mc.Project, em))
+		if err != nil {
+			o.Error(err)
+			return err
+		}
+	}
+
+	// The backup of each migrated project.
+	backups := make(map[string]string)
+	o.Result("Backups", backups)
+	for _, project := range projects {
+		o.Info("MigrateCommand - %s", project).Indent()
+		backup, err := migrateProject(em, project, o)
+		if err != nil {
+			o.Error(err)
+			return err
+		}
+		if backup != "" {
+			backups[project] = backup
+		}
+		o.Dedent().Done()
+	}
+	return nil
+}
+
+// migrateProject backs up the project manifest before saving it in the
+// current schema, if it was written in an older one, and returns the path of
+// the backup.
+func migrateProject(em *ecology_manifest.EcologyManifest, project string, o *output.Output) (backupPath string, err error) {
+	pm, err := em.GetProjectManifestForUpdate(project)
+	if err != nil {
+		return
+	}
+	defer pm.Unlock()
+	if !pm.Migrated() {
+		o.Success("Already up to date.")
+		return "", nil
+	}
+	original, err := ioutil.ReadFile(pm.Config.ManifestPath)
+	if err != nil {
+		return
+	}
+	backupPath = pm.Config.ManifestPath + ".bak-" + time.Now().UTC().Format("20060102T150405Z")
+	o.Info("Backing up %s to %s", pm.Config.ManifestPath, backupPath)
+	if err = atomic_file.WriteFile(backupPath, original, 0644); err != nil {
+		return
+	}
+	return backupPath, pm.Save(o)
+}
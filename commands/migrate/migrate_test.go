@@ -0,0 +1,91 @@
+package migrate_test
+
+import (
+	"encoding/json"
+	"github.com/gbdubs/ecology/commands/command_testing"
+	"github.com/gbdubs/ecology/commands/migrate"
+	"github.com/gbdubs/ecology/manifests/project_manifest"
+	"github.com/gbdubs/ecology/util/output"
+	"io/ioutil"
+	"testing"
+)
+
+// unversion rewrites the project manifest as versions of ecology from before
+// schema versions did.
+func unversion(t *testing.T, path string) []byte {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	doc := make(map[string]interface{})
+	if err = json.Unmarshal(data, &doc); err != nil {
+		t.Fatal(err)
+	}
+	delete(doc, "SchemaVersion")
+	if data, err = json.Marshal(doc); err != nil {
+		t.Fatal(err)
+	}
+	if err = ioutil.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func TestMigrate(t *testing.T) {
+	h := command_testing.New(t)
+	h.CreateProject("P", "A")
+	h.CreateProject("Q")
+	path := h.ProjectManifest("P").Config.ManifestPath
+	original := unversion(t, path)
+	o := output.NewForTesting()
+	if err := (migrate.MigrateCommand{EcologyManifest: h.EcologyManifest()}).Execute(o); err != nil {
+		t.Fatal(err)
+	}
+	backups := o.Results()["Backups"].(map[string]string)
+	if len(backups) != 1 {
+		t.Fatalf("Backups result = %v, want only P backed up", backups)
+	}
+	backup, err := ioutil.ReadFile(backups["P"])
+	if err != nil || string(backup) != string(original) {
+		t.Errorf("backup = %s, %v, want the original manifest", backup, err)
+	}
+	if pm := h.ProjectManifest("P"); pm.Migrated() || pm.SchemaVersion != project_manifest.SchemaVersion {
+		t.Errorf("saved manifest has schema version %d, want %d", pm.SchemaVersion, project_manifest.SchemaVersion)
+	}
+	command_testing.AssertOutput(t, o, "info", "MigrateCommand - Q")
+	command_testing.AssertOutput(t, o, "success", "Already up to date.")
+}
+
+func TestMigrateOneProject(t *testing.T) {
+	h := command_testing.New(t)
+	h.CreateProject("P")
+	h.CreateProject("Q")
+	unversion(t, h.ProjectManifest("P").Config.ManifestPath)
+	unversion(t, h.ProjectManifest("Q").Config.ManifestPath)
+	o := output.NewForTesting()
+	if err := (migrate.MigrateCommand{EcologyManifest: h.EcologyManifest(), Project: "Q"}).Execute(o); err != nil {
+		t.Fatal(err)
+	}
+	if backups := o.Results()["Backups"].(map[string]string); len(backups) != 1 || backups["Q"] == "" {
+		t.Errorf("Backups result = %v, want only Q backed up", backups)
+	}
+	if !h.ProjectManifest("P").Migrated() {
+		t.Error("P was migrated too")
+	}
+}
+
+func TestMigrateFailures(t *testing.T) {
+	h := command_testing.New(t)
+	o := output.NewForTesting()
+	err := migrate.MigrateCommand{EcologyManifest: h.EcologyManifest(), Project: "Q"}.Execute(o)
+	command_testing.AssertError(t, o, err, "--project=Q doesn't exist")
+
+	h.CreateProject("P")
+	path := h.ProjectManifest("P").Config.ManifestPath
+	if err = ioutil.WriteFile(path, []byte("{"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	o = output.NewForTesting()
+	err = migrate.MigrateCommand{EcologyManifest: h.EcologyManifest()}.Execute(o)
+	command_testing.AssertError(t, o, err, "unexpected end of JSON input")
+}
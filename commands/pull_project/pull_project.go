@@ -0,0 +1,69 @@
+package pull_project
+
+import (
+	"github.com/gbdubs/ecology/manifests/ecology_manifest"
+	"github.com/gbdubs/ecology/platforms/platform"
+	"github.com/gbdubs/ecology/platforms/platform_factory"
+	"github.com/gbdubs/ecology/util/flag_validation"
+	"github.com/gbdubs/ecology/util/output"
+)
+
+type PullProjectCommand struct {
+	EcologyManifest ecology_manifest.EcologyManifest
+	Project         string
+	Platform        platform.Platform
+}
+
+func (ppc PullProjectCommand) Execute(o *output.Output) (err error) {
+	em := &ppc.EcologyManifest
+	err = flag_validation.ValidateAll(
+		flag_validation.Project(ppc.Project),
+		flag_validation.ProjectExists(ppc.Project, em),
+		err)
+	if err != nil {
+		o.Error(err)
+		return err
+	}
+	pm, err := em.GetProjectManifestForUpdate(ppc.Project)
+	if err != nil {
+		o.Error(err)
+		return
+	}
+	defer pm.Unlock()
+	p := ppc.Platform
+	if p == nil {
+		p, err = platform_factory.New(pm.Deploy.Platform, pm.Deploy.Region)
+		if err != nil {
+			o.Error(err)
+			return
+		}
+	}
+
+	o.Info("PullProjectCommand - %s.PullFromPlatform", ppc.Project).Indent()
+	changes, err := pm.PullFromPlatform(p, o)
+	if err != nil {
+		o.Error(err)
+		return
+	}
+	o.Dedent().Done()
+	o.Result("Changes", changes)
+
+	if len(changes) == 0 {
+		o.Success("PullProjectCommand - Project %s already matches the platform.", ppc.Project)
+		return nil
+	}
+	o.Warning("PullProjectCommand - %d changes pulled from the platform:", len(changes)).Indent()
+	for _, change := range changes {
+		o.Warning("%s", change)
+	}
+	o.Dedent()
+
+	o.Info("PullProjectCommand - %s.Save", ppc.Project).Indent()
+	err = pm.Save(o)
+	if err != nil {
+		o.Error(err)
+		return
+	}
+	o.Dedent().Done()
+	return nil
+}
@@ -0,0 +1,93 @@
+package pull_project_test
+
+import (
+	"github.com/gbdubs/ecology/commands/command_testing"
+	"github.com/gbdubs/ecology/commands/pull_project"
+	"github.com/gbdubs/ecology/platforms/fake_platform"
+	"github.com/gbdubs/ecology/util/output"
+	"net/http"
+	"testing"
+)
+
+func TestPullProjectUnchanged(t *testing.T) {
+	h := command_testing.New(t)
+	h.CreateProject("P", "A")
+	h.Push("P")
+	o := output.NewForTesting()
+	err := pull_project.PullProjectCommand{
+		EcologyManifest: h.EcologyManifest(),
+		Project:         "P",
+		Platform:        h.Platform,
+	}.Execute(o)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := o.Results()["Changes"].([]string); len(got) != 0 {
+		t.Errorf("Changes result = %v", got)
+	}
+	command_testing.AssertOutput(t, o, "success", "PullProjectCommand - Project P already matches the platform.")
+}
+
+func TestPullProject(t *testing.T) {
+	h := command_testing.New(t)
+	h.CreateProject("P", "A", "B")
+	h.Push("P")
+	h.Platform.Functions["P-A"].Config.CodeSha256 = "changed"
+	delete(h.Platform.Functions, "P-B")
+	o := output.NewForTesting()
+	err := pull_project.PullProjectCommand{
+		EcologyManifest: h.EcologyManifest(),
+		Project:         "P",
+		Platform:        h.Platform,
+	}.Execute(o)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"Lambda P-A code was changed on the platform, clearing its last deployed hash",
+		"Lambda P-B no longer exists on the platform, marking it undeployed",
+	}
+	got := o.Results()["Changes"].([]string)
+	if len(got) != len(want) {
+		t.Fatalf("Changes result = %q, want %q", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("Changes result = %q, want %q", got, want)
+		}
+		command_testing.AssertOutput(t, o, "warning", want[i])
+	}
+	a := h.LambdaManifest("P", "A")
+	if a.Deploy.CodeSha256 != "changed" || a.Deploy.LastDeployedHash != "" {
+		t.Errorf("saved deploy info of A = %+v, want the platform's", a.Deploy)
+	}
+	if b := h.LambdaManifest("P", "B"); b.IsDeployed() {
+		t.Errorf("saved deploy info of B = %+v, want it undeployed", b.Deploy)
+	}
+}
+
+func TestPullProjectFailures(t *testing.T) {
+	h := command_testing.New(t)
+	h.CreateProject("P", "A")
+	h.Push("P")
+	o := output.NewForTesting()
+	err := pull_project.PullProjectCommand{
+		EcologyManifest: h.EcologyManifest(),
+		Project:         "Q",
+		Platform:        h.Platform,
+	}.Execute(o)
+	command_testing.AssertError(t, o, err, "--project=Q doesn't exist")
+
+	delete(h.Platform.Functions, "P-A")
+	h.Platform.FailNext("GetRole", fake_platform.NewServiceError("ServiceFailure", http.StatusInternalServerError, "injected"))
+	o = output.NewForTesting()
+	err = pull_project.PullProjectCommand{
+		EcologyManifest: h.EcologyManifest(),
+		Project:         "P",
+		Platform:        h.Platform,
+	}.Execute(o)
+	command_testing.AssertError(t, o, err, "injected")
+	if lm := h.LambdaManifest("P", "A"); !lm.IsDeployed() {
+		t.Error("a failed pull saved its changes")
+	}
+}
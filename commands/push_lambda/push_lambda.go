@@ -2,6 +2,10 @@ package push_lambda
 
 import (
 	"github.com/gbdubs/ecology/manifests/ecology_manifest"
+	"github.com/gbdubs/ecology/manifests/plan_manifest"
+	"github.com/gbdubs/ecology/manifests/project_manifest"
+	"github.com/gbdubs/ecology/platforms/platform"
+	"github.com/gbdubs/ecology/platforms/platform_factory"
 	"github.com/gbdubs/ecology/util/flag_validation"
 	"github.com/gbdubs/ecology/util/output"
 )
@@ -10,11 +14,20 @@ type PushLambdaCommand struct {
 	EcologyManifest ecology_manifest.EcologyManifest
 	Project         string
 	Lambda          string
+	Plan            bool
+	Platform        platform.Platform
 }
 
 func (plc PushLambdaCommand) Execute(o *output.Output) (err error) {
 	em := &plc.EcologyManifest
-	pm, err := em.GetProjectManifest(plc.Project)
+	// Planning saves nothing, so it doesn't hold up pushes by taking the lock.
+	var pm *project_manifest.ProjectManifest
+	if plc.Plan {
+		pm, err = em.GetProjectManifest(plc.Project)
+	} else {
+		pm, err = em.GetProjectManifestForUpdate(plc.Project)
+	}
+	defer pm.Unlock()
 	err = flag_validation.ValidateAll(
 		flag_validation.Project(plc.Project),
 		flag_validation.ProjectExists(plc.Project, em),
@@ -26,9 +39,34 @@ func (plc PushLambdaCommand) Execute(o *output.Output) (err error) {
 		return err
 	}
 	lm, err := pm.GetLambdaManifest(plc.Lambda)
+	p := plc.Platform
+	if p == nil {
+		p, err = platform_factory.New(pm.Deploy.Platform, pm.Deploy.Region)
+		if err != nil {
+			o.Error(err)
+			return
+		}
+	}
+
+	if plc.Plan {
+		o.Info("PushLambdaCommand - %s.Plan", plc.Lambda).Indent()
+		actions, err := lm.Plan(p)
+		if err != nil {
+			o.Error(err)
+			return err
+		}
+		o.Dedent().Done()
+		plan := &plan_manifest.PlanManifest{
+			Project: plc.Project,
+			Actions: actions,
+		}
+		o.Result("Plan", plan)
+		plan.Print(o)
+		return nil
+	}
 
 	o.Info("PushLambdaCommand - %s.PushToPlatform", plc.Lambda).Indent()
-	err = lm.PushToPlatform(o)
+	err = lm.PushToPlatform(p, o)
 	if err != nil {
 		o.Error(err)
 		return
@@ -42,5 +80,6 @@ func (plc PushLambdaCommand) Execute(o *output.Output) (err error) {
 		return
 	}
 	o.Dedent().Done()
+	o.Result("Lambda", lm.Summary())
 	return nil
 }
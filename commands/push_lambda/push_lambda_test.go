@@ -0,0 +1,266 @@
+package push_lambda_test
+
+import (
+	"archive/zip"
+	"bytes"
+	"debug/elf"
+	"encoding/json"
+	"github.com/gbdubs/ecology/commands/command_testing"
+	"github.com/gbdubs/ecology/commands/create_lambda"
+	"github.com/gbdubs/ecology/commands/push_lambda"
+	"github.com/gbdubs/ecology/manifests/lambda_manifest"
+	"github.com/gbdubs/ecology/manifests/plan_manifest"
+	"github.com/gbdubs/ecology/manifests/project_manifest"
+	"github.com/gbdubs/ecology/platforms/fake_platform"
+	"github.com/gbdubs/ecology/platforms/platform"
+	"github.com/gbdubs/ecology/util/output"
+	"io/ioutil"
+	"net/http"
+	"testing"
+)
+
+func TestPushLambda(t *testing.T) {
+	h := command_testing.New(t)
+	h.CreateProject("P", "A", "B")
+	o := output.NewForTesting()
+	err := push_lambda.PushLambdaCommand{
+		EcologyManifest: h.EcologyManifest(),
+		Project:         "P",
+		Lambda:          "A",
+		Platform:        h.Platform,
+	}.Execute(o)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f, ok := h.Platform.Functions["P-A"]
+	if !ok {
+		t.Fatal("P-A wasn't created")
+	}
+	if _, ok := h.Platform.Functions["P-B"]; ok {
+		t.Error("P-B was pushed too")
+	}
+	lm := h.LambdaManifest("P", "A")
+	if lm.Deploy.Arn != f.Config.Arn || lm.Deploy.CodeSha256 != f.Config.CodeSha256 || !lm.ExecutorRoleManifest.Deploy.ExistsOnPlatform {
+		t.Errorf("saved manifest = %+v, want it pushed", lm)
+	}
+	if summary := o.Results()["Lambda"].(lambda_manifest.LambdaSummary); summary.Arn != f.Config.Arn {
+		t.Errorf("Lambda result = %+v", summary)
+	}
+	command_testing.AssertOutput(t, o, "success", "Alias live Points At Version 1.")
+}
+
+func TestPlanLambda(t *testing.T) {
+	h := command_testing.New(t)
+	h.CreateProject("P", "A")
+	o := output.NewForTesting()
+	err := push_lambda.PushLambdaCommand{
+		EcologyManifest: h.EcologyManifest(),
+		Project:         "P",
+		Lambda:          "A",
+		Plan:            true,
+		Platform:        h.Platform,
+	}.Execute(o)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(h.Platform.Functions) != 0 || h.LambdaManifest("P", "A").IsDeployed() {
+		t.Fatal("planning pushed the lambda")
+	}
+	plan := o.Results()["Plan"].(*plan_manifest.PlanManifest)
+	if action, _ := plan.Changes("lambda", "P-A"); action.Change != plan_manifest.ChangeCreate {
+		t.Errorf("plan = %+v, want P-A created", plan)
+	}
+	command_testing.AssertOutput(t, o, "info", "Plan for Project P:")
+}
+
+func TestPushLambdaFailures(t *testing.T) {
+	h := command_testing.New(t)
+	h.CreateProject("P", "A")
+	o := output.NewForTesting()
+	err := push_lambda.PushLambdaCommand{
+		EcologyManifest: h.EcologyManifest(),
+		Project:         "P",
+		Lambda:          "Z",
+		Platform:        h.Platform,
+	}.Execute(o)
+	command_testing.AssertError(t, o, err, "--lambda=Z doesn't exist")
+
+	h.Platform.FailNext("CreateFunction", fake_platform.NewServiceError("ServiceException", http.StatusInternalServerError, "injected"))
+	o = output.NewForTesting()
+	err = push_lambda.PushLambdaCommand{
+		EcologyManifest: h.EcologyManifest(),
+		Project:         "P",
+		Lambda:          "A",
+		Platform:        h.Platform,
+	}.Execute(o)
+	command_testing.AssertError(t, o, err, "injected")
+	if lm := h.LambdaManifest("P", "A"); lm.IsDeployed() {
+		t.Errorf("saved manifest = %+v, want it undeployed", lm.Deploy)
+	}
+	if _, ok := o.Results()["Lambda"]; ok {
+		t.Error("Lambda result set on failure")
+	}
+}
+
+func TestPushLambdaSettingsOnly(t *testing.T) {
+	h := command_testing.New(t)
+	h.CreateProject("P", "A")
+	h.Push("P")
+	push := func() {
+		t.Helper()
+		h.Platform.Calls = nil
+		err := push_lambda.PushLambdaCommand{
+			EcologyManifest: h.EcologyManifest(),
+			Project:         "P",
+			Lambda:          "A",
+			Platform:        h.Platform,
+		}.Execute(output.NewForTesting())
+		if err != nil {
+			t.Fatal(err)
+		}
+		updated := false
+		for _, call := range h.Platform.Calls {
+			if call == "UpdateFunctionCode" {
+				t.Error("pushing only settings updated the code")
+			}
+			updated = updated || call == "UpdateFunctionConfiguration"
+		}
+		if !updated {
+			t.Fatalf("pushing changed settings didn't call UpdateFunctionConfiguration, calls = %v", h.Platform.Calls)
+		}
+	}
+
+	h.UpdateProjectManifest("P", func(pm *project_manifest.ProjectManifest) {
+		pm.LambdaManifests[0].Config.MemorySize = 512
+		pm.LambdaManifests[0].Config.Environment = map[string]string{"GREETING": "hello"}
+	})
+	push()
+	f := h.Platform.Functions["P-A"].Config
+	if f.MemorySize != 512 || f.Environment["GREETING"] != "hello" {
+		t.Errorf("function settings = %+v, want the declared ones", f.FunctionSettings)
+	}
+
+	h.UpdateProjectManifest("P", func(pm *project_manifest.ProjectManifest) {
+		pm.LambdaManifests[0].Config.MemorySize = 0
+		pm.LambdaManifests[0].Config.Environment = nil
+	})
+	push()
+	f = h.Platform.Functions["P-A"].Config
+	if f.MemorySize != 128 || len(f.Environment) != 0 {
+		t.Errorf("function settings = %+v, want the defaults", f.FunctionSettings)
+	}
+}
+
+func TestPushLambdaForArm64(t *testing.T) {
+	h := command_testing.New(t)
+	h.CreateProject("P")
+	clc := create_lambda.CreateLambdaCommand{
+		EcologyManifest: h.EcologyManifest(),
+		Project:         "P",
+		Lambda:          "A",
+		Architecture:    platform.ArchitectureArm64,
+	}
+	if err := clc.Execute(output.NewForTesting()); err != nil {
+		t.Fatal(err)
+	}
+	h.WriteSource("P", "A", command_testing.LambdaSource)
+	h.Push("P")
+
+	f := h.Platform.Functions["P-A"]
+	if f.Config.Architecture != platform.ArchitectureArm64 || f.Config.Runtime != lambda_manifest.DefaultRuntime {
+		t.Errorf("function runs on %s %s, want %s %s", f.Config.Runtime, f.Config.Architecture, lambda_manifest.DefaultRuntime, platform.ArchitectureArm64)
+	}
+	reader, err := zip.NewReader(bytes.NewReader(f.ZipFile), int64(len(f.ZipFile)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(reader.File) != 1 || reader.File[0].Name != "bootstrap" {
+		t.Fatalf("zip holds %v, want only bootstrap", reader.File)
+	}
+	bootstrap := reader.File[0]
+	if bootstrap.Mode()&0111 == 0 {
+		t.Errorf("bootstrap has mode %v, want it executable", bootstrap.Mode())
+	}
+	r, err := bootstrap.Open()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	executable, err := ioutil.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	binary, err := elf.NewFile(bytes.NewReader(executable))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if binary.Machine != elf.EM_AARCH64 || binary.Type != elf.ET_EXEC {
+		t.Errorf("bootstrap is a %v %v, want an arm64 executable", binary.Machine, binary.Type)
+	}
+}
+
+func TestPushLambdaMovesOffGo1x(t *testing.T) {
+	h := command_testing.New(t)
+	h.CreateProject("P", "A")
+	h.Push("P")
+
+	// Rewrite the lambda as versions of ecology from before custom runtimes
+	// left it, deployed on go1.x.
+	path := h.ProjectManifest("P").Config.ManifestPath
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	doc := make(map[string]interface{})
+	if err = json.Unmarshal(data, &doc); err != nil {
+		t.Fatal(err)
+	}
+	lambda := doc["LambdaManifests"].([]interface{})[0].(map[string]interface{})
+	delete(lambda, "SchemaVersion")
+	config := lambda["Config"].(map[string]interface{})
+	config["Runtime"] = "go1.x"
+	delete(config, "Architecture")
+	lambda["Deploy"].(map[string]interface{})["Runtime"] = "go1.x"
+	lambda["Deploy"].(map[string]interface{})["Handler"] = "A"
+	if data, err = json.Marshal(doc); err != nil {
+		t.Fatal(err)
+	}
+	if err = ioutil.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	h.Platform.Functions["P-A"].Config.Runtime = "go1.x"
+	h.Platform.Functions["P-A"].Config.Handler = "A"
+
+	h.Platform.Calls = nil
+	o := output.NewForTesting()
+	err = push_lambda.PushLambdaCommand{
+		EcologyManifest: h.EcologyManifest(),
+		Project:         "P",
+		Lambda:          "A",
+		Platform:        h.Platform,
+	}.Execute(o)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !contains(h.Platform.Calls, "UpdateFunctionConfiguration") {
+		t.Fatalf("calls = %v, want the runtime updated", h.Platform.Calls)
+	}
+	command_testing.AssertOutput(t, o, "info", `runtime "go1.x" -> "provided.al2023"`)
+	f := h.Platform.Functions["P-A"].Config
+	if f.Runtime != lambda_manifest.DefaultRuntime || f.Handler != "bootstrap" {
+		t.Errorf("function runs %s on %s, want bootstrap on %s", f.Handler, f.Runtime, lambda_manifest.DefaultRuntime)
+	}
+	lm := h.LambdaManifest("P", "A")
+	if lm.Config.Runtime != lambda_manifest.DefaultRuntime || lm.Deploy.Runtime != lambda_manifest.DefaultRuntime || lm.SchemaVersion != lambda_manifest.SchemaVersion {
+		t.Errorf("saved manifest = %+v, want it migrated and deployed on %s", lm, lambda_manifest.DefaultRuntime)
+	}
+}
+
+func contains(calls []string, call string) bool {
+	for _, c := range calls {
+		if c == call {
+			return true
+		}
+	}
+	return false
+}
@@ -1,7 +1,12 @@
 package push_project
 
 import (
+	"errors"
 	"github.com/gbdubs/ecology/manifests/ecology_manifest"
+	"github.com/gbdubs/ecology/manifests/plan_manifest"
+	"github.com/gbdubs/ecology/manifests/project_manifest"
+	"github.com/gbdubs/ecology/platforms/platform"
+	"github.com/gbdubs/ecology/platforms/platform_factory"
 	"github.com/gbdubs/ecology/util/flag_validation"
 	"github.com/gbdubs/ecology/util/output"
 )
@@ -9,27 +14,86 @@ import (
 type PushProjectCommand struct {
 	EcologyManifest ecology_manifest.EcologyManifest
 	Project         string
+	Plan            bool
+	PlanFile        string
+	Apply           string
+	Parallelism     int
+	Platform        platform.Platform
 }
 
 func (ppc PushProjectCommand) Execute(o *output.Output) (err error) {
 	em := &ppc.EcologyManifest
+	planning := ppc.Plan || ppc.PlanFile != ""
 	err = flag_validation.ValidateAll(
 		flag_validation.Project(ppc.Project),
 		flag_validation.ProjectExists(ppc.Project, em),
+		planFlags(planning, ppc.Apply),
+		flag_validation.Parallelism(ppc.Parallelism),
 		err)
 	if err != nil {
 		o.Error(err)
 		return err
 	}
-	pm, err := em.GetProjectManifest(ppc.Project)
-
-	o.Info("PushProjectCommand - %s.PushToPlatform", ppc.Project).Indent()
-	err = pm.PushToPlatform(o)
+	// Planning saves nothing, so it doesn't hold up pushes by taking the lock.
+	var pm *project_manifest.ProjectManifest
+	if planning {
+		pm, err = em.GetProjectManifest(ppc.Project)
+	} else {
+		pm, err = em.GetProjectManifestForUpdate(ppc.Project)
+	}
 	if err != nil {
 		o.Error(err)
 		return
 	}
-	o.Dedent().Done()
+	defer pm.Unlock()
+	p := ppc.Platform
+	if p == nil {
+		p, err = platform_factory.New(pm.Deploy.Platform, pm.Deploy.Region)
+		if err != nil {
+			o.Error(err)
+			return
+		}
+	}
+
+	if planning {
+		o.Info("PushProjectCommand - %s.Plan", ppc.Project).Indent()
+		plan, err := pm.Plan(p, o)
+		if err != nil {
+			o.Error(err)
+			return err
+		}
+		o.Dedent().Done()
+		o.Result("Plan", plan)
+		plan.Print(o)
+		if ppc.PlanFile != "" {
+			return plan.Save(ppc.PlanFile, o)
+		}
+		return nil
+	}
+
+	if ppc.Apply != "" {
+		o.Info("PushProjectCommand - %s.ApplyPlan", ppc.Project).Indent()
+		plan, err := plan_manifest.GetPlanManifestFromFile(ppc.Apply)
+		if err == nil {
+			err = pm.ApplyPlan(plan, p, ppc.Parallelism, o)
+		}
+		if err != nil {
+			o.Error(err)
+			return err
+		}
+		o.Dedent().Done()
+	} else {
+		o.Info("PushProjectCommand - %s.PushToPlatform", ppc.Project).Indent()
+		err = pm.PushToPlatform(p, ppc.Parallelism, o)
+		if err != nil {
+			o.Error(err)
+			// Keep track of the lambdas that did get pushed.
+			pm.Save(o)
+			o.Result("Project", pm.Summary())
+			return
+		}
+		o.Dedent().Done()
+	}
 
 	o.Info("PushProjectCommand - %s.Save", ppc.Project).Indent()
 	err = pm.Save(o)
@@ -38,5 +102,13 @@ func (ppc PushProjectCommand) Execute(o *output.Output) (err error) {
 		return
 	}
 	o.Dedent().Done()
+	o.Result("Project", pm.Summary())
+	return nil
+}
+
+func planFlags(plan bool, apply string) error {
+	if plan && apply != "" {
+		return errors.New("Can't set both --plan and --apply")
+	}
 	return nil
 }
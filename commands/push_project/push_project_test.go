@@ -0,0 +1,257 @@
+package push_project_test
+
+import (
+	"github.com/gbdubs/ecology/commands/command_testing"
+	"github.com/gbdubs/ecology/commands/push_project"
+	"github.com/gbdubs/ecology/manifests/plan_manifest"
+	"github.com/gbdubs/ecology/manifests/project_manifest"
+	"github.com/gbdubs/ecology/platforms/fake_platform"
+	"github.com/gbdubs/ecology/util/output"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestPushProject(t *testing.T) {
+	h := command_testing.New(t)
+	h.CreateProject("P", "A", "B")
+	o := output.NewForTesting()
+	ppc := push_project.PushProjectCommand{
+		EcologyManifest: h.EcologyManifest(),
+		Project:         "P",
+		Platform:        h.Platform,
+	}
+	if err := ppc.Execute(o); err != nil {
+		t.Fatal(err)
+	}
+	for _, lambda := range []string{"A", "B"} {
+		lm := h.LambdaManifest("P", lambda)
+		f, ok := h.Platform.Functions["P-"+lambda]
+		if !ok {
+			t.Fatalf("P-%s wasn't created", lambda)
+		}
+		if lm.Deploy.Arn != f.Config.Arn || lm.Deploy.LastDeployedHash == "" || lm.Deploy.AliasVersion != "1" {
+			t.Errorf("saved deploy info of %s = %+v, want it pushed", lambda, lm.Deploy)
+		}
+	}
+	summary := o.Results()["Project"].(project_manifest.ProjectSummary)
+	if len(summary.Lambdas) != 2 || summary.Lambdas[0].Arn == "" {
+		t.Errorf("Project result = %+v", summary)
+	}
+	command_testing.AssertOutput(t, o, "info", "PushProjectCommand - P.Save")
+
+	// Pushing again changes nothing.
+	h.Platform.Calls = nil
+	o = output.NewForTesting()
+	ppc.EcologyManifest = h.EcologyManifest()
+	if err := ppc.Execute(o); err != nil {
+		t.Fatal(err)
+	}
+	for _, call := range h.Platform.Calls {
+		if call == "CreateFunction" || call == "UpdateFunctionCode" {
+			t.Errorf("second push called %s", call)
+		}
+	}
+	command_testing.AssertOutput(t, o, "success", "Code hasn't changed since last push")
+}
+
+func TestPushProjectSavesLambdasThatWerePushed(t *testing.T) {
+	h := command_testing.New(t)
+	h.CreateProject("P", "A", "B")
+	h.Platform.FailNext("CreateFunction", fake_platform.NewServiceError("ServiceException", http.StatusInternalServerError, "injected"))
+	o := output.NewForTesting()
+	err := push_project.PushProjectCommand{
+		EcologyManifest: h.EcologyManifest(),
+		Project:         "P",
+		Platform:        h.Platform,
+	}.Execute(o)
+	command_testing.AssertError(t, o, err, "1 of 2 lambdas failed to push")
+	pushed := 0
+	for _, lambda := range []string{"A", "B"} {
+		if h.LambdaManifest("P", lambda).IsDeployed() {
+			pushed++
+		}
+	}
+	if pushed != 1 || len(h.Platform.Functions) != 1 {
+		t.Fatalf("%d lambdas saved as pushed and %d functions created, want 1 of each", pushed, len(h.Platform.Functions))
+	}
+	if _, ok := o.Results()["Project"]; !ok {
+		t.Error("no Project result after a partial push")
+	}
+
+	// Pushing again finishes the job.
+	h.Push("P")
+	if len(h.Platform.Functions) != 2 {
+		t.Fatalf("functions = %v, want both lambdas", h.Platform.Functions)
+	}
+}
+
+// TestPushProjectInParallel is worth running with -race.
+func TestPushProjectInParallel(t *testing.T) {
+	h := command_testing.New(t)
+	lambdas := []string{"A", "B", "C", "D", "E", "F"}
+	h.CreateProject("P", lambdas...)
+	h.Platform.FailNext("CreateRole", fake_platform.NewServiceError("ServiceFailure", http.StatusInternalServerError, "injected role failure"))
+	h.Platform.FailNext("CreateFunction", fake_platform.NewServiceError("ServiceException", http.StatusInternalServerError, "injected first failure"))
+	h.Platform.FailNext("CreateFunction", fake_platform.NewServiceError("ServiceException", http.StatusInternalServerError, "injected second failure"))
+	o := output.NewForTesting()
+	err := push_project.PushProjectCommand{
+		EcologyManifest: h.EcologyManifest(),
+		Project:         "P",
+		Parallelism:     4,
+		Platform:        h.Platform,
+	}.Execute(o)
+	command_testing.AssertError(t, o, err, "3 of 6 lambdas failed to push")
+	for _, message := range []string{"injected role failure", "injected first failure", "injected second failure"} {
+		if !strings.Contains(err.Error(), message) {
+			t.Errorf("error %q doesn't report %q", err, message)
+		}
+	}
+
+	// Every lambda was attempted, whatever failed alongside it.
+	calls := make(map[string]int)
+	for _, call := range h.Platform.Calls {
+		calls[call]++
+	}
+	if calls["CreateRole"] != 6 || calls["CreateFunction"] != 5 {
+		t.Errorf("called CreateRole %d times and CreateFunction %d times, want 6 and 5", calls["CreateRole"], calls["CreateFunction"])
+	}
+	if len(h.Platform.Functions) != 3 {
+		t.Errorf("created %d functions, want 3", len(h.Platform.Functions))
+	}
+	pushed := 0
+	for _, lambda := range lambdas {
+		if h.LambdaManifest("P", lambda).IsDeployed() {
+			pushed++
+		}
+	}
+	if pushed != 3 {
+		t.Errorf("%d lambdas saved as pushed, want 3", pushed)
+	}
+
+	// Each lambda's output is labelled with its name.
+	tasks := make(map[string]bool)
+	for _, e := range o.Events() {
+		tasks[e.Task] = true
+	}
+	for _, lambda := range lambdas {
+		if !tasks[lambda] {
+			t.Errorf("no output from task %s", lambda)
+		}
+	}
+
+	// Pushing again finishes the job.
+	o = output.NewForTesting()
+	err = push_project.PushProjectCommand{
+		EcologyManifest: h.EcologyManifest(),
+		Project:         "P",
+		Parallelism:     4,
+		Platform:        h.Platform,
+	}.Execute(o)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(h.Platform.Functions) != 6 {
+		t.Errorf("created %d functions, want all 6", len(h.Platform.Functions))
+	}
+}
+
+func TestPlanAndApply(t *testing.T) {
+	h := command_testing.New(t)
+	h.CreateProject("P", "A")
+	planFile := filepath.Join(t.TempDir(), "plan.json")
+	o := output.NewForTesting()
+	err := push_project.PushProjectCommand{
+		EcologyManifest: h.EcologyManifest(),
+		Project:         "P",
+		PlanFile:        planFile,
+		Platform:        h.Platform,
+	}.Execute(o)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(h.Platform.Functions) != 0 || len(h.Platform.Roles) != 0 {
+		t.Fatal("planning changed the platform")
+	}
+	plan, err := plan_manifest.GetPlanManifestFromFile(planFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if action, changed := plan.Changes("lambda", "P-A"); !changed || action.Change != plan_manifest.ChangeCreate {
+		t.Fatalf("plan = %+v, want P-A created", plan)
+	}
+
+	o = output.NewForTesting()
+	err = push_project.PushProjectCommand{
+		EcologyManifest: h.EcologyManifest(),
+		Project:         "P",
+		Apply:           planFile,
+		Platform:        h.Platform,
+	}.Execute(o)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := h.Platform.Functions["P-A"]; !ok || !h.LambdaManifest("P", "A").IsDeployed() {
+		t.Fatal("applying the plan didn't push P-A")
+	}
+
+	// The plan no longer matches once applied.
+	o = output.NewForTesting()
+	err = push_project.PushProjectCommand{
+		EcologyManifest: h.EcologyManifest(),
+		Project:         "P",
+		Apply:           planFile,
+		Platform:        h.Platform,
+	}.Execute(o)
+	command_testing.AssertError(t, o, err, "Plan is out of date")
+}
+
+func TestPlanWhilePushing(t *testing.T) {
+	h := command_testing.New(t)
+	h.CreateProject("P", "A")
+	em := h.EcologyManifest()
+	pushing, err := em.GetProjectManifestForUpdate("P")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pushing.Unlock()
+
+	o := output.NewForTesting()
+	err = push_project.PushProjectCommand{
+		EcologyManifest: h.EcologyManifest(),
+		Project:         "P",
+		Plan:            true,
+		Platform:        h.Platform,
+	}.Execute(o)
+	if err != nil {
+		t.Fatal(err)
+	}
+	command_testing.AssertOutput(t, o, "info", "Plan for Project P:")
+}
+
+func TestPushProjectFailures(t *testing.T) {
+	h := command_testing.New(t)
+	h.CreateProject("P", "A")
+	tests := map[string]push_project.PushProjectCommand{
+		"--project=Q doesn't exist":         {Project: "Q"},
+		"Can't set both --plan and --apply": {Project: "P", Plan: true, Apply: "plan.json"},
+		"--parallelism can't be negative":   {Project: "P", Parallelism: -1},
+	}
+	for message, ppc := range tests {
+		o := output.NewForTesting()
+		ppc.EcologyManifest = h.EcologyManifest()
+		ppc.Platform = h.Platform
+		command_testing.AssertError(t, o, ppc.Execute(o), message)
+	}
+
+	h.Platform.FailNext("GetRole", fake_platform.NewServiceError("ServiceFailure", http.StatusInternalServerError, "injected"))
+	o := output.NewForTesting()
+	err := push_project.PushProjectCommand{
+		EcologyManifest: h.EcologyManifest(),
+		Project:         "P",
+		Plan:            true,
+		Platform:        h.Platform,
+	}.Execute(o)
+	command_testing.AssertError(t, o, err, "injected")
+}
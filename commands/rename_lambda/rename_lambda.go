@@ -0,0 +1,56 @@
+package rename_lambda
+
+import (
+	"github.com/gbdubs/ecology/manifests/ecology_manifest"
+	"github.com/gbdubs/ecology/platforms/platform"
+	"github.com/gbdubs/ecology/platforms/platform_factory"
+	"github.com/gbdubs/ecology/util/flag_validation"
+	"github.com/gbdubs/ecology/util/output"
+)
+
+type RenameLambdaCommand struct {
+	EcologyManifest ecology_manifest.EcologyManifest
+	Project         string
+	Lambda          string
+	NewLambda       string
+	Platform        platform.Platform
+}
+
+func (rlc RenameLambdaCommand) Execute(o *output.Output) (err error) {
+	em := &rlc.EcologyManifest
+	pm, err := em.GetProjectManifestForUpdate(rlc.Project)
+	defer pm.Unlock()
+	err = flag_validation.ValidateAll(
+		flag_validation.Project(rlc.Project),
+		flag_validation.ProjectExists(rlc.Project, em),
+		flag_validation.Lambda(rlc.Lambda),
+		flag_validation.LambdaExists(rlc.Lambda, pm),
+		flag_validation.NewLambda(rlc.NewLambda),
+		flag_validation.LambdaDoesNotExist(rlc.NewLambda, pm),
+		err)
+	if err != nil {
+		o.Error(err)
+		return err
+	}
+	p := rlc.Platform
+	if p == nil {
+		p, err = platform_factory.New(pm.Deploy.Platform, pm.Deploy.Region)
+		if err != nil {
+			o.Error(err)
+			return
+		}
+	}
+
+	o.Info("RenameLambdaCommand - %s.RenameLambda", rlc.Project).Indent()
+	err = pm.RenameLambda(rlc.Lambda, rlc.NewLambda, p, o)
+	if err != nil {
+		o.Error(err)
+		o.Warning("Rerun rename_lambda with the same flags to resume the rename.")
+		return
+	}
+	o.Dedent().Done()
+	if lm, lambdaErr := pm.GetLambdaManifest(rlc.NewLambda); lambdaErr == nil {
+		o.Result("Lambda", lm.Summary())
+	}
+	return nil
+}
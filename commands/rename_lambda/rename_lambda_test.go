@@ -0,0 +1,108 @@
+package rename_lambda_test
+
+import (
+	"github.com/gbdubs/ecology/commands/command_testing"
+	"github.com/gbdubs/ecology/commands/rename_lambda"
+	"github.com/gbdubs/ecology/manifests/lambda_manifest"
+	"github.com/gbdubs/ecology/platforms/fake_platform"
+	"github.com/gbdubs/ecology/util/output"
+	"net/http"
+	"os"
+	"testing"
+)
+
+func TestRenameLambda(t *testing.T) {
+	h := command_testing.New(t)
+	h.CreateProject("P", "A")
+	h.AddRoute("P", "GET", "/a", "A")
+	h.Push("P")
+	o := output.NewForTesting()
+	err := rename_lambda.RenameLambdaCommand{
+		EcologyManifest: h.EcologyManifest(),
+		Project:         "P",
+		Lambda:          "A",
+		NewLambda:       "B",
+		Platform:        h.Platform,
+	}.Execute(o)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := h.Platform.Functions["P-A"]; ok {
+		t.Error("P-A is still on the platform")
+	}
+	if _, ok := h.Platform.Roles["P-A-executor"]; ok {
+		t.Error("P-A-executor is still on the platform")
+	}
+	lm := h.LambdaManifest("P", "B")
+	f, ok := h.Platform.Functions["P-B"]
+	if !ok || lm.Deploy.Arn != f.Config.Arn || lm.Deploy.RenamedFrom != "P-A" {
+		t.Fatalf("saved deploy info of B = %+v, want P-B deployed", lm.Deploy)
+	}
+	if _, err := os.Stat(lm.Config.CodePath); err != nil {
+		t.Errorf("source wasn't moved: %v", err)
+	}
+	pm := h.ProjectManifest("P")
+	if routes := pm.ApiManifest.Config.Routes; len(routes) != 1 || routes[0].Lambda != "B" {
+		t.Errorf("saved routes = %+v, want GET /a routed to B", routes)
+	}
+	for _, route := range h.Platform.Apis[pm.ApiManifest.Deploy.ApiId].Routes {
+		if route.FunctionArn != lm.Deploy.AliasArn {
+			t.Errorf("route %s invokes %s, want %s", route.RouteKey, route.FunctionArn, lm.Deploy.AliasArn)
+		}
+	}
+	if summary := o.Results()["Lambda"].(lambda_manifest.LambdaSummary); summary.FunctionName != "P-B" {
+		t.Errorf("Lambda result = %+v", summary)
+	}
+}
+
+func TestRenameLambdaResumesAfterFailure(t *testing.T) {
+	h := command_testing.New(t)
+	h.CreateProject("P", "A")
+	h.Push("P")
+	h.Platform.FailNext("DeleteFunction", fake_platform.NewServiceError("ServiceException", http.StatusInternalServerError, "injected"))
+	rlc := rename_lambda.RenameLambdaCommand{
+		EcologyManifest: h.EcologyManifest(),
+		Project:         "P",
+		Lambda:          "A",
+		NewLambda:       "B",
+		Platform:        h.Platform,
+	}
+	o := output.NewForTesting()
+	command_testing.AssertError(t, o, rlc.Execute(o), "injected")
+	command_testing.AssertOutput(t, o, "warning", "Rerun rename_lambda with the same flags to resume the rename.")
+	if _, ok := h.Platform.Functions["P-A"]; !ok {
+		t.Fatal("P-A was deleted")
+	}
+
+	o = output.NewForTesting()
+	rlc.EcologyManifest = h.EcologyManifest()
+	if err := rlc.Execute(o); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := h.Platform.Functions["P-A"]; ok || len(h.Platform.Functions) != 1 {
+		t.Errorf("functions = %v, want only P-B", h.Platform.Functions)
+	}
+	if lm := h.LambdaManifest("P", "B"); !lm.IsDeployed() {
+		t.Errorf("saved deploy info of B = %+v, want it deployed", lm.Deploy)
+	}
+}
+
+func TestRenameLambdaFailures(t *testing.T) {
+	h := command_testing.New(t)
+	h.CreateProject("P", "A", "B")
+	tests := map[string]rename_lambda.RenameLambdaCommand{
+		"--lambda=Z doesn't exist":  {Lambda: "Z", NewLambda: "C"},
+		"--lambda=B already exists": {Lambda: "A", NewLambda: "B"},
+		"Must set --new_lambda":     {Lambda: "A"},
+	}
+	for message, rlc := range tests {
+		o := output.NewForTesting()
+		rlc.EcologyManifest = h.EcologyManifest()
+		rlc.Project = "P"
+		rlc.Platform = h.Platform
+		command_testing.AssertError(t, o, rlc.Execute(o), message)
+	}
+	if len(h.ProjectManifest("P").LambdaManifests) != 2 || len(h.Platform.Calls) != 0 {
+		t.Error("a rejected rename changed something")
+	}
+}
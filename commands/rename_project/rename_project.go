@@ -0,0 +1,69 @@
+package rename_project
+
+import (
+	"github.com/gbdubs/ecology/manifests/ecology_manifest"
+	"github.com/gbdubs/ecology/platforms/platform"
+	"github.com/gbdubs/ecology/platforms/platform_factory"
+	"github.com/gbdubs/ecology/util/flag_validation"
+	"github.com/gbdubs/ecology/util/output"
+)
+
+type RenameProjectCommand struct {
+	EcologyManifest ecology_manifest.EcologyManifest
+	Project         string
+	NewProject      string
+	Platform        platform.Platform
+}
+
+func (rpc RenameProjectCommand) Execute(o *output.Output) (err error) {
+	em := &rpc.EcologyManifest
+	err = flag_validation.ValidateAll(
+		flag_validation.Project(rpc.Project),
+		flag_validation.ProjectExists(rpc.Project, em),
+		flag_validation.NewProject(rpc.NewProject),
+		flag_validation.ProjectDoesNotExist(rpc.NewProject, em),
+		err)
+	if err != nil {
+		o.Error(err)
+		return err
+	}
+	pm, err := em.GetProjectManifestForUpdate(rpc.Project)
+	if err != nil {
+		o.Error(err)
+		return
+	}
+	defer pm.Unlock()
+	p := rpc.Platform
+	if p == nil {
+		p, err = platform_factory.New(pm.Deploy.Platform, pm.Deploy.Region)
+		if err != nil {
+			o.Error(err)
+			return
+		}
+	}
+
+	// A previous run may have renamed the project but failed to save the
+	// ecology manifest, in which case only that is left to do.
+	if pm.Config.Name != rpc.NewProject {
+		o.Info("RenameProjectCommand - %s.Rename", rpc.Project).Indent()
+		err = pm.Rename(rpc.NewProject, p, o)
+		if err != nil {
+			o.Error(err)
+			o.Warning("Rerun rename_project with the same flags to resume the rename.")
+			return
+		}
+		o.Dedent().Done()
+	}
+
+	o.Info("RenameProjectCommand - EcologyManifest.Update").Indent()
+	err = em.Update(o, func(em *ecology_manifest.EcologyManifest) {
+		em.RenameProject(rpc.Project, rpc.NewProject)
+	})
+	if err != nil {
+		o.Error(err)
+		return
+	}
+	o.Dedent().Done()
+	o.Result("Project", pm.Summary())
+	return nil
+}
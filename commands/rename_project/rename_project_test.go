@@ -0,0 +1,105 @@
+package rename_project_test
+
+import (
+	"github.com/gbdubs/ecology/commands/command_testing"
+	"github.com/gbdubs/ecology/commands/rename_project"
+	"github.com/gbdubs/ecology/manifests/project_manifest"
+	"github.com/gbdubs/ecology/platforms/fake_platform"
+	"github.com/gbdubs/ecology/util/output"
+	"net/http"
+	"testing"
+)
+
+func TestRenameProject(t *testing.T) {
+	h := command_testing.New(t)
+	h.CreateProject("P", "A", "B")
+	h.Push("P")
+	o := output.NewForTesting()
+	err := rename_project.RenameProjectCommand{
+		EcologyManifest: h.EcologyManifest(),
+		Project:         "P",
+		NewProject:      "Q",
+		Platform:        h.Platform,
+	}.Execute(o)
+	if err != nil {
+		t.Fatal(err)
+	}
+	em := h.EcologyManifest()
+	if _, ok := em.ProjectManifestPaths["P"]; ok || em.ProjectManifestPaths["Q"] == "" {
+		t.Fatalf("projects = %v, want only Q", em.ProjectManifestPaths)
+	}
+	pm := h.ProjectManifest("Q")
+	if pm.Config.Name != "Q" || pm.Config.RenamingTo != "" {
+		t.Errorf("saved config = %+v", pm.Config)
+	}
+	for _, lambda := range []string{"A", "B"} {
+		lm := h.LambdaManifest("Q", lambda)
+		if f, ok := h.Platform.Functions["Q-"+lambda]; !ok || lm.Deploy.Arn != f.Config.Arn {
+			t.Errorf("saved deploy info of %s = %+v, want Q-%s deployed", lambda, lm.Deploy, lambda)
+		}
+		if _, ok := h.Platform.Functions["P-"+lambda]; ok {
+			t.Errorf("P-%s is still on the platform", lambda)
+		}
+	}
+	if summary := o.Results()["Project"].(project_manifest.ProjectSummary); summary.Name != "Q" {
+		t.Errorf("Project result = %+v", summary)
+	}
+}
+
+func TestRenameProjectResumesAfterFailure(t *testing.T) {
+	h := command_testing.New(t)
+	h.CreateProject("P", "A", "B")
+	h.Push("P")
+	// A is renamed, and then creating Q-B fails.
+	h.Platform.FailNext("CreateFunction", nil)
+	h.Platform.FailNext("CreateFunction", fake_platform.NewServiceError("ServiceException", http.StatusInternalServerError, "injected"))
+	rpc := rename_project.RenameProjectCommand{
+		EcologyManifest: h.EcologyManifest(),
+		Project:         "P",
+		NewProject:      "Q",
+		Platform:        h.Platform,
+	}
+	o := output.NewForTesting()
+	command_testing.AssertError(t, o, rpc.Execute(o), "injected")
+	command_testing.AssertOutput(t, o, "warning", "Rerun rename_project with the same flags to resume the rename.")
+	pm := h.ProjectManifest("P")
+	if pm.Config.RenamingTo != "Q" || pm.LambdaManifests[0].Config.FullyQualifiedName != "Q-A" || pm.LambdaManifests[1].Config.FullyQualifiedName != "P-B" {
+		t.Fatalf("saved manifest = %+v, want A renamed and B not", pm)
+	}
+
+	o = output.NewForTesting()
+	rpc.EcologyManifest = h.EcologyManifest()
+	if err := rpc.Execute(o); err != nil {
+		t.Fatal(err)
+	}
+	command_testing.AssertOutput(t, o, "info", "Lambda A was already renamed.")
+	if len(h.Platform.Functions) != 2 || h.Platform.Functions["Q-B"] == nil {
+		t.Errorf("functions = %v, want Q-A and Q-B", h.Platform.Functions)
+	}
+	if pm := h.ProjectManifest("Q"); pm.Config.Name != "Q" {
+		t.Errorf("saved config = %+v", pm.Config)
+	}
+}
+
+func TestRenameProjectFailures(t *testing.T) {
+	h := command_testing.New(t)
+	h.CreateProject("P", "A")
+	h.CreateProject("Q")
+	tests := map[string]string{
+		"--project=Q already exists": "Q",
+		"Must set --new_project":     "",
+	}
+	for message, newProject := range tests {
+		o := output.NewForTesting()
+		err := rename_project.RenameProjectCommand{
+			EcologyManifest: h.EcologyManifest(),
+			Project:         "P",
+			NewProject:      newProject,
+			Platform:        h.Platform,
+		}.Execute(o)
+		command_testing.AssertError(t, o, err, message)
+	}
+	if pm := h.ProjectManifest("P"); pm.Config.Name != "P" {
+		t.Errorf("saved config = %+v", pm.Config)
+	}
+}
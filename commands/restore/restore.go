@@ -0,0 +1,43 @@
+package restore
+
+import (
+	"github.com/gbdubs/ecology/manifests/ecology_manifest"
+	"github.com/gbdubs/ecology/util/flag_validation"
+	"github.com/gbdubs/ecology/util/output"
+)
+
+type RestoreCommand struct {
+	EcologyManifest ecology_manifest.EcologyManifest
+	Project         string
+	Snapshot        string
+}
+
+func (rc RestoreCommand) Execute(o *output.Output) (err error) {
+	em := &rc.EcologyManifest
+	err = flag_validation.ValidateAll(
+		flag_validation.Project(rc.Project),
+		flag_validation.ProjectExists(rc.Project, em),
+		flag_validation.Snapshot(rc.Snapshot),
+		err)
+	if err != nil {
+		o.Error(err)
+		return err
+	}
+	pm, err := em.GetProjectManifestForUpdate(rc.Project)
+	if err != nil {
+		o.Error(err)
+		return
+	}
+	defer pm.Unlock()
+
+	o.Info("RestoreCommand - %s.Restore", rc.Project).Indent()
+	err = pm.Restore(rc.Snapshot, o)
+	if err != nil {
+		o.Error(err)
+		return
+	}
+	o.Dedent().Done()
+	o.Result("Project", pm.Summary())
+	o.Warning("The restored manifest may not match what is deployed, check it with `ecology drift --project=%s`.", rc.Project)
+	return nil
+}
@@ -0,0 +1,64 @@
+package restore_test
+
+import (
+	"github.com/gbdubs/ecology/commands/command_testing"
+	"github.com/gbdubs/ecology/commands/restore"
+	"github.com/gbdubs/ecology/manifests/project_manifest"
+	"github.com/gbdubs/ecology/util/manifest_history"
+	"github.com/gbdubs/ecology/util/output"
+	"testing"
+)
+
+func TestRestore(t *testing.T) {
+	h := command_testing.New(t)
+	h.CreateProject("P", "A")
+	snapshots, err := manifest_history.List(h.ProjectManifest("P").HistoryDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	h.Push("P")
+	o := output.NewForTesting()
+	err = restore.RestoreCommand{
+		EcologyManifest: h.EcologyManifest(),
+		Project:         "P",
+		Snapshot:        snapshots[0].Id,
+	}.Execute(o)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lm := h.LambdaManifest("P", "A"); lm.IsDeployed() {
+		t.Errorf("restored deploy info = %+v, want it from before the push", lm.Deploy)
+	}
+	if summary := o.Results()["Project"].(project_manifest.ProjectSummary); len(summary.Lambdas) != 1 || summary.Lambdas[0].Arn != "" {
+		t.Errorf("Project result = %+v", summary)
+	}
+	command_testing.AssertOutput(t, o, "warning", "check it with `ecology drift --project=P`")
+}
+
+func TestRestoreFailures(t *testing.T) {
+	h := command_testing.New(t)
+	h.CreateProject("P", "A")
+	h.CreateProject("Q")
+	other, err := manifest_history.List(h.ProjectManifest("Q").HistoryDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Snapshot ids are unique to their project.
+	tests := map[string]string{
+		"Must set --snapshot":           "",
+		`"latest" is not a snapshot id`: "latest",
+		"No snapshot " + other[0].Id:    other[0].Id,
+	}
+	for message, snapshot := range tests {
+		o := output.NewForTesting()
+		err := restore.RestoreCommand{
+			EcologyManifest: h.EcologyManifest(),
+			Project:         "P",
+			Snapshot:        snapshot,
+		}.Execute(o)
+		command_testing.AssertError(t, o, err, message)
+	}
+	if n := len(h.ProjectManifest("P").LambdaManifests); n != 1 {
+		t.Errorf("project has %d lambdas after failed restores, want 1", n)
+	}
+}
@@ -0,0 +1,60 @@
+package rollback_lambda
+
+import (
+	"github.com/gbdubs/ecology/manifests/ecology_manifest"
+	"github.com/gbdubs/ecology/platforms/platform"
+	"github.com/gbdubs/ecology/platforms/platform_factory"
+	"github.com/gbdubs/ecology/util/flag_validation"
+	"github.com/gbdubs/ecology/util/output"
+	"strconv"
+)
+
+type RollbackLambdaCommand struct {
+	EcologyManifest ecology_manifest.EcologyManifest
+	Project         string
+	Lambda          string
+	// 0 for the version before the one that the alias points at now.
+	ToVersion int
+	Platform  platform.Platform
+}
+
+func (rlc RollbackLambdaCommand) Execute(o *output.Output) (err error) {
+	em := &rlc.EcologyManifest
+	pm, err := em.GetProjectManifestForUpdate(rlc.Project)
+	defer pm.Unlock()
+	err = flag_validation.ValidateAll(
+		flag_validation.Project(rlc.Project),
+		flag_validation.ProjectExists(rlc.Project, em),
+		flag_validation.Lambda(rlc.Lambda),
+		flag_validation.LambdaExists(rlc.Lambda, pm),
+		flag_validation.ToVersion(rlc.ToVersion),
+		err)
+	if err != nil {
+		o.Error(err)
+		return err
+	}
+	p := rlc.Platform
+	if p == nil {
+		p, err = platform_factory.New(pm.Deploy.Platform, pm.Deploy.Region)
+		if err != nil {
+			o.Error(err)
+			return
+		}
+	}
+	toVersion := ""
+	if rlc.ToVersion > 0 {
+		toVersion = strconv.Itoa(rlc.ToVersion)
+	}
+
+	o.Info("RollbackLambdaCommand - %s.RollbackLambda", rlc.Project).Indent()
+	err = pm.RollbackLambda(rlc.Lambda, toVersion, p, o)
+	if err != nil {
+		o.Error(err)
+		return
+	}
+	o.Dedent().Done()
+	if lm, lambdaErr := pm.GetLambdaManifest(rlc.Lambda); lambdaErr == nil {
+		o.Result("Lambda", lm.Summary())
+	}
+	return nil
+}
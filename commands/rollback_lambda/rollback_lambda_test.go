@@ -0,0 +1,112 @@
+package rollback_lambda_test
+
+import (
+	"github.com/gbdubs/ecology/commands/command_testing"
+	"github.com/gbdubs/ecology/commands/rollback_lambda"
+	"github.com/gbdubs/ecology/manifests/lambda_manifest"
+	"github.com/gbdubs/ecology/platforms/fake_platform"
+	"github.com/gbdubs/ecology/util/output"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+// pushTwice pushes A as version 1, and changed as version 2.
+func pushTwice(t *testing.T) *command_testing.Home {
+	h := command_testing.New(t)
+	h.CreateProject("P", "A")
+	h.AddRoute("P", "GET", "/a", "A")
+	h.Push("P")
+	h.WriteSource("P", "A", strings.Replace(command_testing.LambdaSource, "request.Input=", "v2 request.Input=", 1))
+	h.Push("P")
+	if v := h.LambdaManifest("P", "A").Deploy.AliasVersion; v != "2" {
+		t.Fatalf("alias points at version %s after two pushes", v)
+	}
+	return h
+}
+
+func TestRollbackLambda(t *testing.T) {
+	h := pushTwice(t)
+	o := output.NewForTesting()
+	err := rollback_lambda.RollbackLambdaCommand{
+		EcologyManifest: h.EcologyManifest(),
+		Project:         "P",
+		Lambda:          "A",
+		Platform:        h.Platform,
+	}.Execute(o)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v := h.Platform.Functions["P-A"].Aliases["live"].FunctionVersion; v != "1" {
+		t.Errorf("alias points at version %s on the platform, want 1", v)
+	}
+	lm := h.LambdaManifest("P", "A")
+	if lm.Deploy.AliasVersion != "1" {
+		t.Errorf("saved alias version = %s, want 1", lm.Deploy.AliasVersion)
+	}
+	if summary := o.Results()["Lambda"].(lambda_manifest.LambdaSummary); summary.AliasVersion != "1" {
+		t.Errorf("Lambda result = %+v", summary)
+	}
+	command_testing.AssertOutput(t, o, "success", "Alias live Now Points At Version 1, Instead Of 2.")
+
+	// And forward again.
+	o = output.NewForTesting()
+	err = rollback_lambda.RollbackLambdaCommand{
+		EcologyManifest: h.EcologyManifest(),
+		Project:         "P",
+		Lambda:          "A",
+		ToVersion:       2,
+		Platform:        h.Platform,
+	}.Execute(o)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lm := h.LambdaManifest("P", "A"); lm.Deploy.AliasVersion != "2" {
+		t.Errorf("saved alias version = %s, want 2", lm.Deploy.AliasVersion)
+	}
+}
+
+func TestRollbackLambdaFailures(t *testing.T) {
+	h := pushTwice(t)
+	tests := map[string]int{
+		"--to_version can't be negative":                                     -1,
+		"Lambda A has no recorded version 3, its recorded versions are 1, 2": 3,
+	}
+	for message, toVersion := range tests {
+		o := output.NewForTesting()
+		err := rollback_lambda.RollbackLambdaCommand{
+			EcologyManifest: h.EcologyManifest(),
+			Project:         "P",
+			Lambda:          "A",
+			ToVersion:       toVersion,
+			Platform:        h.Platform,
+		}.Execute(o)
+		command_testing.AssertError(t, o, err, message)
+	}
+
+	h.Platform.FailNext("PutAlias", fake_platform.NewServiceError("ServiceException", http.StatusInternalServerError, "injected"))
+	o := output.NewForTesting()
+	err := rollback_lambda.RollbackLambdaCommand{
+		EcologyManifest: h.EcologyManifest(),
+		Project:         "P",
+		Lambda:          "A",
+		Platform:        h.Platform,
+	}.Execute(o)
+	command_testing.AssertError(t, o, err, "injected")
+	if lm := h.LambdaManifest("P", "A"); lm.Deploy.AliasVersion != "2" {
+		t.Errorf("saved alias version = %s after a failed rollback, want 2", lm.Deploy.AliasVersion)
+	}
+}
+
+func TestRollbackUnpushedLambda(t *testing.T) {
+	h := command_testing.New(t)
+	h.CreateProject("P", "A")
+	o := output.NewForTesting()
+	err := rollback_lambda.RollbackLambdaCommand{
+		EcologyManifest: h.EcologyManifest(),
+		Project:         "P",
+		Lambda:          "A",
+		Platform:        h.Platform,
+	}.Execute(o)
+	command_testing.AssertError(t, o, err, "Lambda A has no alias to roll back yet, push it first")
+}
@@ -0,0 +1,142 @@
+package secret
+
+import (
+	"errors"
+	"fmt"
+	"github.com/gbdubs/ecology/manifests/ecology_manifest"
+	"github.com/gbdubs/ecology/util/flag_validation"
+	"github.com/gbdubs/ecology/util/output"
+	"github.com/gbdubs/ecology/util/secret_store"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+)
+
+const usage = "usage: ecology secret set|get|list|rm --project=P [NAME]"
+
+type SecretCommand struct {
+	EcologyManifest ecology_manifest.EcologyManifest
+	Project         string
+	// One of "set", "get", "list" or "rm".
+	Action string
+	Name   string
+	// Where set reads the value from, os.Stdin if nil, so that it never ends up
+	// in shell history.
+	In io.Reader
+}
+
+func (sc SecretCommand) Execute(o *output.Output) (err error) {
+	em := &sc.EcologyManifest
+	validations := []error{
+		flag_validation.Project(sc.Project),
+		flag_validation.ProjectExists(sc.Project, em),
+	}
+	switch sc.Action {
+	case "set", "get", "rm":
+		validations = append(validations, flag_validation.SecretName(sc.Name))
+	case "list":
+	default:
+		validations = append(validations, errors.New(fmt.Sprintf("Unknown secret action %q, %s", sc.Action, usage)))
+	}
+	err = flag_validation.ValidateAll(validations...)
+	if err != nil {
+		o.Error(err)
+		return
+	}
+	// Setting and removing lock the project, so that two writers can't lose
+	// each other's secrets.
+	pm, err := em.GetProjectManifest(sc.Project)
+	if sc.Action == "set" || sc.Action == "rm" {
+		pm, err = em.GetProjectManifestForUpdate(sc.Project)
+	}
+	if err != nil {
+		o.Error(err)
+		return
+	}
+	defer pm.Unlock()
+	store, err := secret_store.Open(pm.SecretsPath())
+	if err != nil {
+		o.Error(err)
+		return
+	}
+
+	switch sc.Action {
+	case "set":
+		err = sc.set(store, o)
+	case "get":
+		err = sc.get(store, o)
+	case "list":
+		o.Info("SecretCommand - Listing Secrets of Project %s", sc.Project).Indent()
+		names := store.Names()
+		for _, name := range names {
+			o.Info("%s", name)
+		}
+		o.Result("Secrets", names)
+		o.Dedent().Done()
+	case "rm":
+		o.Info("SecretCommand - Removing Secret %s from Project %s", sc.Name, sc.Project).Indent()
+		if err = store.Remove(sc.Name); err == nil {
+			err = store.Save()
+		}
+		if err != nil {
+			o.Error(err)
+			return
+		}
+		o.Warning("Lambdas that use %s will fail to push until it is set again.", sc.Name)
+		o.Dedent().Done()
+	}
+	return
+}
+
+func (sc SecretCommand) set(store *secret_store.Store, o *output.Output) (err error) {
+	o.Info("SecretCommand - Setting Secret %s of Project %s", sc.Name, sc.Project).Indent()
+	passphrase, err := secret_store.Passphrase()
+	if err != nil {
+		o.Error(err)
+		return
+	}
+	in := sc.In
+	if in == nil {
+		in = os.Stdin
+	}
+	data, err := ioutil.ReadAll(in)
+	if err != nil {
+		o.Error(err)
+		return
+	}
+	// Values are usually echoed or typed in, with a newline that isn't part of them.
+	value := strings.TrimSuffix(strings.TrimSuffix(string(data), "\n"), "\r")
+	if value == "" {
+		err = errors.New(fmt.Sprintf("No value for secret %s, pipe it in, e.g. `printf %%s \"$VALUE\" | ecology secret set --project=%s %s`", sc.Name, sc.Project, sc.Name))
+		o.Error(err)
+		return
+	}
+	if err = store.Set(sc.Name, value, passphrase); err == nil {
+		err = store.Save()
+	}
+	if err != nil {
+		o.Error(err)
+		return
+	}
+	o.Success("Set secret %s, lambdas that use it will be updated on their next push.", sc.Name)
+	o.Dedent().Done()
+	return
+}
+
+func (sc SecretCommand) get(store *secret_store.Store, o *output.Output) (err error) {
+	o.Info("SecretCommand - Getting Secret %s of Project %s", sc.Name, sc.Project).Indent()
+	passphrase, err := secret_store.Passphrase()
+	if err != nil {
+		o.Error(err)
+		return
+	}
+	value, err := store.Get(sc.Name, passphrase)
+	if err != nil {
+		o.Error(err)
+		return
+	}
+	o.Reveal("Value", value)
+	o.Dedent().Done()
+	return
+}
@@ -0,0 +1,128 @@
+package secret_test
+
+import (
+	"github.com/gbdubs/ecology/commands/command_testing"
+	"github.com/gbdubs/ecology/commands/secret"
+	"github.com/gbdubs/ecology/manifests/project_manifest"
+	"github.com/gbdubs/ecology/util/output"
+	"github.com/gbdubs/ecology/util/secret_store"
+	"io/ioutil"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func run(h *command_testing.Home, action string, name string, value string) (*output.Output, error) {
+	o := output.NewForTesting()
+	err := secret.SecretCommand{
+		EcologyManifest: h.EcologyManifest(),
+		Project:         "P",
+		Action:          action,
+		Name:            name,
+		In:              strings.NewReader(value),
+	}.Execute(o)
+	return o, err
+}
+
+func TestSecrets(t *testing.T) {
+	t.Setenv(secret_store.PassphraseVariable, "passphrase")
+	h := command_testing.New(t)
+	h.CreateProject("P", "A")
+	o, err := run(h, "set", "api-key", "s3cret\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	command_testing.AssertOutput(t, o, "success", "Set secret api-key")
+	if _, err = run(h, "set", "other", "value"); err != nil {
+		t.Fatal(err)
+	}
+	secretsPath := h.ProjectManifest("P").SecretsPath()
+	if data, _ := ioutil.ReadFile(secretsPath); strings.Contains(string(data), "s3cret") {
+		t.Fatalf("%s has the secret in the clear:\n%s", secretsPath, data)
+	}
+
+	if o, err = run(h, "get", "api-key", ""); err != nil {
+		t.Fatal(err)
+	}
+	if got := o.Results()["Value"]; got != "s3cret" {
+		t.Errorf("Value result = %q, want the value without its newline", got)
+	}
+	for _, e := range o.Events() {
+		if strings.Contains(e.Message, "s3cret") {
+			t.Errorf("output revealed the secret: %s", e.Message)
+		}
+	}
+
+	if o, err = run(h, "list", "", ""); err != nil {
+		t.Fatal(err)
+	}
+	if got := o.Results()["Secrets"]; !reflect.DeepEqual(got, []string{"api-key", "other"}) {
+		t.Errorf("Secrets result = %v", got)
+	}
+
+	if o, err = run(h, "rm", "other", ""); err != nil {
+		t.Fatal(err)
+	}
+	command_testing.AssertOutput(t, o, "warning", "Lambdas that use other will fail to push until it is set again.")
+	store, err := secret_store.Open(secretsPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if names := store.Names(); !reflect.DeepEqual(names, []string{"api-key"}) {
+		t.Errorf("saved secrets = %v, want only api-key", names)
+	}
+}
+
+func TestPushedSecrets(t *testing.T) {
+	t.Setenv(secret_store.PassphraseVariable, "passphrase")
+	h := command_testing.New(t)
+	h.CreateProject("P", "A")
+	if _, err := run(h, "set", "api-key", "s3cret"); err != nil {
+		t.Fatal(err)
+	}
+	h.UpdateProjectManifest("P", func(pm *project_manifest.ProjectManifest) {
+		pm.LambdaManifests[0].Config.Secrets = map[string]string{"API_KEY": "api-key"}
+	})
+	h.Push("P")
+	if got := h.Platform.Functions["P-A"].Config.Environment["API_KEY"]; got != "s3cret" {
+		t.Errorf("API_KEY = %q on the platform, want the secret", got)
+	}
+	if data, _ := ioutil.ReadFile(h.ProjectManifest("P").Config.ManifestPath); strings.Contains(string(data), "s3cret") {
+		t.Errorf("the project manifest has the secret in it:\n%s", data)
+	}
+}
+
+func TestSecretFailures(t *testing.T) {
+	t.Setenv(secret_store.PassphraseVariable, "passphrase")
+	h := command_testing.New(t)
+	h.CreateProject("P")
+	if _, err := run(h, "set", "api-key", "s3cret"); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		action  string
+		name    string
+		value   string
+		message string
+	}{
+		{"bogus", "", "", `Unknown secret action "bogus"`},
+		{"get", "", "", "Must give the name of the secret"},
+		{"get", "missing", "", "No secret named missing"},
+		{"rm", "missing", "", "No secret named missing"},
+		{"set", "empty", "\n", "No value for secret empty"},
+	}
+	for _, test := range tests {
+		o, err := run(h, test.action, test.name, test.value)
+		command_testing.AssertError(t, o, err, test.message)
+	}
+
+	t.Setenv(secret_store.PassphraseVariable, "wrong")
+	o, err := run(h, "get", "api-key", "")
+	command_testing.AssertError(t, o, err, "Couldn't decrypt secret api-key")
+	o, err = run(h, "set", "other", "value")
+	command_testing.AssertError(t, o, err, "Couldn't decrypt secret api-key")
+
+	t.Setenv(secret_store.PassphraseVariable, "")
+	o, err = run(h, "set", "other", "value")
+	command_testing.AssertError(t, o, err, "Set "+secret_store.PassphraseVariable)
+}
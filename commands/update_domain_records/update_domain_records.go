@@ -0,0 +1,78 @@
+package update_domain_records
+
+import (
+	"errors"
+	"fmt"
+	"github.com/gbdubs/ecology/dns_providers/dns_provider"
+	"github.com/gbdubs/ecology/dns_providers/dns_provider_factory"
+	"github.com/gbdubs/ecology/manifests/ecology_manifest"
+	"github.com/gbdubs/ecology/platforms/platform"
+	"github.com/gbdubs/ecology/platforms/platform_factory"
+	"github.com/gbdubs/ecology/util/flag_validation"
+	"github.com/gbdubs/ecology/util/output"
+)
+
+type UpdateDomainRecordsCommand struct {
+	EcologyManifest ecology_manifest.EcologyManifest
+	Project         string
+	Platform        platform.Platform
+	DnsProvider     dns_provider.DnsProvider
+}
+
+func (udrc UpdateDomainRecordsCommand) Execute(o *output.Output) (err error) {
+	em := &udrc.EcologyManifest
+	err = flag_validation.ValidateAll(
+		flag_validation.Project(udrc.Project),
+		flag_validation.ProjectExists(udrc.Project, em),
+		err)
+	if err != nil {
+		o.Error(err)
+		return err
+	}
+	pm, err := em.GetProjectManifestForUpdate(udrc.Project)
+	if err != nil {
+		o.Error(err)
+		return
+	}
+	defer pm.Unlock()
+	if !pm.RoutingManifest.IsInitialized() {
+		err = errors.New(fmt.Sprintf("--project=%s has no routing, run initialize_routing first", udrc.Project))
+		o.Error(err)
+		return err
+	}
+	p := udrc.Platform
+	if p == nil {
+		p, err = platform_factory.New(pm.Deploy.Platform, pm.Deploy.Region)
+		if err != nil {
+			o.Error(err)
+			return
+		}
+	}
+	dp := udrc.DnsProvider
+	if dp == nil {
+		rc := pm.RoutingManifest.Config
+		dp, err = dns_provider_factory.New(rc.Registrar, rc.Server, rc.ZoneFilePath)
+		if err != nil {
+			o.Error(err)
+			return
+		}
+	}
+
+	o.Info("UpdateDomainRecordsCommand - %s.UpdateDomainRecords", udrc.Project).Indent()
+	err = pm.UpdateDomainRecords(p, dp, o)
+	if err != nil {
+		o.Error(err)
+		return
+	}
+	o.Dedent().Done()
+
+	o.Info("UpdateDomainRecordsCommand - %s.Save", udrc.Project).Indent()
+	err = pm.Save(o)
+	if err != nil {
+		o.Error(err)
+		return
+	}
+	o.Dedent().Done()
+	o.Result("Records", pm.RoutingManifest.Deploy.Records)
+	return nil
+}
@@ -0,0 +1,94 @@
+package update_domain_records_test
+
+import (
+	"github.com/gbdubs/ecology/commands/command_testing"
+	"github.com/gbdubs/ecology/commands/initialize_routing"
+	"github.com/gbdubs/ecology/commands/update_domain_records"
+	"github.com/gbdubs/ecology/dns_providers/dns_provider"
+	"github.com/gbdubs/ecology/platforms/fake_platform"
+	"github.com/gbdubs/ecology/util/output"
+	"io/ioutil"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// routedProject routes api.example.com to a pushed project through a zone file.
+func routedProject(t *testing.T) (*command_testing.Home, string) {
+	h := command_testing.New(t)
+	h.CreateProject("P", "A")
+	h.AddRoute("P", "GET", "/a", "A")
+	h.Push("P")
+	zoneFile := filepath.Join(t.TempDir(), "example.com.zone")
+	err := initialize_routing.InitializeRoutingCommand{
+		EcologyManifest: h.EcologyManifest(),
+		Project:         "P",
+		Domain:          "api.example.com",
+		Registrar:       "zone_file",
+		ZoneFile:        zoneFile,
+		Platform:        h.Platform,
+	}.Execute(output.NewForTesting())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return h, zoneFile
+}
+
+func TestUpdateDomainRecords(t *testing.T) {
+	h, zoneFile := routedProject(t)
+	// The API's endpoint moves, as it does when it is recreated.
+	apiId := h.ProjectManifest("P").ApiManifest.Deploy.ApiId
+	h.Platform.Apis[apiId].Api.Endpoint = "https://moved.execute-api.us-west-2.amazonaws.com"
+	o := output.NewForTesting()
+	err := update_domain_records.UpdateDomainRecordsCommand{
+		EcologyManifest: h.EcologyManifest(),
+		Project:         "P",
+		Platform:        h.Platform,
+	}.Execute(o)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := dns_provider.Record{Name: "api.example.com", Type: "CNAME", Ttl: 300, Value: "moved.execute-api.us-west-2.amazonaws.com"}
+	pm := h.ProjectManifest("P")
+	if pm.ApiManifest.Deploy.Endpoint != "https://moved.execute-api.us-west-2.amazonaws.com" {
+		t.Errorf("saved endpoint = %s", pm.ApiManifest.Deploy.Endpoint)
+	}
+	if records := pm.RoutingManifest.Deploy.Records; len(records) != 1 || records[0] != want {
+		t.Errorf("saved records = %+v, want %+v", records, want)
+	}
+	zone, _ := ioutil.ReadFile(zoneFile)
+	if strings.Count(string(zone), "CNAME") != 1 || !strings.Contains(string(zone), "CNAME moved.execute-api.us-west-2.amazonaws.com.") {
+		t.Errorf("zone file is\n%s", zone)
+	}
+	if records := o.Results()["Records"].([]dns_provider.Record); len(records) != 1 || records[0] != want {
+		t.Errorf("Records result = %+v", records)
+	}
+	command_testing.AssertOutput(t, o, "info", "UpdateDomainRecordsCommand - P.Save")
+}
+
+func TestUpdateDomainRecordsFailures(t *testing.T) {
+	h := command_testing.New(t)
+	h.CreateProject("P", "A")
+	o := output.NewForTesting()
+	err := update_domain_records.UpdateDomainRecordsCommand{
+		EcologyManifest: h.EcologyManifest(),
+		Project:         "P",
+		Platform:        h.Platform,
+	}.Execute(o)
+	command_testing.AssertError(t, o, err, "--project=P has no routing, run initialize_routing first")
+
+	h, zoneFile := routedProject(t)
+	before, _ := ioutil.ReadFile(zoneFile)
+	h.Platform.FailNext("GetApi", fake_platform.NewServiceError("TooManyRequestsException", http.StatusTooManyRequests, "injected"))
+	o = output.NewForTesting()
+	err = update_domain_records.UpdateDomainRecordsCommand{
+		EcologyManifest: h.EcologyManifest(),
+		Project:         "P",
+		Platform:        h.Platform,
+	}.Execute(o)
+	command_testing.AssertError(t, o, err, "injected")
+	if after, _ := ioutil.ReadFile(zoneFile); string(after) != string(before) {
+		t.Errorf("zone file changed by a failed update:\n%s", after)
+	}
+}
@@ -0,0 +1,58 @@
+package workspace
+
+import (
+	"errors"
+	"fmt"
+	"github.com/gbdubs/ecology/manifests/ecology_manifest"
+	"github.com/gbdubs/ecology/util/ecology_home"
+	"github.com/gbdubs/ecology/util/output"
+	"os"
+)
+
+type WorkspaceCommand struct {
+	EcologyManifest ecology_manifest.EcologyManifest
+	// One of "use" or "list".
+	Action string
+	Name   string
+}
+
+func (wc WorkspaceCommand) Execute(o *output.Output) (err error) {
+	em := &wc.EcologyManifest
+	switch wc.Action {
+	case "", "list":
+		o.Info("Workspaces in %s:", em.Home()).Indent()
+		workspaces := ecology_home.Workspaces(em.Home())
+		o.Result("Workspaces", workspaces)
+		o.Result("Workspace", em.Workspace())
+		for _, w := range workspaces {
+			if w == em.Workspace() {
+				o.Success("%s (current)", w)
+			} else {
+				o.Info("%s", w)
+			}
+		}
+		o.Dedent().Done()
+		return nil
+	case "use":
+		if wc.Name == "" {
+			err = errors.New("Usage: ecology workspace use NAME")
+			o.Error(err)
+			return
+		}
+		o.Info("WorkspaceCommand - Switching to Workspace %s", wc.Name).Indent()
+		if err = ecology_home.UseWorkspace(em.Home(), wc.Name); err != nil {
+			o.Error(err)
+			return
+		}
+		if _, statErr := os.Stat(ecology_home.ManifestPath(em.Home(), wc.Name)); os.IsNotExist(statErr) {
+			o.Warning("Workspace %s is new, run `ecology initialize` to configure it.", wc.Name)
+		}
+		o.Result("Workspace", wc.Name)
+		o.Dedent().Done()
+		return nil
+	default:
+		err = errors.New(fmt.Sprintf("Unknown workspace action %q, should be use or list", wc.Action))
+		o.Error(err)
+		return
+	}
+}
@@ -0,0 +1,68 @@
+package workspace_test
+
+import (
+	"github.com/gbdubs/ecology/commands/command_testing"
+	"github.com/gbdubs/ecology/commands/workspace"
+	"github.com/gbdubs/ecology/util/output"
+	"reflect"
+	"testing"
+)
+
+func TestWorkspaces(t *testing.T) {
+	h := command_testing.New(t)
+	h.CreateProject("P")
+	o := output.NewForTesting()
+	if err := (workspace.WorkspaceCommand{EcologyManifest: h.EcologyManifest(), Action: "use", Name: "staging"}).Execute(o); err != nil {
+		t.Fatal(err)
+	}
+	command_testing.AssertOutput(t, o, "warning", "Workspace staging is new, run `ecology initialize` to configure it.")
+	if got := o.Results()["Workspace"]; got != "staging" {
+		t.Errorf("Workspace result = %v", got)
+	}
+	// Projects are kept apart in each workspace.
+	em := h.EcologyManifest()
+	if em.Workspace() != "staging" || len(em.ProjectManifestPaths) != 0 {
+		t.Fatalf("staging workspace = %s with projects %v", em.Workspace(), em.ProjectManifestPaths)
+	}
+	h.CreateProject("Q")
+
+	o = output.NewForTesting()
+	if err := (workspace.WorkspaceCommand{EcologyManifest: h.EcologyManifest(), Action: "list"}).Execute(o); err != nil {
+		t.Fatal(err)
+	}
+	if got := o.Results()["Workspaces"]; !reflect.DeepEqual(got, []string{"default", "staging"}) {
+		t.Errorf("Workspaces result = %v", got)
+	}
+	command_testing.AssertOutput(t, o, "success", "staging (current)")
+	command_testing.AssertOutput(t, o, "info", "default")
+
+	o = output.NewForTesting()
+	if err := (workspace.WorkspaceCommand{EcologyManifest: h.EcologyManifest(), Action: "use", Name: "default"}).Execute(o); err != nil {
+		t.Fatal(err)
+	}
+	for _, e := range o.Events() {
+		if e.Level == "warning" {
+			t.Errorf("switching back warned: %s", e.Message)
+		}
+	}
+	if em := h.EcologyManifest(); em.ProjectManifestPaths["P"] == "" || em.ProjectManifestPaths["Q"] != "" {
+		t.Errorf("default workspace has projects %v, want only P", em.ProjectManifestPaths)
+	}
+}
+
+func TestWorkspaceFailures(t *testing.T) {
+	h := command_testing.New(t)
+	tests := map[string]workspace.WorkspaceCommand{
+		"Usage: ecology workspace use NAME": {Action: "use"},
+		`Workspace name "../x" should only`: {Action: "use", Name: "../x"},
+		`Unknown workspace action "rm"`:     {Action: "rm", Name: "staging"},
+	}
+	for message, wc := range tests {
+		o := output.NewForTesting()
+		wc.EcologyManifest = h.EcologyManifest()
+		command_testing.AssertError(t, o, wc.Execute(o), message)
+	}
+	if em := h.EcologyManifest(); em.Workspace() != "default" {
+		t.Errorf("workspace = %s after failures, want default", em.Workspace())
+	}
+}
@@ -0,0 +1,33 @@
+package dns_provider
+
+import (
+	"strings"
+)
+
+// DnsProvider manages records in a DNS zone that ecology routes custom
+// domains through. Record names are fully qualified, without a trailing dot.
+type DnsProvider interface {
+	Name() string
+	// UpsertRecord replaces any records with the same name and type.
+	UpsertRecord(zone string, record Record) error
+	DeleteRecord(zone string, record Record) error
+}
+
+type Record struct {
+	Name  string
+	Type  string
+	Ttl   int64
+	Value string
+}
+
+func (r Record) SameRecordSet(other Record) bool {
+	return strings.EqualFold(r.Name, other.Name) && strings.EqualFold(r.Type, other.Type)
+}
+
+// Fqdn adds the trailing dot that DNS wire formats expect.
+func Fqdn(name string) string {
+	if strings.HasSuffix(name, ".") {
+		return name
+	}
+	return name + "."
+}
@@ -0,0 +1,33 @@
+package dns_provider_factory
+
+import (
+	"errors"
+	"fmt"
+	"github.com/gbdubs/ecology/dns_providers/dns_provider"
+	"github.com/gbdubs/ecology/dns_providers/rfc2136_dns_provider"
+	"github.com/gbdubs/ecology/dns_providers/route53_dns_provider"
+	"github.com/gbdubs/ecology/dns_providers/zone_file_dns_provider"
+)
+
+var Registrars = []string{"route53", "rfc2136", "zone_file"}
+
+// New constructs the DnsProvider named by a routing manifest's Registrar.
+func New(registrar string, server string, zoneFilePath string) (dns_provider.DnsProvider, error) {
+	switch registrar {
+	case "route53":
+		return route53_dns_provider.New(), nil
+	case "rfc2136":
+		dp, err := rfc2136_dns_provider.New(server)
+		if err != nil {
+			return nil, err
+		}
+		return dp, nil
+	case "zone_file":
+		dp, err := zone_file_dns_provider.New(zoneFilePath)
+		if err != nil {
+			return nil, err
+		}
+		return dp, nil
+	}
+	return nil, errors.New(fmt.Sprintf("Unknown registrar %s", registrar))
+}
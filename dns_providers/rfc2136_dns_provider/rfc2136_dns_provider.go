@@ -0,0 +1,92 @@
+package rfc2136_dns_provider
+
+import (
+	"errors"
+	"fmt"
+	"github.com/gbdubs/ecology/dns_providers/dns_provider"
+	"github.com/miekg/dns"
+	"net"
+	"os"
+	"time"
+)
+
+// Rfc2136DnsProvider sends RFC2136 dynamic updates to an authoritative DNS
+// server, optionally signed with a TSIG key.
+type Rfc2136DnsProvider struct {
+	Server      string
+	TsigKeyName string
+	TsigSecret  string
+	Timeout     time.Duration
+}
+
+// New builds a provider for the given server (host or host:port), reading the
+// TSIG key from ECOLOGY_TSIG_KEY_NAME and ECOLOGY_TSIG_SECRET if they are set.
+func New(server string) (*Rfc2136DnsProvider, error) {
+	if server == "" {
+		return nil, errors.New("The rfc2136 registrar needs a --dns_server to send updates to")
+	}
+	if _, _, err := net.SplitHostPort(server); err != nil {
+		server = net.JoinHostPort(server, "53")
+	}
+	return &Rfc2136DnsProvider{
+		Server:      server,
+		TsigKeyName: os.Getenv("ECOLOGY_TSIG_KEY_NAME"),
+		TsigSecret:  os.Getenv("ECOLOGY_TSIG_SECRET"),
+		Timeout:     10 * time.Second,
+	}, nil
+}
+
+func (rdp *Rfc2136DnsProvider) Name() string {
+	return "rfc2136"
+}
+
+func (rdp *Rfc2136DnsProvider) UpsertRecord(zone string, record dns_provider.Record) error {
+	rr, err := toRR(record)
+	if err != nil {
+		return err
+	}
+	m := new(dns.Msg)
+	m.SetUpdate(dns_provider.Fqdn(zone))
+	m.RemoveRRset([]dns.RR{rr})
+	m.Insert([]dns.RR{rr})
+	return rdp.exchange(m)
+}
+
+func (rdp *Rfc2136DnsProvider) DeleteRecord(zone string, record dns_provider.Record) error {
+	rr, err := toRR(record)
+	if err != nil {
+		return err
+	}
+	m := new(dns.Msg)
+	m.SetUpdate(dns_provider.Fqdn(zone))
+	m.Remove([]dns.RR{rr})
+	return rdp.exchange(m)
+}
+
+func (rdp *Rfc2136DnsProvider) exchange(m *dns.Msg) error {
+	c := &dns.Client{
+		Net:     "tcp",
+		Timeout: rdp.Timeout,
+	}
+	if rdp.TsigKeyName != "" {
+		keyName := dns_provider.Fqdn(rdp.TsigKeyName)
+		c.TsigSecret = map[string]string{keyName: rdp.TsigSecret}
+		m.SetTsig(keyName, dns.HmacSHA256, 300, time.Now().Unix())
+	}
+	response, _, err := c.Exchange(m, rdp.Server)
+	if err != nil {
+		return err
+	}
+	if response.Rcode != dns.RcodeSuccess {
+		return errors.New(fmt.Sprintf("DNS update rejected by %s: %s", rdp.Server, dns.RcodeToString[response.Rcode]))
+	}
+	return nil
+}
+
+func toRR(record dns_provider.Record) (dns.RR, error) {
+	value := record.Value
+	if record.Type == "CNAME" {
+		value = dns_provider.Fqdn(value)
+	}
+	return dns.NewRR(fmt.Sprintf("%s %d IN %s %s", dns_provider.Fqdn(record.Name), record.Ttl, record.Type, value))
+}
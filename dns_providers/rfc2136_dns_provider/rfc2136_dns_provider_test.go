@@ -0,0 +1,169 @@
+package rfc2136_dns_provider
+
+import (
+	"github.com/gbdubs/ecology/dns_providers/dns_provider"
+	"github.com/miekg/dns"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+const testKeyName = "ecology-key."
+
+// A base64 secret, as TSIG keys are.
+const testSecret = "c2VjcmV0LWtleS1mb3ItZWNvbG9neS10ZXN0cw=="
+
+// testServer is a DNS server that records the updates it is sent, and answers
+// them with rcode, or NOTAUTH if their TSIG doesn't verify.
+type testServer struct {
+	mu      sync.Mutex
+	updates []*dns.Msg
+	rcode   int
+}
+
+func (ts *testServer) serve(w dns.ResponseWriter, r *dns.Msg) {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+	m := new(dns.Msg)
+	m.SetReply(r)
+	m.Rcode = ts.rcode
+	if r.IsTsig() != nil {
+		if w.TsigStatus() != nil {
+			m.Rcode = dns.RcodeNotAuth
+		} else {
+			m.SetTsig(testKeyName, dns.HmacSHA256, 300, time.Now().Unix())
+		}
+	}
+	ts.updates = append(ts.updates, r)
+	w.WriteMsg(m)
+}
+
+func startServer(t *testing.T, rcode int) (*testServer, string) {
+	ts := &testServer{rcode: rcode}
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	started := make(chan struct{})
+	server := &dns.Server{
+		Listener:   listener,
+		Handler:    dns.HandlerFunc(ts.serve),
+		TsigSecret: map[string]string{testKeyName: testSecret},
+		// The default refuses anything but queries and notifies.
+		MsgAcceptFunc: func(dns.Header) dns.MsgAcceptAction {
+			return dns.MsgAccept
+		},
+		NotifyStartedFunc: func() {
+			close(started)
+		},
+	}
+	go server.ActivateAndServe()
+	<-started
+	t.Cleanup(func() {
+		server.Shutdown()
+	})
+	return ts, listener.Addr().String()
+}
+
+func testProvider(server string) *Rfc2136DnsProvider {
+	return &Rfc2136DnsProvider{
+		Server:  server,
+		Timeout: time.Second,
+	}
+}
+
+var testRecord = dns_provider.Record{
+	Name:  "api.example.com",
+	Type:  "CNAME",
+	Ttl:   300,
+	Value: "abc123.execute-api.us-west-2.amazonaws.com",
+}
+
+func TestUpsertRecordReplacesTheRecordSet(t *testing.T) {
+	ts, server := startServer(t, dns.RcodeSuccess)
+	if err := testProvider(server).UpsertRecord("example.com", testRecord); err != nil {
+		t.Fatal(err)
+	}
+	if len(ts.updates) != 1 {
+		t.Fatalf("server got %d updates, want 1", len(ts.updates))
+	}
+	update := ts.updates[0]
+	if update.Opcode != dns.OpcodeUpdate || len(update.Question) != 1 || update.Question[0].Name != "example.com." {
+		t.Fatalf("update = %v, want an update of zone example.com.", update)
+	}
+	if len(update.Ns) != 2 {
+		t.Fatalf("update changes %v, want the record set removed and the record added", update.Ns)
+	}
+	removed, added := update.Ns[0].Header(), update.Ns[1]
+	if removed.Name != "api.example.com." || removed.Rrtype != dns.TypeCNAME || removed.Class != dns.ClassANY {
+		t.Errorf("first change = %v, want the CNAME record set of api.example.com. removed", update.Ns[0])
+	}
+	cname, ok := added.(*dns.CNAME)
+	if !ok || cname.Hdr.Name != "api.example.com." || cname.Hdr.Ttl != 300 || cname.Target != "abc123.execute-api.us-west-2.amazonaws.com." {
+		t.Errorf("second change = %v, want the CNAME added", added)
+	}
+}
+
+func TestDeleteRecordRemovesOnlyThatRecord(t *testing.T) {
+	ts, server := startServer(t, dns.RcodeSuccess)
+	record := dns_provider.Record{Name: "api.example.com", Type: "A", Ttl: 60, Value: "192.0.2.1"}
+	if err := testProvider(server).DeleteRecord("example.com", record); err != nil {
+		t.Fatal(err)
+	}
+	update := ts.updates[0]
+	if len(update.Ns) != 1 {
+		t.Fatalf("update changes %v, want one record removed", update.Ns)
+	}
+	a, ok := update.Ns[0].(*dns.A)
+	if !ok || a.Hdr.Class != dns.ClassNONE || a.A.String() != "192.0.2.1" {
+		t.Errorf("change = %v, want the A record 192.0.2.1 removed", update.Ns[0])
+	}
+}
+
+func TestRejectedUpdate(t *testing.T) {
+	_, server := startServer(t, dns.RcodeRefused)
+	err := testProvider(server).UpsertRecord("example.com", testRecord)
+	if err == nil || !strings.Contains(err.Error(), "DNS update rejected by "+server+": REFUSED") {
+		t.Fatalf("UpsertRecord() = %v, want it rejected", err)
+	}
+}
+
+func TestSignedUpdates(t *testing.T) {
+	ts, server := startServer(t, dns.RcodeSuccess)
+	rdp := testProvider(server)
+	rdp.TsigKeyName = strings.TrimSuffix(testKeyName, ".")
+	rdp.TsigSecret = testSecret
+	if err := rdp.UpsertRecord("example.com", testRecord); err != nil {
+		t.Fatal(err)
+	}
+	if ts.updates[0].IsTsig() == nil {
+		t.Fatal("update wasn't signed")
+	}
+
+	rdp.TsigSecret = "d3Jvbmctc2VjcmV0"
+	if err := rdp.UpsertRecord("example.com", testRecord); err == nil {
+		t.Fatal("UpsertRecord() with the wrong TSIG secret succeeded")
+	}
+}
+
+func TestNew(t *testing.T) {
+	if _, err := New(""); err == nil {
+		t.Error("New() without a server succeeded")
+	}
+	tests := map[string]string{
+		"ns1.example.com":      "ns1.example.com:53",
+		"ns1.example.com:5353": "ns1.example.com:5353",
+		"192.0.2.53":           "192.0.2.53:53",
+	}
+	for server, want := range tests {
+		rdp, err := New(server)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if rdp.Server != want {
+			t.Errorf("New(%q).Server = %q, want %q", server, rdp.Server, want)
+		}
+	}
+}
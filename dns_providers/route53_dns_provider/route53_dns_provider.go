@@ -0,0 +1,75 @@
+package route53_dns_provider
+
+import (
+	"errors"
+	"fmt"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/route53"
+	"github.com/aws/aws-sdk-go/service/route53/route53iface"
+	"github.com/gbdubs/ecology/dns_providers/dns_provider"
+)
+
+type Route53DnsProvider struct {
+	svc route53iface.Route53API
+}
+
+func New() *Route53DnsProvider {
+	return &Route53DnsProvider{
+		svc: route53.New(session.New()),
+	}
+}
+
+func (rdp *Route53DnsProvider) Name() string {
+	return "route53"
+}
+
+func (rdp *Route53DnsProvider) UpsertRecord(zone string, record dns_provider.Record) error {
+	return rdp.change(zone, route53.ChangeActionUpsert, record)
+}
+
+func (rdp *Route53DnsProvider) DeleteRecord(zone string, record dns_provider.Record) error {
+	return rdp.change(zone, route53.ChangeActionDelete, record)
+}
+
+func (rdp *Route53DnsProvider) change(zone string, action string, record dns_provider.Record) error {
+	hostedZoneId, err := rdp.hostedZoneId(zone)
+	if err != nil {
+		return err
+	}
+	_, err = rdp.svc.ChangeResourceRecordSets(&route53.ChangeResourceRecordSetsInput{
+		HostedZoneId: aws.String(hostedZoneId),
+		ChangeBatch: &route53.ChangeBatch{
+			Comment: aws.String("Ecology-Generated record change."),
+			Changes: []*route53.Change{
+				{
+					Action: aws.String(action),
+					ResourceRecordSet: &route53.ResourceRecordSet{
+						Name: aws.String(dns_provider.Fqdn(record.Name)),
+						Type: aws.String(record.Type),
+						TTL:  aws.Int64(record.Ttl),
+						ResourceRecords: []*route53.ResourceRecord{
+							{Value: aws.String(record.Value)},
+						},
+					},
+				},
+			},
+		},
+	})
+	return err
+}
+
+func (rdp *Route53DnsProvider) hostedZoneId(zone string) (string, error) {
+	result, err := rdp.svc.ListHostedZonesByName(&route53.ListHostedZonesByNameInput{
+		DNSName: aws.String(dns_provider.Fqdn(zone)),
+	})
+	if err != nil {
+		return "", err
+	}
+	for _, hz := range result.HostedZones {
+		if aws.StringValue(hz.Name) == dns_provider.Fqdn(zone) {
+			return aws.StringValue(hz.Id), nil
+		}
+	}
+	return "", errors.New(fmt.Sprintf("No Route53 hosted zone found for %s", zone))
+}
@@ -0,0 +1,105 @@
+package zone_file_dns_provider
+
+import (
+	"errors"
+	"fmt"
+	"github.com/gbdubs/ecology/dns_providers/dns_provider"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ZoneFileDnsProvider keeps records in a BIND-style zone file, for registrars
+// that import zone files or DNS servers that serve them directly. Only lines
+// of the form "name ttl IN type value" are managed; anything else is kept.
+type ZoneFileDnsProvider struct {
+	Path string
+}
+
+func New(path string) (*ZoneFileDnsProvider, error) {
+	if path == "" {
+		return nil, errors.New("The zone_file registrar needs a --zone_file to write records to")
+	}
+	return &ZoneFileDnsProvider{Path: path}, nil
+}
+
+func (zfdp *ZoneFileDnsProvider) Name() string {
+	return "zone_file"
+}
+
+func (zfdp *ZoneFileDnsProvider) UpsertRecord(zone string, record dns_provider.Record) error {
+	return zfdp.rewrite(zone, func(records []dns_provider.Record) []dns_provider.Record {
+		kept := make([]dns_provider.Record, 0)
+		for _, r := range records {
+			if !r.SameRecordSet(record) {
+				kept = append(kept, r)
+			}
+		}
+		return append(kept, record)
+	})
+}
+
+func (zfdp *ZoneFileDnsProvider) DeleteRecord(zone string, record dns_provider.Record) error {
+	return zfdp.rewrite(zone, func(records []dns_provider.Record) []dns_provider.Record {
+		kept := make([]dns_provider.Record, 0)
+		for _, r := range records {
+			if !r.SameRecordSet(record) || r.Value != record.Value {
+				kept = append(kept, r)
+			}
+		}
+		return kept
+	})
+}
+
+func (zfdp *ZoneFileDnsProvider) rewrite(zone string, update func([]dns_provider.Record) []dns_provider.Record) error {
+	data, err := ioutil.ReadFile(zfdp.Path)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	otherLines := make([]string, 0)
+	records := make([]dns_provider.Record, 0)
+	for _, line := range strings.Split(string(data), "\n") {
+		if record, ok := parseRecord(line); ok {
+			records = append(records, record)
+		} else if strings.TrimSpace(line) != "" && !strings.HasPrefix(line, "$ORIGIN") {
+			otherLines = append(otherLines, line)
+		}
+	}
+	records = update(records)
+	sort.Slice(records, func(i, j int) bool {
+		return records[i].Name+records[i].Type < records[j].Name+records[j].Type
+	})
+	lines := []string{"$ORIGIN " + dns_provider.Fqdn(zone)}
+	lines = append(lines, otherLines...)
+	for _, r := range records {
+		value := r.Value
+		if r.Type == "CNAME" {
+			value = dns_provider.Fqdn(value)
+		}
+		lines = append(lines, fmt.Sprintf("%s %d IN %s %s", dns_provider.Fqdn(r.Name), r.Ttl, r.Type, value))
+	}
+	return ioutil.WriteFile(zfdp.Path, []byte(strings.Join(lines, "\n")+"\n"), 0644)
+}
+
+func parseRecord(line string) (record dns_provider.Record, ok bool) {
+	fields := strings.Fields(line)
+	if len(fields) != 5 || fields[2] != "IN" {
+		return
+	}
+	ttl, err := strconv.ParseInt(fields[1], 10, 64)
+	if err != nil {
+		return
+	}
+	record = dns_provider.Record{
+		Name:  strings.TrimSuffix(fields[0], "."),
+		Type:  fields[3],
+		Ttl:   ttl,
+		Value: fields[4],
+	}
+	if record.Type == "CNAME" {
+		record.Value = strings.TrimSuffix(record.Value, ".")
+	}
+	return record, true
+}
@@ -0,0 +1,109 @@
+package zone_file_dns_provider
+
+import (
+	"github.com/gbdubs/ecology/dns_providers/dns_provider"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+)
+
+const existingZone = `$ORIGIN example.com.
+$TTL 3600
+@ 3600 IN SOA ns1.example.com. admin.example.com. 1 7200 3600 1209600 3600
+www.example.com. 300 IN A 192.0.2.1
+api.example.com. 300 IN CNAME old.execute-api.us-west-2.amazonaws.com.
+`
+
+func testProvider(t *testing.T, contents string) *ZoneFileDnsProvider {
+	path := filepath.Join(t.TempDir(), "example.com.zone")
+	if contents != "" {
+		if err := ioutil.WriteFile(path, []byte(contents), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	zfdp, err := New(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return zfdp
+}
+
+func assertZone(t *testing.T, zfdp *ZoneFileDnsProvider, want string) {
+	t.Helper()
+	got, err := ioutil.ReadFile(zfdp.Path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != want {
+		t.Fatalf("zone file is\n%s\nwant\n%s", got, want)
+	}
+}
+
+var apiRecord = dns_provider.Record{
+	Name:  "api.example.com",
+	Type:  "CNAME",
+	Ttl:   300,
+	Value: "new.execute-api.us-west-2.amazonaws.com",
+}
+
+func TestUpsertRecordReplacesTheRecordSet(t *testing.T) {
+	zfdp := testProvider(t, existingZone)
+	if err := zfdp.UpsertRecord("example.com", apiRecord); err != nil {
+		t.Fatal(err)
+	}
+	assertZone(t, zfdp, `$ORIGIN example.com.
+$TTL 3600
+@ 3600 IN SOA ns1.example.com. admin.example.com. 1 7200 3600 1209600 3600
+api.example.com. 300 IN CNAME new.execute-api.us-west-2.amazonaws.com.
+www.example.com. 300 IN A 192.0.2.1
+`)
+	// Upserting again changes nothing.
+	if err := zfdp.UpsertRecord("example.com", apiRecord); err != nil {
+		t.Fatal(err)
+	}
+	assertZone(t, zfdp, `$ORIGIN example.com.
+$TTL 3600
+@ 3600 IN SOA ns1.example.com. admin.example.com. 1 7200 3600 1209600 3600
+api.example.com. 300 IN CNAME new.execute-api.us-west-2.amazonaws.com.
+www.example.com. 300 IN A 192.0.2.1
+`)
+}
+
+func TestUpsertRecordCreatesTheZoneFile(t *testing.T) {
+	zfdp := testProvider(t, "")
+	if err := zfdp.UpsertRecord("example.com", apiRecord); err != nil {
+		t.Fatal(err)
+	}
+	assertZone(t, zfdp, `$ORIGIN example.com.
+api.example.com. 300 IN CNAME new.execute-api.us-west-2.amazonaws.com.
+`)
+}
+
+func TestDeleteRecordOnlyDeletesMatchingValues(t *testing.T) {
+	zfdp := testProvider(t, existingZone)
+	if err := zfdp.DeleteRecord("example.com", apiRecord); err != nil {
+		t.Fatal(err)
+	}
+	assertZone(t, zfdp, `$ORIGIN example.com.
+$TTL 3600
+@ 3600 IN SOA ns1.example.com. admin.example.com. 1 7200 3600 1209600 3600
+api.example.com. 300 IN CNAME old.execute-api.us-west-2.amazonaws.com.
+www.example.com. 300 IN A 192.0.2.1
+`)
+	old := apiRecord
+	old.Value = "old.execute-api.us-west-2.amazonaws.com"
+	if err := zfdp.DeleteRecord("example.com", old); err != nil {
+		t.Fatal(err)
+	}
+	assertZone(t, zfdp, `$ORIGIN example.com.
+$TTL 3600
+@ 3600 IN SOA ns1.example.com. admin.example.com. 1 7200 3600 1209600 3600
+www.example.com. 300 IN A 192.0.2.1
+`)
+}
+
+func TestNew(t *testing.T) {
+	if _, err := New(""); err == nil {
+		t.Error("New() without a zone file succeeded")
+	}
+}
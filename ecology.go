@@ -4,56 +4,142 @@ import (
 	"errors"
 	"flag"
 	"fmt"
+	"github.com/gbdubs/ecology/commands/cache"
 	"github.com/gbdubs/ecology/commands/create_lambda"
 	"github.com/gbdubs/ecology/commands/create_project"
 	"github.com/gbdubs/ecology/commands/delete_lambda"
 	"github.com/gbdubs/ecology/commands/delete_project"
+	"github.com/gbdubs/ecology/commands/drift"
+	"github.com/gbdubs/ecology/commands/history"
+	"github.com/gbdubs/ecology/commands/initialize"
+	"github.com/gbdubs/ecology/commands/initialize_routing"
 	"github.com/gbdubs/ecology/commands/list_project"
+	"github.com/gbdubs/ecology/commands/migrate"
+	"github.com/gbdubs/ecology/commands/pull_project"
 	"github.com/gbdubs/ecology/commands/push_lambda"
 	"github.com/gbdubs/ecology/commands/push_project"
+	"github.com/gbdubs/ecology/commands/rename_lambda"
+	"github.com/gbdubs/ecology/commands/rename_project"
+	"github.com/gbdubs/ecology/commands/restore"
+	"github.com/gbdubs/ecology/commands/rollback_lambda"
+	"github.com/gbdubs/ecology/commands/secret"
+	"github.com/gbdubs/ecology/commands/update_domain_records"
+	"github.com/gbdubs/ecology/commands/workspace"
 	"github.com/gbdubs/ecology/manifests/ecology_manifest"
+	"github.com/gbdubs/ecology/manifests/lambda_manifest"
+	"github.com/gbdubs/ecology/util/ecology_home"
 	"github.com/gbdubs/ecology/util/output"
 	"os"
+	"strings"
 )
 
 func main() {
-	o := output.New()
+	// Global Flags, which can go before or after the command name.
+
+	outputFlagKey := "output"
+	// output, one of text, json or ndjson.
+	outputFlagValue, args := extractGlobalFlag(os.Args[1:], outputFlagKey)
+	if outputFlagValue == "" {
+		outputFlagValue = output.TextFormat
+	}
+	o, err := output.NewWithFormat(outputFlagValue)
+	if err != nil {
+		o.Error(err)
+		os.Exit(1)
+	}
+
+	quietFlagKey := "quiet"
+	// quiet, only showing warnings and errors.
+	quietFlagValue, args := extractGlobalBool(args, quietFlagKey)
+	verboseFlagKey := "verbose"
+	// verbose, also showing debug lines and more detail from list_project.
+	verboseFlagValue, args := extractGlobalBool(args, verboseFlagKey)
+	if quietFlagValue && verboseFlagValue {
+		err = errors.New("Can't set both --quiet and --verbose")
+		o.Error(err)
+		os.Exit(1)
+	} else if quietFlagValue {
+		o.SetLevel(output.WarnLevel)
+	} else if verboseFlagValue {
+		o.SetLevel(output.DebugLevel)
+	}
+	output.DisableColorUnlessTerminal()
+
+	logFileFlagKey := "log_file"
+	// log_file, which gets every line of output, including debug lines.
+	logFileFlagValue, args := extractGlobalFlag(args, logFileFlagKey)
+	if logFileFlagValue != "" {
+		if err = o.SetLogFile(logFileFlagValue); err != nil {
+			o.Error(err)
+			os.Exit(1)
+		}
+	}
+
+	ecologyHomeFlagKey := "ecology_home"
+	// ecology_home, overriding $ECOLOGY_HOME and the XDG config directory.
+	ecologyHomeFlagValue, args := extractGlobalFlag(args, ecologyHomeFlagKey)
+	os.Args = append(os.Args[:1], args...)
+	ecologyHome, err := ecology_home.Resolve(ecologyHomeFlagValue)
+	if err != nil {
+		o.Error(err)
+		o.Finish("", err)
+		os.Exit(1)
+	}
+
 	o.Info("Reading Ecology Manifest...").Indent()
-	ecologyManifest, err := ecology_manifest.Get(o)
+	ecologyManifest, err := ecology_manifest.Get(ecologyHome, o)
 	o.Dedent().Done()
 
 	if err != nil {
 		o.Error(err)
+		o.Finish("", err)
 		os.Exit(1)
 	}
+	ecologyManifest.ApplyCredentialProfiles()
 
 	// Subcommands
+	initializeCommand := flag.NewFlagSet("initialize", flag.ExitOnError)
+
 	createProjectCommand := flag.NewFlagSet("create_project", flag.ExitOnError)
 	listProjectCommand := flag.NewFlagSet("list_project", flag.ExitOnError)
 	pushProjectCommand := flag.NewFlagSet("push_project", flag.ExitOnError)
+	pullProjectCommand := flag.NewFlagSet("pull_project", flag.ExitOnError)
+	renameProjectCommand := flag.NewFlagSet("rename_project", flag.ExitOnError)
 	deleteProjectCommand := flag.NewFlagSet("delete_project", flag.ExitOnError)
+	driftCommand := flag.NewFlagSet("drift", flag.ExitOnError)
+	historyCommand := flag.NewFlagSet("history", flag.ExitOnError)
+	restoreCommand := flag.NewFlagSet("restore", flag.ExitOnError)
+	migrateCommand := flag.NewFlagSet("migrate", flag.ExitOnError)
+	workspaceCommand := flag.NewFlagSet("workspace", flag.ExitOnError)
+	cacheCommand := flag.NewFlagSet("cache", flag.ExitOnError)
+	secretCommand := flag.NewFlagSet("secret", flag.ExitOnError)
+
+	initializeRoutingCommand := flag.NewFlagSet("initialize_routing", flag.ExitOnError)
+	updateDomainRecordsCommand := flag.NewFlagSet("update_domain_records", flag.ExitOnError)
 
 	createLambdaCommand := flag.NewFlagSet("create_lambda", flag.ExitOnError)
 	pushLambdaCommand := flag.NewFlagSet("push_lambda", flag.ExitOnError)
+	renameLambdaCommand := flag.NewFlagSet("rename_lambda", flag.ExitOnError)
+	rollbackLambdaCommand := flag.NewFlagSet("rollback_lambda", flag.ExitOnError)
 	deleteLambdaCommand := flag.NewFlagSet("delete_lambda", flag.ExitOnError)
 
 	// Common Flag Arguments
 
 	platformFlagKey := "platform"
-	platformDefaultValue := "AWS"
+	platformDefaultValue := ecologyManifest.DefaultPlatform()
 	platformHelpText := "The name of the platform that should be used for this command, AWS or GCP."
 	// create_project.platform
 	createProjectPlatformPtr := createProjectCommand.String(platformFlagKey, platformDefaultValue, platformHelpText)
 
 	regionFlagKey := "region"
-	regionDefaultValue := "us-west-2"
+	regionDefaultValue := ecologyManifest.DefaultRegion()
 	regionHelpText := "The name of the region that new resources should be created in"
 	// create_project.region
 	createProjectRegionPtr := createProjectCommand.String(regionFlagKey, regionDefaultValue, regionHelpText)
 
 	pathFlagKey := "path"
 	pathDefaultValue := ""
-	pathHelpText := "The path that the configuration for a project should go in"
+	pathHelpText := "The path that the configuration for a project should go in, defaults to a folder in the initialized projects directory"
 	// create_project.path
 	createProjectPathPtr := createProjectCommand.String(pathFlagKey, pathDefaultValue, pathHelpText)
 
@@ -64,15 +150,43 @@ func main() {
 	createProjectProjectPtr := createProjectCommand.String(projectFlagKey, projectDefaultValue, projectHelpText)
 	// push_project.project
 	pushProjectProjectPtr := pushProjectCommand.String(projectFlagKey, projectDefaultValue, projectHelpText)
+	// pull_project.project
+	pullProjectProjectPtr := pullProjectCommand.String(projectFlagKey, projectDefaultValue, projectHelpText)
+	// rename_project.project
+	renameProjectProjectPtr := renameProjectCommand.String(projectFlagKey, projectDefaultValue, projectHelpText)
 	// delete_project.project
 	deleteProjectProjectPtr := deleteProjectCommand.String(projectFlagKey, projectDefaultValue, projectHelpText)
+	// drift.project
+	driftProjectPtr := driftCommand.String(projectFlagKey, projectDefaultValue, projectHelpText)
+	// history.project
+	historyProjectPtr := historyCommand.String(projectFlagKey, projectDefaultValue, projectHelpText)
+	// restore.project
+	restoreProjectPtr := restoreCommand.String(projectFlagKey, projectDefaultValue, projectHelpText)
+	// secret.project
+	secretProjectPtr := secretCommand.String(projectFlagKey, projectDefaultValue, projectHelpText)
+	// migrate.project
+	migrateProjectPtr := migrateCommand.String(projectFlagKey, projectDefaultValue, "The name of the project to migrate, or blank to migrate every project.")
+	// initialize_routing.project
+	initializeRoutingProjectPtr := initializeRoutingCommand.String(projectFlagKey, projectDefaultValue, projectHelpText)
+	// update_domain_records.project
+	updateDomainRecordsProjectPtr := updateDomainRecordsCommand.String(projectFlagKey, projectDefaultValue, projectHelpText)
 	// create_lambda.project
 	createLambdaProjectPtr := createLambdaCommand.String(projectFlagKey, projectDefaultValue, projectHelpText)
 	// push_lambda.project
 	pushLambdaProjectPtr := pushLambdaCommand.String(projectFlagKey, projectDefaultValue, projectHelpText)
+	// rename_lambda.project
+	renameLambdaProjectPtr := renameLambdaCommand.String(projectFlagKey, projectDefaultValue, projectHelpText)
+	// rollback_lambda.project
+	rollbackLambdaProjectPtr := rollbackLambdaCommand.String(projectFlagKey, projectDefaultValue, projectHelpText)
 	// delete_lambda.project
 	deleteLambdaProjectPtr := deleteLambdaCommand.String(projectFlagKey, projectDefaultValue, projectHelpText)
 
+	newProjectFlagKey := "new_project"
+	newProjectDefaultValue := ""
+	newProjectHelpText := "The name that the project should be renamed to."
+	// rename_project.new_project
+	renameProjectNewProjectPtr := renameProjectCommand.String(newProjectFlagKey, newProjectDefaultValue, newProjectHelpText)
+
 	lambdaFlagKey := "lambda"
 	lambdaDefaultValue := ""
 	lambdaHelpText := "The name of the lambda that this command should operate over."
@@ -80,14 +194,116 @@ func main() {
 	createLambdaLambdaPtr := createLambdaCommand.String(lambdaFlagKey, lambdaDefaultValue, lambdaHelpText)
 	// push_lambda.lambda
 	pushLambdaLambdaPtr := pushLambdaCommand.String(lambdaFlagKey, lambdaDefaultValue, lambdaHelpText)
+	// rename_lambda.lambda
+	renameLambdaLambdaPtr := renameLambdaCommand.String(lambdaFlagKey, lambdaDefaultValue, lambdaHelpText)
+	// rollback_lambda.lambda
+	rollbackLambdaLambdaPtr := rollbackLambdaCommand.String(lambdaFlagKey, lambdaDefaultValue, lambdaHelpText)
 	// delete_lambda.lambda
 	deleteLambdaLambdaPtr := deleteLambdaCommand.String(lambdaFlagKey, lambdaDefaultValue, lambdaHelpText)
 
-	verboseFlagKey := "verbose"
-	verboseDefaultValue := false
-	verboseHelpText := "Whether or not to be verbose in the resulting output."
-	// list_project.verbose
-	listProjectVerbosePtr := listProjectCommand.Bool(verboseFlagKey, verboseDefaultValue, verboseHelpText)
+	newLambdaFlagKey := "new_lambda"
+	newLambdaDefaultValue := ""
+	newLambdaHelpText := "The name that the lambda should be renamed to."
+	// rename_lambda.new_lambda
+	renameLambdaNewLambdaPtr := renameLambdaCommand.String(newLambdaFlagKey, newLambdaDefaultValue, newLambdaHelpText)
+
+	toVersionFlagKey := "to_version"
+	toVersionDefaultValue := 0
+	toVersionHelpText := "The version to point the lambda's alias at, defaults to the version before the one it points at now."
+	// rollback_lambda.to_version
+	rollbackLambdaToVersionPtr := rollbackLambdaCommand.Int(toVersionFlagKey, toVersionDefaultValue, toVersionHelpText)
+
+	answersFlagKey := "answers"
+	answersDefaultValue := ""
+	answersHelpText := "A JSON file of answers to use instead of prompting interactively."
+	// initialize.answers
+	initializeAnswersPtr := initializeCommand.String(answersFlagKey, answersDefaultValue, answersHelpText)
+
+	domainFlagKey := "domain"
+	domainDefaultValue := ""
+	domainHelpText := "The custom domain that the project's API should be served from."
+	// initialize_routing.domain
+	initializeRoutingDomainPtr := initializeRoutingCommand.String(domainFlagKey, domainDefaultValue, domainHelpText)
+
+	registrarFlagKey := "registrar"
+	registrarDefaultValue := ""
+	registrarHelpText := "Where the domain's records are managed: route53, rfc2136 or zone_file."
+	// initialize_routing.registrar
+	initializeRoutingRegistrarPtr := initializeRoutingCommand.String(registrarFlagKey, registrarDefaultValue, registrarHelpText)
+
+	zoneFlagKey := "zone"
+	zoneDefaultValue := ""
+	zoneHelpText := "The DNS zone that holds the domain, defaults to the domain's parent."
+	// initialize_routing.zone
+	initializeRoutingZonePtr := initializeRoutingCommand.String(zoneFlagKey, zoneDefaultValue, zoneHelpText)
+
+	dnsServerFlagKey := "dns_server"
+	dnsServerDefaultValue := ""
+	dnsServerHelpText := "The DNS server to send dynamic updates to, for --registrar=rfc2136."
+	// initialize_routing.dns_server
+	initializeRoutingDnsServerPtr := initializeRoutingCommand.String(dnsServerFlagKey, dnsServerDefaultValue, dnsServerHelpText)
+
+	zoneFileFlagKey := "zone_file"
+	zoneFileDefaultValue := ""
+	zoneFileHelpText := "The zone file to write records into, for --registrar=zone_file."
+	// initialize_routing.zone_file
+	initializeRoutingZoneFilePtr := initializeRoutingCommand.String(zoneFileFlagKey, zoneFileDefaultValue, zoneFileHelpText)
+
+	planFlagKey := "plan"
+	planDefaultValue := false
+	planHelpText := "Whether to only show what would be pushed, without pushing anything."
+	// push_project.plan
+	pushProjectPlanPtr := pushProjectCommand.Bool(planFlagKey, planDefaultValue, planHelpText)
+	// push_lambda.plan
+	pushLambdaPlanPtr := pushLambdaCommand.Bool(planFlagKey, planDefaultValue, planHelpText)
+
+	planFileFlagKey := "plan_file"
+	planFileDefaultValue := ""
+	planFileHelpText := "A file to save the plan to as JSON, to be pushed later with --apply."
+	// push_project.plan_file
+	pushProjectPlanFilePtr := pushProjectCommand.String(planFileFlagKey, planFileDefaultValue, planFileHelpText)
+
+	applyFlagKey := "apply"
+	applyDefaultValue := ""
+	applyHelpText := "A plan file saved by --plan_file, to push exactly as planned."
+	// push_project.apply
+	pushProjectApplyPtr := pushProjectCommand.String(applyFlagKey, applyDefaultValue, applyHelpText)
+
+	snapshotFlagKey := "snapshot"
+	snapshotDefaultValue := ""
+	snapshotHelpText := "The id of the manifest snapshot to restore, as listed by history."
+	// restore.snapshot
+	restoreSnapshotPtr := restoreCommand.String(snapshotFlagKey, snapshotDefaultValue, snapshotHelpText)
+
+	parallelismFlagKey := "parallelism"
+	parallelismDefaultValue := 1
+	parallelismHelpText := "How many lambdas to build and upload at once."
+	// push_project.parallelism
+	pushProjectParallelismPtr := pushProjectCommand.Int(parallelismFlagKey, parallelismDefaultValue, parallelismHelpText)
+
+	runtimeFlagKey := "runtime"
+	runtimeDefaultValue := lambda_manifest.DefaultRuntime
+	runtimeHelpText := fmt.Sprintf("The AWS runtime for the lambda, one of %s.", strings.Join(lambda_manifest.Runtimes, ", "))
+	// create_lambda.runtime
+	createLambdaRuntimePtr := createLambdaCommand.String(runtimeFlagKey, runtimeDefaultValue, runtimeHelpText)
+
+	architectureFlagKey := "architecture"
+	architectureDefaultValue := lambda_manifest.DefaultArchitecture
+	architectureHelpText := fmt.Sprintf("The architecture to build the lambda for, one of %s.", strings.Join(lambda_manifest.Architectures, ", "))
+	// create_lambda.architecture
+	createLambdaArchitecturePtr := createLambdaCommand.String(architectureFlagKey, architectureDefaultValue, architectureHelpText)
+
+	maxAgeDaysFlagKey := "max_age_days"
+	maxAgeDaysDefaultValue := 30
+	maxAgeDaysHelpText := "Remove cached builds that haven't been used for this many days, or 0 to keep them all."
+	// cache.max_age_days
+	cacheMaxAgeDaysPtr := cacheCommand.Int(maxAgeDaysFlagKey, maxAgeDaysDefaultValue, maxAgeDaysHelpText)
+
+	maxSizeMbFlagKey := "max_size_mb"
+	maxSizeMbDefaultValue := 0
+	maxSizeMbHelpText := "Remove the least recently used cached builds until the cache is no bigger than this, or 0 for no limit."
+	// cache.max_size_mb
+	cacheMaxSizeMbPtr := cacheCommand.Int(maxSizeMbFlagKey, maxSizeMbDefaultValue, maxSizeMbHelpText)
 
 	command := ""
 	if len(os.Args) > 1 {
@@ -95,22 +311,64 @@ func main() {
 	}
 	illegalCommandNameError := errors.New(fmt.Sprintf(`Invalid command "%v" - implemented commands:
 	help
+	initialize
+	workspace
+	cache
 	
 	create_project
 	list_project
 	push_project
+	pull_project
+	rename_project
 	delete_project
+	drift
+	history
+	restore
+	migrate
+	secret
+	
+	initialize_routing
+	update_domain_records
 	
 	create_lambda
 	push_lambda
+	rename_lambda
+	rollback_lambda
 	delete_lambda`, command))
 
 	if len(os.Args) < 2 {
 		o.Error(illegalCommandNameError)
+		o.Finish(command, illegalCommandNameError)
 		os.Exit(1)
 	}
 
 	switch os.Args[1] {
+	case "initialize":
+		initializeCommand.Parse(os.Args[2:])
+		err = initialize.InitializeCommand{
+			EcologyManifest: ecologyManifest,
+			AnswersFile:     *initializeAnswersPtr,
+		}.Execute(o)
+	case "workspace":
+		workspaceCommand.Parse(os.Args[2:])
+		err = workspace.WorkspaceCommand{
+			EcologyManifest: ecologyManifest,
+			Action:          workspaceCommand.Arg(0),
+			Name:            workspaceCommand.Arg(1),
+		}.Execute(o)
+	case "cache":
+		cacheCommand.Parse(os.Args[2:])
+		action := cacheCommand.Arg(0)
+		if action != "" {
+			// Flags can come after the action too.
+			cacheCommand.Parse(cacheCommand.Args()[1:])
+		}
+		err = cache.CacheCommand{
+			EcologyManifest: ecologyManifest,
+			Action:          action,
+			MaxAgeDays:      *cacheMaxAgeDaysPtr,
+			MaxSizeMb:       *cacheMaxSizeMbPtr,
+		}.Execute(o)
 	case "create_project":
 		createProjectCommand.Parse(os.Args[2:])
 		cpc := &create_project.CreateProjectCommand{
@@ -120,47 +378,185 @@ func main() {
 			Project:         *createProjectProjectPtr,
 			Path:            *createProjectPathPtr,
 		}
-		cpc.Execute(o)
+		err = cpc.Execute(o)
 	case "list_project":
 		listProjectCommand.Parse(os.Args[2:])
-		list_project.ListProjectCommand{
+		err = list_project.ListProjectCommand{
 			EcologyManifest: ecologyManifest,
-			Verbose:         *listProjectVerbosePtr,
+			Verbose:         verboseFlagValue,
 		}.Execute(o)
 	case "push_project":
 		pushProjectCommand.Parse(os.Args[2:])
-		push_project.PushProjectCommand{
+		err = push_project.PushProjectCommand{
 			EcologyManifest: ecologyManifest,
 			Project:         *pushProjectProjectPtr,
+			Plan:            *pushProjectPlanPtr,
+			PlanFile:        *pushProjectPlanFilePtr,
+			Apply:           *pushProjectApplyPtr,
+			Parallelism:     *pushProjectParallelismPtr,
+		}.Execute(o)
+	case "pull_project":
+		pullProjectCommand.Parse(os.Args[2:])
+		err = pull_project.PullProjectCommand{
+			EcologyManifest: ecologyManifest,
+			Project:         *pullProjectProjectPtr,
+		}.Execute(o)
+	case "rename_project":
+		renameProjectCommand.Parse(os.Args[2:])
+		err = rename_project.RenameProjectCommand{
+			EcologyManifest: ecologyManifest,
+			Project:         *renameProjectProjectPtr,
+			NewProject:      *renameProjectNewProjectPtr,
 		}.Execute(o)
 	case "delete_project":
 		deleteProjectCommand.Parse(os.Args[2:])
-		delete_project.DeleteProjectCommand{
+		err = delete_project.DeleteProjectCommand{
 			EcologyManifest: ecologyManifest,
 			Project:         *deleteProjectProjectPtr,
 		}.Execute(o)
+	case "drift":
+		driftCommand.Parse(os.Args[2:])
+		err = drift.DriftCommand{
+			EcologyManifest: ecologyManifest,
+			Project:         *driftProjectPtr,
+		}.Execute(o)
+	case "history":
+		historyCommand.Parse(os.Args[2:])
+		err = history.HistoryCommand{
+			EcologyManifest: ecologyManifest,
+			Project:         *historyProjectPtr,
+		}.Execute(o)
+	case "restore":
+		restoreCommand.Parse(os.Args[2:])
+		err = restore.RestoreCommand{
+			EcologyManifest: ecologyManifest,
+			Project:         *restoreProjectPtr,
+			Snapshot:        *restoreSnapshotPtr,
+		}.Execute(o)
+	case "secret":
+		secretCommand.Parse(os.Args[2:])
+		action := secretCommand.Arg(0)
+		name := ""
+		if action != "" {
+			// Flags can come after the action and name too.
+			secretCommand.Parse(secretCommand.Args()[1:])
+			name = secretCommand.Arg(0)
+			if name != "" {
+				secretCommand.Parse(secretCommand.Args()[1:])
+			}
+		}
+		err = secret.SecretCommand{
+			EcologyManifest: ecologyManifest,
+			Project:         *secretProjectPtr,
+			Action:          action,
+			Name:            name,
+		}.Execute(o)
+	case "migrate":
+		migrateCommand.Parse(os.Args[2:])
+		err = migrate.MigrateCommand{
+			EcologyManifest: ecologyManifest,
+			Project:         *migrateProjectPtr,
+		}.Execute(o)
+	case "initialize_routing":
+		initializeRoutingCommand.Parse(os.Args[2:])
+		err = initialize_routing.InitializeRoutingCommand{
+			EcologyManifest: ecologyManifest,
+			Project:         *initializeRoutingProjectPtr,
+			Domain:          *initializeRoutingDomainPtr,
+			Registrar:       *initializeRoutingRegistrarPtr,
+			Zone:            *initializeRoutingZonePtr,
+			DnsServer:       *initializeRoutingDnsServerPtr,
+			ZoneFile:        *initializeRoutingZoneFilePtr,
+		}.Execute(o)
+	case "update_domain_records":
+		updateDomainRecordsCommand.Parse(os.Args[2:])
+		err = update_domain_records.UpdateDomainRecordsCommand{
+			EcologyManifest: ecologyManifest,
+			Project:         *updateDomainRecordsProjectPtr,
+		}.Execute(o)
 	case "create_lambda":
 		createLambdaCommand.Parse(os.Args[2:])
-		create_lambda.CreateLambdaCommand{
+		err = create_lambda.CreateLambdaCommand{
 			EcologyManifest: ecologyManifest,
 			Project:         *createLambdaProjectPtr,
 			Lambda:          *createLambdaLambdaPtr,
+			Runtime:         *createLambdaRuntimePtr,
+			Architecture:    *createLambdaArchitecturePtr,
 		}.Execute(o)
 	case "push_lambda":
 		pushLambdaCommand.Parse(os.Args[2:])
-		push_lambda.PushLambdaCommand{
+		err = push_lambda.PushLambdaCommand{
 			EcologyManifest: ecologyManifest,
 			Project:         *pushLambdaProjectPtr,
 			Lambda:          *pushLambdaLambdaPtr,
+			Plan:            *pushLambdaPlanPtr,
+		}.Execute(o)
+	case "rename_lambda":
+		renameLambdaCommand.Parse(os.Args[2:])
+		err = rename_lambda.RenameLambdaCommand{
+			EcologyManifest: ecologyManifest,
+			Project:         *renameLambdaProjectPtr,
+			Lambda:          *renameLambdaLambdaPtr,
+			NewLambda:       *renameLambdaNewLambdaPtr,
+		}.Execute(o)
+	case "rollback_lambda":
+		rollbackLambdaCommand.Parse(os.Args[2:])
+		err = rollback_lambda.RollbackLambdaCommand{
+			EcologyManifest: ecologyManifest,
+			Project:         *rollbackLambdaProjectPtr,
+			Lambda:          *rollbackLambdaLambdaPtr,
+			ToVersion:       *rollbackLambdaToVersionPtr,
 		}.Execute(o)
 	case "delete_lambda":
 		deleteLambdaCommand.Parse(os.Args[2:])
-		delete_lambda.DeleteLambdaCommand{
+		err = delete_lambda.DeleteLambdaCommand{
 			EcologyManifest: ecologyManifest,
 			Project:         *deleteLambdaProjectPtr,
 			Lambda:          *deleteLambdaLambdaPtr,
 		}.Execute(o)
 	default:
-		o.Error(illegalCommandNameError)
+		err = illegalCommandNameError
+		o.Error(err)
+	}
+
+	o.Finish(command, err)
+	// Drift is reported through the exit code, for CI jobs.
+	if command == "drift" && err != nil {
+		os.Exit(1)
+	}
+}
+
+// extractGlobalBool removes a boolean flag from anywhere in the arguments,
+// returning whether it was set and the remaining arguments.
+func extractGlobalBool(args []string, key string) (value bool, rest []string) {
+	for _, arg := range args {
+		name := strings.TrimLeft(arg, "-")
+		if strings.HasPrefix(arg, "-") && (name == key || name == key+"=true") {
+			value = true
+		} else if strings.HasPrefix(arg, "-") && name == key+"=false" {
+			value = false
+		} else {
+			rest = append(rest, arg)
+		}
+	}
+	return
+}
+
+// extractGlobalFlag removes a flag from anywhere in the arguments, returning
+// its value and the remaining arguments.
+func extractGlobalFlag(args []string, key string) (value string, rest []string) {
+	for i := 0; i < len(args); i++ {
+		name := strings.TrimLeft(args[i], "-")
+		if !strings.HasPrefix(args[i], "-") || !strings.HasPrefix(name, key) {
+			rest = append(rest, args[i])
+		} else if name == key && i+1 < len(args) {
+			value = args[i+1]
+			i++
+		} else if strings.HasPrefix(name, key+"=") {
+			value = name[len(key)+1:]
+		} else {
+			rest = append(rest, args[i])
+		}
 	}
+	return
 }
@@ -1,16 +1,293 @@
 package api_manifest
 
-import ()
+import (
+	"errors"
+	"fmt"
+	"github.com/gbdubs/ecology/manifests/plan_manifest"
+	"github.com/gbdubs/ecology/platforms/platform"
+	"github.com/gbdubs/ecology/util/output"
+	"strings"
+)
+
+var routeMethods = []string{"ANY", "GET", "POST", "PUT", "PATCH", "DELETE", "HEAD", "OPTIONS"}
+
+var routeAuths = []string{"NONE", "AWS_IAM"}
+
+type ApiRouteConfig struct {
+	Method string
+	Path   string
+	Lambda string
+	// One of NONE (the default) or AWS_IAM.
+	Auth string
+}
 
 type ApiConfigInfo struct {
+	Routes []ApiRouteConfig
 }
 
+// ApiDeployInfo is where the API is deployed. It is always on the project's
+// platform and in its region.
 type ApiDeployInfo struct {
-	Platform string
-	Region   string
+	ApiId    string
+	Endpoint string
 }
 
 type ApiManifest struct {
 	Config ApiConfigInfo
 	Deploy ApiDeployInfo
 }
+
+func (r *ApiRouteConfig) RouteKey() string {
+	return strings.ToUpper(r.Method) + " " + r.Path
+}
+
+func (r *ApiRouteConfig) authorizationType() string {
+	if r.Auth == "" {
+		return "NONE"
+	}
+	return strings.ToUpper(r.Auth)
+}
+
+func (r *ApiRouteConfig) validate() error {
+	if !contains(routeMethods, strings.ToUpper(r.Method)) {
+		return errors.New(fmt.Sprintf("Route %s has an unsupported method, use one of %s", r.RouteKey(), strings.Join(routeMethods, ", ")))
+	}
+	if !strings.HasPrefix(r.Path, "/") {
+		return errors.New(fmt.Sprintf("Route %s should have a path starting with /", r.RouteKey()))
+	}
+	if !contains(routeAuths, r.authorizationType()) {
+		return errors.New(fmt.Sprintf("Route %s has an unsupported auth, use one of %s", r.RouteKey(), strings.Join(routeAuths, ", ")))
+	}
+	return nil
+}
+
+func (am *ApiManifest) IsDeployed() bool {
+	return am.Deploy.ApiId != ""
+}
+
+// RoutesTo lists the route keys of every route that targets the lambda.
+func (am *ApiManifest) RoutesTo(lambdaName string) []string {
+	routeKeys := make([]string, 0)
+	for _, r := range am.Config.Routes {
+		if r.Lambda == lambdaName {
+			routeKeys = append(routeKeys, r.RouteKey())
+		}
+	}
+	return routeKeys
+}
+
+func (am *ApiManifest) RenameLambda(lambdaName string, newLambdaName string) {
+	for i, _ := range am.Config.Routes {
+		if am.Config.Routes[i].Lambda == lambdaName {
+			am.Config.Routes[i].Lambda = newLambdaName
+		}
+	}
+}
+
+// Plan works out what PushToPlatform would do, by comparing the configured
+// routes with the API's routes on the platform. functionArns is as for
+// PushToPlatform, except that lambdas whose ARN won't be known until they are
+// pushed are left out, and their routes are planned as changing.
+func (am *ApiManifest) Plan(p platform.Platform, apiName string, functionArns map[string]string) (action plan_manifest.Action, err error) {
+	action = plan_manifest.Action{
+		Resource: "api",
+		Name:     apiName,
+		Change:   plan_manifest.ChangeNone,
+		Reason:   "no routes declared",
+	}
+	if len(am.Config.Routes) == 0 {
+		if am.IsDeployed() {
+			action.Change = plan_manifest.ChangeDelete
+		}
+		return
+	}
+	ap, ok := p.(platform.ApiPlatform)
+	if !ok {
+		err = errors.New(fmt.Sprintf("Platform %s doesn't support API routes", p.Name()))
+		return
+	}
+	for _, r := range am.Config.Routes {
+		if err = r.validate(); err != nil {
+			return
+		}
+	}
+	exists := am.IsDeployed()
+	if exists {
+		_, err = ap.GetApi(am.Deploy.ApiId)
+		if platform.IsNotFound(err) {
+			err = nil
+			exists = false
+		} else if err != nil {
+			return
+		}
+	}
+	if !exists {
+		action.Change = plan_manifest.ChangeCreate
+		action.Reason = fmt.Sprintf("create %d routes", len(am.Config.Routes))
+		return
+	}
+
+	existingRoutes, err := ap.GetRoutes(am.Deploy.ApiId)
+	if err != nil {
+		return
+	}
+	existingByKey := make(map[string]*platform.ApiRoute)
+	for _, r := range existingRoutes {
+		existingByKey[r.RouteKey] = r
+	}
+	var created, updated int
+	for _, r := range am.Config.Routes {
+		existing, ok := existingByKey[r.RouteKey()]
+		if !ok {
+			created++
+			continue
+		}
+		delete(existingByKey, r.RouteKey())
+		functionArn := functionArns[r.Lambda]
+		if functionArn == "" || existing.FunctionArn != functionArn || existing.AuthorizationType != r.authorizationType() {
+			updated++
+		}
+	}
+	deleted := len(existingByKey)
+	if created+updated+deleted == 0 {
+		action.Reason = "routes unchanged"
+		return
+	}
+	action.Change = plan_manifest.ChangeUpdate
+	action.Reason = fmt.Sprintf("create %d, update %d and delete %d routes", created, updated, deleted)
+	return
+}
+
+// PushToPlatform makes the platform's API match the configured routes,
+// creating the API on first push. functionArns maps each deployed lambda's
+// name to the ARN that its routes should invoke.
+func (am *ApiManifest) PushToPlatform(p platform.Platform, apiName string, functionArns map[string]string, o *output.Output) (err error) {
+	o.Info("ApiManifest - PushToPlatform - %s", apiName).Indent()
+	if len(am.Config.Routes) == 0 {
+		o.Info("No Routes Declared.").Dedent().Done()
+		if am.IsDeployed() {
+			return am.DeleteFromPlatform(p, o)
+		}
+		return nil
+	}
+	ap, ok := p.(platform.ApiPlatform)
+	if !ok {
+		return errors.New(fmt.Sprintf("Platform %s doesn't support API routes", p.Name()))
+	}
+	for _, r := range am.Config.Routes {
+		if err = r.validate(); err != nil {
+			return
+		}
+		if functionArns[r.Lambda] == "" {
+			return errors.New(fmt.Sprintf("Route %s targets lambda %s, which isn't deployed", r.RouteKey(), r.Lambda))
+		}
+	}
+
+	if am.IsDeployed() {
+		o.Info("Checking that API %s still exists...", am.Deploy.ApiId)
+		_, err = ap.GetApi(am.Deploy.ApiId)
+		if platform.IsNotFound(err) {
+			o.Warning("API %s no longer exists on the platform.", am.Deploy.ApiId)
+			am.Deploy.ApiId = ""
+			am.Deploy.Endpoint = ""
+		} else if err != nil {
+			o.Error(err)
+			return
+		}
+	}
+	if !am.IsDeployed() {
+		o.Info("Creating API %s", apiName)
+		api, err := ap.CreateApi(apiName)
+		if err != nil {
+			o.Error(err)
+			return err
+		}
+		am.Deploy.ApiId = api.Id
+		am.Deploy.Endpoint = api.Endpoint
+	}
+
+	existingRoutes, err := ap.GetRoutes(am.Deploy.ApiId)
+	if err != nil {
+		o.Error(err)
+		return
+	}
+	existingByKey := make(map[string]*platform.ApiRoute)
+	for _, r := range existingRoutes {
+		existingByKey[r.RouteKey] = r
+	}
+
+	permitted := make(map[string]bool)
+	for _, r := range am.Config.Routes {
+		functionArn := functionArns[r.Lambda]
+		if !permitted[functionArn] {
+			if err = ap.AddInvokePermission(functionArn, am.Deploy.ApiId); err != nil {
+				o.Error(err)
+				return
+			}
+			permitted[functionArn] = true
+		}
+		route := &platform.ApiRoute{
+			RouteKey:          r.RouteKey(),
+			FunctionArn:       functionArn,
+			AuthorizationType: r.authorizationType(),
+		}
+		if existing, ok := existingByKey[route.RouteKey]; ok {
+			delete(existingByKey, route.RouteKey)
+			if existing.FunctionArn == route.FunctionArn && existing.AuthorizationType == route.AuthorizationType {
+				continue
+			}
+			route.RouteId = existing.RouteId
+			o.Info("Updating Route %s -> %s", route.RouteKey, r.Lambda)
+		} else {
+			o.Info("Creating Route %s -> %s", route.RouteKey, r.Lambda)
+		}
+		if _, err = ap.PutRoute(am.Deploy.ApiId, route); err != nil {
+			o.Error(err)
+			return
+		}
+	}
+	for routeKey, r := range existingByKey {
+		o.Info("Deleting Route %s", routeKey)
+		if err = ap.DeleteRoute(am.Deploy.ApiId, r.RouteId); err != nil {
+			o.Error(err)
+			return
+		}
+	}
+	o.Success("API Endpoint = %s", am.Deploy.Endpoint)
+	o.Dedent().Done()
+	return nil
+}
+
+func (am *ApiManifest) DeleteFromPlatform(p platform.Platform, o *output.Output) (err error) {
+	o.Info("ApiManifest - DeleteFromPlatform - %s", am.Deploy.ApiId).Indent()
+	if !am.IsDeployed() {
+		o.Info("No Removal Needed.").Dedent().Done()
+		return nil
+	}
+	ap, ok := p.(platform.ApiPlatform)
+	if !ok {
+		return errors.New(fmt.Sprintf("Platform %s doesn't support API routes", p.Name()))
+	}
+	err = ap.DeleteApi(am.Deploy.ApiId)
+	if platform.IsNotFound(err) {
+		o.Warning("API %s was already gone from the platform.", am.Deploy.ApiId)
+		err = nil
+	}
+	if err != nil {
+		o.Error(err)
+		return
+	}
+	am.Deploy.ApiId = ""
+	am.Deploy.Endpoint = ""
+	o.Dedent().Done()
+	return nil
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
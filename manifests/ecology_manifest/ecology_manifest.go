@@ -1,27 +1,66 @@
 package ecology_manifest
 
 import (
+	"bytes"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"github.com/gbdubs/ecology/manifests/project_manifest"
+	"github.com/gbdubs/ecology/util/atomic_file"
+	"github.com/gbdubs/ecology/util/build_cache"
+	"github.com/gbdubs/ecology/util/ecology_home"
+	"github.com/gbdubs/ecology/util/manifest_history"
+	"github.com/gbdubs/ecology/util/manifest_lock"
 	"github.com/gbdubs/ecology/util/output"
 	"io/ioutil"
 	"os"
-	"strings"
+	"path/filepath"
 )
 
+// EcologyConfig holds the preferences chosen by `ecology initialize`.
+type EcologyConfig struct {
+	ProjectsDir      string
+	DefaultPlatform  string
+	DefaultRegion    string
+	AwsProfile       string
+	GcpConfiguration string
+	GitHost          string
+	GitUser          string
+	GitPrivateRepos  bool
+	// When LockTable is set, manifest locks are kept in that DynamoDB table
+	// rather than in lock files, so that they can be shared between machines.
+	LockTable    string
+	LockRegion   string
+	LockEndpoint string
+}
+
 type EcologyManifest struct {
 	ManifestPath         string
+	Config               EcologyConfig
 	ProjectManifestPaths map[string]string
+
+	// The manifest file as it was read, to detect other writers.
+	loaded []byte
+	// The ecology home and workspace that the manifest was read from.
+	home      string
+	workspace string
 }
 
-const defaultEcologyManifestFilePath = "/Users/gradyward/.ecology/ecology.json"
+const fallbackPlatform = "AWS"
+const fallbackRegion = "us-west-2"
+
+const ecologyLockKey = "ecology"
 
-func Get(o *output.Output) (ecologyManifest EcologyManifest, err error) {
-	data, err := ioutil.ReadFile(defaultEcologyManifestFilePath)
+// Get reads the ecology manifest of the current workspace in the given
+// ecology home, as resolved by ecology_home.Resolve.
+func Get(home string, o *output.Output) (ecologyManifest EcologyManifest, err error) {
+	workspace := ecology_home.CurrentWorkspace(home)
+	manifestPath := ecology_home.ManifestPath(home, workspace)
+	data, err := ioutil.ReadFile(manifestPath)
 	if err != nil {
 		o.Warning("Ecology Manifest Not Found. Creating New Ecology Manifest.").Indent()
 		ecologyManifest = EcologyManifest{
-			ManifestPath:         defaultEcologyManifestFilePath,
+			ManifestPath:         manifestPath,
 			ProjectManifestPaths: make(map[string]string),
 		}
 		o.Dedent().Done()
@@ -31,32 +70,156 @@ func Get(o *output.Output) (ecologyManifest EcologyManifest, err error) {
 		if err != nil {
 			return
 		}
+		// The saved path is wherever the home was when the manifest was last
+		// written, which is stale if the home has moved since.
+		ecologyManifest.ManifestPath = manifestPath
+		ecologyManifest.loaded = data
 	}
+	ecologyManifest.home = home
+	ecologyManifest.workspace = workspace
 	return ecologyManifest, err
 }
 
+func (em *EcologyManifest) Home() string {
+	return em.home
+}
+
+func (em *EcologyManifest) Workspace() string {
+	return em.workspace
+}
+
+// Save writes the manifest, refusing to overwrite changes that another
+// process saved since it was read. Prefer Update, which can't conflict.
 func (em *EcologyManifest) Save(o *output.Output) (err error) {
+	lock, err := em.Locker().Lock(ecologyLockKey)
+	if err != nil {
+		o.Error(err)
+		return
+	}
+	defer lock.Unlock()
+	data, err := ioutil.ReadFile(em.ManifestPath)
+	if err == nil && !bytes.Equal(data, em.loaded) {
+		err = errors.New(fmt.Sprintf("Ecology Manifest %s was changed by another process since it was read, re-run the command to pick up its changes", em.ManifestPath))
+		o.Error(err)
+		return
+	}
+	return em.write(o)
+}
+
+// Update applies the change to the latest saved manifest and writes it back,
+// all while holding the ecology lock, so that concurrent updates from other
+// processes aren't lost.
+func (em *EcologyManifest) Update(o *output.Output, change func(em *EcologyManifest)) (err error) {
+	lock, err := em.Locker().Lock(ecologyLockKey)
+	if err != nil {
+		o.Error(err)
+		return
+	}
+	defer lock.Unlock()
+	data, err := ioutil.ReadFile(em.ManifestPath)
+	if err == nil {
+		latest := EcologyManifest{}
+		if err = json.Unmarshal(data, &latest); err != nil {
+			o.Error(err)
+			return
+		}
+		if latest.ProjectManifestPaths == nil {
+			latest.ProjectManifestPaths = make(map[string]string)
+		}
+		latest.ManifestPath = em.ManifestPath
+		latest.home = em.home
+		latest.workspace = em.workspace
+		*em = latest
+	}
+	change(em)
+	return em.write(o)
+}
+
+func (em *EcologyManifest) write(o *output.Output) (err error) {
 	o.Info("Writing Ecology Manifest to %s...", em.ManifestPath).Indent()
 
 	file, err := json.MarshalIndent(em, "", "  ")
 	if err == nil {
-		if strings.Index(em.ManifestPath, "/") > -1 {
-			parentDir := em.ManifestPath[:strings.LastIndex(em.ManifestPath, "/")]
-			err = os.MkdirAll(parentDir, 0777)
-			if err != nil {
-				o.Error(err)
-				return
-			}
-		}
-		err = ioutil.WriteFile(em.ManifestPath, file, 0777)
+		err = atomic_file.WriteFile(em.ManifestPath, file, 0644)
 	}
 	if err != nil {
 		o.Error(err)
+		return
+	}
+	em.loaded = file
+	if historyErr := manifest_history.Record(filepath.Join(filepath.Dir(em.ManifestPath), "history"), file); historyErr != nil {
+		o.Warning("Couldn't snapshot the manifest: %v", historyErr)
 	}
 	o.Dedent().Done()
 	return
 }
 
+// Locker returns the locker that guards the ecology and project manifests.
+func (em *EcologyManifest) Locker() manifest_lock.Locker {
+	if em.Config.LockTable != "" {
+		region := em.Config.LockRegion
+		if region == "" {
+			region = em.DefaultRegion()
+		}
+		return manifest_lock.NewDynamoLocker(em.Config.LockTable, region, em.Config.LockEndpoint)
+	}
+	return manifest_lock.NewFileLocker(filepath.Join(filepath.Dir(em.ManifestPath), "locks"))
+}
+
+// BuildCache returns the cache of built lambdas in the ecology home, or nil if
+// the manifest wasn't read from one.
+func (em *EcologyManifest) BuildCache() *build_cache.Cache {
+	if em.home == "" {
+		return nil
+	}
+	return build_cache.New(ecology_home.CacheDir(em.home))
+}
+
 func (em *EcologyManifest) GetProjectManifest(project string) (*project_manifest.ProjectManifest, error) {
-	return project_manifest.GetProjectManifestFromFile(em.ProjectManifestPaths[project])
+	pm, err := project_manifest.GetProjectManifestFromFile(em.ProjectManifestPaths[project])
+	if err == nil {
+		pm.SetLocker(em.Locker())
+		pm.SetBuildCache(em.BuildCache())
+	}
+	return pm, err
+}
+
+// GetProjectManifestForUpdate reads the project manifest while holding its
+// lock, which the caller must release with Unlock once done saving it.
+func (em *EcologyManifest) GetProjectManifestForUpdate(project string) (*project_manifest.ProjectManifest, error) {
+	pm, err := project_manifest.GetProjectManifestForUpdate(em.ProjectManifestPaths[project], project, em.Locker())
+	if err == nil {
+		pm.SetBuildCache(em.BuildCache())
+	}
+	return pm, err
+}
+
+func (em *EcologyManifest) RenameProject(project string, newProject string) {
+	em.ProjectManifestPaths[newProject] = em.ProjectManifestPaths[project]
+	delete(em.ProjectManifestPaths, project)
+}
+
+func (em *EcologyManifest) DefaultPlatform() string {
+	if em.Config.DefaultPlatform != "" {
+		return em.Config.DefaultPlatform
+	}
+	return fallbackPlatform
+}
+
+func (em *EcologyManifest) DefaultRegion() string {
+	if em.Config.DefaultRegion != "" {
+		return em.Config.DefaultRegion
+	}
+	return fallbackRegion
+}
+
+// ApplyCredentialProfiles points the AWS SDK and gcloud at the configured
+// credential profiles, unless the environment already picks one.
+func (em *EcologyManifest) ApplyCredentialProfiles() {
+	if em.Config.AwsProfile != "" && os.Getenv("AWS_PROFILE") == "" {
+		os.Setenv("AWS_PROFILE", em.Config.AwsProfile)
+	}
+	if em.Config.GcpConfiguration != "" && os.Getenv("CLOUDSDK_ACTIVE_CONFIG_NAME") == "" {
+		os.Setenv("CLOUDSDK_ACTIVE_CONFIG_NAME", em.Config.GcpConfiguration)
+	}
 }
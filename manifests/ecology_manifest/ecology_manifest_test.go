@@ -0,0 +1,91 @@
+package ecology_manifest
+
+import (
+	"github.com/gbdubs/ecology/util/ecology_home"
+	"github.com/gbdubs/ecology/util/output"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestGetWritesToTheHomeItWasReadFrom(t *testing.T) {
+	oldHome := t.TempDir()
+	em, err := Get(oldHome, output.NewForTesting())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = em.Update(output.NewForTesting(), func(em *EcologyManifest) {
+		em.ProjectManifestPaths["P"] = "/old/P/project.ecology.json"
+	}); err != nil {
+		t.Fatal(err)
+	}
+	saved, err := ioutil.ReadFile(filepath.Join(oldHome, "ecology.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The manifest is moved to a new home, still naming the old one.
+	newHome := t.TempDir()
+	newPath := filepath.Join(newHome, "ecology.json")
+	if err = ioutil.WriteFile(newPath, saved, 0644); err != nil {
+		t.Fatal(err)
+	}
+	em, err = Get(newHome, output.NewForTesting())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if em.ManifestPath != newPath {
+		t.Fatalf("ManifestPath = %s, want %s", em.ManifestPath, newPath)
+	}
+	if err = em.Update(output.NewForTesting(), func(em *EcologyManifest) {
+		em.ProjectManifestPaths["Q"] = "/new/Q/project.ecology.json"
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if err = em.Save(output.NewForTesting()); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := ioutil.ReadFile(newPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), "/new/Q/project.ecology.json") {
+		t.Errorf("new home's manifest = %s, want it updated", data)
+	}
+	data, err = ioutil.ReadFile(filepath.Join(oldHome, "ecology.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != string(saved) {
+		t.Errorf("old home's manifest = %s, want it untouched", data)
+	}
+	for _, dir := range []string{"history", "locks"} {
+		if _, err := os.Stat(filepath.Join(newHome, dir)); err != nil {
+			t.Errorf("no %s in the new home: %v", dir, err)
+		}
+	}
+}
+
+func TestGetReadsTheCurrentWorkspace(t *testing.T) {
+	home := t.TempDir()
+	if err := ecology_home.UseWorkspace(home, "staging"); err != nil {
+		t.Fatal(err)
+	}
+	em, err := Get(home, output.NewForTesting())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = em.Update(output.NewForTesting(), func(em *EcologyManifest) {}); err != nil {
+		t.Fatal(err)
+	}
+	want := filepath.Join(home, "workspaces", "staging", "ecology.json")
+	if _, err := os.Stat(want); err != nil {
+		t.Fatalf("staging manifest wasn't written: %v", err)
+	}
+	if em.Workspace() != "staging" || em.Home() != home {
+		t.Errorf("read workspace %s of %s, want staging of %s", em.Workspace(), em.Home(), home)
+	}
+}
@@ -2,15 +2,27 @@ package lambda_manifest
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
 	"fmt"
-	"github.com/aws/aws-sdk-go/aws"
-	"github.com/aws/aws-sdk-go/aws/session"
-	"github.com/aws/aws-sdk-go/service/lambda"
+	"github.com/gbdubs/ecology/manifests/plan_manifest"
 	"github.com/gbdubs/ecology/manifests/role_manifest"
+	"github.com/gbdubs/ecology/platforms/platform"
+	"github.com/gbdubs/ecology/util/atomic_file"
+	"github.com/gbdubs/ecology/util/build_cache"
+	"github.com/gbdubs/ecology/util/cloud_function_source"
+	"github.com/gbdubs/ecology/util/deploy_zip"
 	"github.com/gbdubs/ecology/util/file_hash"
 	"github.com/gbdubs/ecology/util/output"
-	"io/ioutil"
+	"github.com/gbdubs/ecology/util/schema_migration"
+	"os"
 	"os/exec"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"sort"
 	"strings"
 	"time"
 )
@@ -22,22 +34,161 @@ type LambdaConfigInfo struct {
 	CodePath           string
 	BuiltPath          string
 	ZippedPath         string
+	// The AWS custom runtime and architecture to build for, unset on GCP.
+	Runtime      string
+	Architecture string
+	// Memory, timeout, environment variables and so on, applied on each push.
+	platform.FunctionSettings
+	// Environment variables set from the project's secrets, by the name of the
+	// secret, so that their values are never written to the manifest.
+	Secrets map[string]string `json:",omitempty"`
+	// The alias that API routes invoke, DefaultAlias if unset.
+	Alias string `json:",omitempty"`
 }
 
 type LambdaDeployInfo struct {
 	Platform         string
 	Region           string
 	LastDeployedHash string
-	Arn              string
+	// The platform's own hash of the deployed package, to spot out-of-band changes.
+	CodeSha256   string
+	Arn          string
+	Runtime      string
+	Architecture string
+	Handler      string
+	// The hash of the function settings last applied, so that changing them
+	// pushes the lambda even if its code hasn't changed.
+	SettingsHash string
+	// The versions that pushes published, oldest first, to roll back to.
+	Versions []LambdaVersion `json:",omitempty"`
+	// The alias that API routes invoke, and the version that it points at.
+	Alias        string
+	AliasArn     string
+	AliasVersion string
+	// The function that the lambda was renamed from, whose versions were
+	// deleted along with it, so they can't be rolled back to.
+	RenamedFrom string `json:",omitempty"`
+}
+
+// LambdaVersion is a version of the function that a push published, and what
+// it was built from.
+type LambdaVersion struct {
+	Version      string
+	CodeHash     string
+	CodeSha256   string
+	SettingsHash string
+	Published    time.Time
 }
 
 type LambdaManifest struct {
+	SchemaVersion        int
 	Config               LambdaConfigInfo
 	Deploy               LambdaDeployInfo
 	ExecutorRoleManifest role_manifest.RoleManifest
+
+	// Where the lambda is built, or nil to build it in its own folder.
+	buildCache *build_cache.Cache
+	// Where the values of Config.Secrets come from.
+	secrets Secrets
+}
+
+// Secrets looks up the secrets that lambdas refer to by name.
+type Secrets interface {
+	// Fingerprint changes whenever the secret does, without revealing it.
+	Fingerprint(name string) (string, error)
+	Value(name string) (string, error)
+}
+
+// Lambdas on AWS run as a bootstrap executable on one of these custom
+// runtimes, since the go1.x runtime was retired.
+var Runtimes = []string{"provided.al2023", "provided.al2"}
+
+const DefaultRuntime = "provided.al2023"
+
+var Architectures = []string{platform.ArchitectureX86_64, platform.ArchitectureArm64}
+
+const DefaultArchitecture = platform.ArchitectureX86_64
+
+const DefaultAlias = "live"
+
+// Only the most recent versions are recorded, to keep the manifest small.
+const maxRecordedVersions = 20
+
+// bootstrap is the executable that custom runtimes run.
+const bootstrap = "bootstrap"
+
+// Migrations upgrade lambda manifests written by older versions of ecology.
+var Migrations = schema_migration.Migrations{
+	schema_migration.Unversioned,
+	{
+		Description: "move AWS lambdas from go1.x to the provided.al2023 runtime",
+		Apply:       moveToCustomRuntime,
+	},
+}
+
+var SchemaVersion = Migrations.Current()
+
+// Upgrade migrates the lambda manifest and its executor role manifest.
+func Upgrade(doc schema_migration.Document) error {
+	if err := Migrations.Upgrade("Lambda", doc); err != nil {
+		return err
+	}
+	if role := schema_migration.Child(doc, "ExecutorRoleManifest"); role != nil {
+		return role_manifest.Upgrade(role)
+	}
+	return nil
+}
+
+// moveToCustomRuntime configures AWS lambdas to build a bootstrap executable
+// for provided.al2023. Their functions are still deployed on go1.x, so the
+// next push moves them over.
+func moveToCustomRuntime(doc schema_migration.Document) error {
+	config := schema_migration.Child(doc, "Config")
+	if config == nil {
+		return nil
+	}
+	if deploy := schema_migration.Child(doc, "Deploy"); deploy != nil && deploy["Platform"] == "GCP" {
+		return nil
+	}
+	if runtime, _ := config["Runtime"].(string); runtime == "" || runtime == "go1.x" {
+		config["Runtime"] = DefaultRuntime
+	}
+	if architecture, _ := config["Architecture"].(string); architecture == "" {
+		config["Architecture"] = DefaultArchitecture
+	}
+	return nil
 }
 
-func New(projectDir string, projectName string, lambdaName string, platform string, region string, o *output.Output) (lm *LambdaManifest, err error) {
+// LambdaSummary is what commands report about a lambda in their results.
+type LambdaSummary struct {
+	Name         string
+	FunctionName string
+	Arn          string
+	CodeSha256   string
+	CodeHash     string
+	RoleArn      string
+	Runtime      string
+	Architecture string
+	AliasArn     string
+	AliasVersion string
+}
+
+func (lm *LambdaManifest) Summary() LambdaSummary {
+	return LambdaSummary{
+		Name:         lm.Config.Name,
+		FunctionName: lm.Config.FullyQualifiedName,
+		Arn:          lm.Deploy.Arn,
+		CodeSha256:   lm.Deploy.CodeSha256,
+		CodeHash:     lm.Deploy.LastDeployedHash,
+		RoleArn:      lm.ExecutorRoleManifest.Deploy.Arn,
+		Runtime:      lm.Deploy.Runtime,
+		Architecture: lm.Deploy.Architecture,
+		AliasArn:     lm.Deploy.AliasArn,
+		AliasVersion: lm.Deploy.AliasVersion,
+	}
+}
+
+func New(projectDir string, projectName string, lambdaName string, platform string, region string, runtime string, architecture string, o *output.Output) (lm *LambdaManifest, err error) {
 	fullyQualifiedLambdaName := projectName + "-" + lambdaName
 	erm := role_manifest.New(fullyQualifiedLambdaName + "-executor")
 	configInfoFolderPath := fmt.Sprintf("%s/lambda/%s", projectDir, lambdaName)
@@ -45,6 +196,7 @@ func New(projectDir string, projectName string, lambdaName string, platform stri
 	configInfoBuiltPath := fmt.Sprintf("%s/%s", configInfoFolderPath, fullyQualifiedLambdaName)
 	configInfoZippedPath := fmt.Sprintf("%s/%s.zip", configInfoFolderPath, fullyQualifiedLambdaName)
 	lambdaManifest := LambdaManifest{
+		SchemaVersion: SchemaVersion,
 		Config: LambdaConfigInfo{
 			Name:               lambdaName,
 			FullyQualifiedName: fullyQualifiedLambdaName,
@@ -61,37 +213,501 @@ func New(projectDir string, projectName string, lambdaName string, platform stri
 		},
 		ExecutorRoleManifest: erm,
 	}
+	// Cloud Functions are built by GCP, which picks the runtime itself.
+	if platform != "GCP" {
+		lambdaManifest.Config.Runtime = runtime
+		lambdaManifest.Config.Architecture = architecture
+	}
 	lm = &lambdaManifest
 	return
 }
 
-func (lm *LambdaManifest) packageToDeploy(o *output.Output) (err error) {
+// Renamed returns an undeployed copy of the lambda under the given project and
+// lambda names, with its folder, source file, build outputs and executor role
+// renamed to match. Its recorded versions are dropped, since they belong to
+// the old function, which is deleted once the renamed one is pushed.
+func (lm *LambdaManifest) Renamed(projectName string, lambdaName string) LambdaManifest {
+	renamed := *lm
+	renamed.Config.Name = lambdaName
+	renamed.Config.FullyQualifiedName = projectName + "-" + lambdaName
+	renamed.Config.FolderPath = fmt.Sprintf("%s/%s", filepath.Dir(lm.Config.FolderPath), lambdaName)
+	renamed.Config.CodePath = fmt.Sprintf("%s/%s.go", renamed.Config.FolderPath, lambdaName)
+	renamed.Config.BuiltPath = fmt.Sprintf("%s/%s", renamed.Config.FolderPath, renamed.Config.FullyQualifiedName)
+	renamed.Config.ZippedPath = fmt.Sprintf("%s/%s.zip", renamed.Config.FolderPath, renamed.Config.FullyQualifiedName)
+	renamed.Deploy.LastDeployedHash = ""
+	renamed.Deploy.CodeSha256 = ""
+	renamed.Deploy.Arn = ""
+	renamed.Deploy.Runtime = ""
+	renamed.Deploy.Architecture = ""
+	renamed.Deploy.Handler = ""
+	renamed.Deploy.SettingsHash = ""
+	renamed.Deploy.Versions = nil
+	renamed.Deploy.Alias = ""
+	renamed.Deploy.AliasArn = ""
+	renamed.Deploy.AliasVersion = ""
+	renamed.Deploy.RenamedFrom = lm.Config.FullyQualifiedName
+	renamed.ExecutorRoleManifest = role_manifest.New(renamed.Config.FullyQualifiedName + "-executor")
+	return renamed
+}
+
+// MoveFilesTo moves the lambda's folder and source file to where the renamed
+// lambda expects them, dropping stale build outputs. Files that were already
+// moved by an earlier attempt are left alone.
+func (lm *LambdaManifest) MoveFilesTo(renamed *LambdaManifest, o *output.Output) (err error) {
+	o.Info("LambdaManifest - MoveFilesTo - %s", renamed.Config.FolderPath).Indent()
+	if lm.Config.FolderPath != renamed.Config.FolderPath {
+		if _, err = os.Stat(lm.Config.FolderPath); err == nil {
+			if _, err = os.Stat(renamed.Config.FolderPath); err == nil {
+				return errors.New(fmt.Sprintf("Folder already exists: %s", renamed.Config.FolderPath))
+			}
+			if err = os.Rename(lm.Config.FolderPath, renamed.Config.FolderPath); err != nil {
+				return
+			}
+		} else if _, err = os.Stat(renamed.Config.FolderPath); err != nil {
+			return errors.New(fmt.Sprintf("Couldn't find lambda folder %s", lm.Config.FolderPath))
+		}
+	}
+	movedCodePath := filepath.Join(renamed.Config.FolderPath, filepath.Base(lm.Config.CodePath))
+	if movedCodePath != renamed.Config.CodePath {
+		if _, err = os.Stat(movedCodePath); err == nil {
+			if err = os.Rename(movedCodePath, renamed.Config.CodePath); err != nil {
+				return
+			}
+		}
+	}
+	os.Remove(filepath.Join(renamed.Config.FolderPath, filepath.Base(lm.Config.BuiltPath)))
+	os.Remove(filepath.Join(renamed.Config.FolderPath, filepath.Base(lm.Config.ZippedPath)))
+	o.Dedent().Done()
+	return nil
+}
+
+func (lm *LambdaManifest) IsDeployed() bool {
+	return lm.Deploy.Arn != ""
+}
+
+// InvokeArn is what API routes invoke: the alias, once there is one, so that
+// rolling the alias back moves the routes with it.
+func (lm *LambdaManifest) InvokeArn() string {
+	if lm.Deploy.AliasArn != "" {
+		return lm.Deploy.AliasArn
+	}
+	return lm.Deploy.Arn
+}
+
+// PlannedInvokeArn is what API routes will invoke once the lambda is pushed,
+// or "" if that won't be known until then, because the function or its alias
+// hasn't been created yet.
+func (lm *LambdaManifest) PlannedInvokeArn() string {
+	if !lm.IsDeployed() || lm.alias() != lm.Deploy.Alias {
+		return ""
+	}
+	return lm.InvokeArn()
+}
+
+// alias is the name of the alias that API routes invoke, or "" on platforms
+// that don't keep versions.
+func (lm *LambdaManifest) alias() string {
+	if lm.Deploy.Platform == "GCP" {
+		return ""
+	}
+	if lm.Config.Alias != "" {
+		return lm.Config.Alias
+	}
+	return DefaultAlias
+}
+
+// buildEnv is the environment that the lambda is built in.
+func (lm *LambdaManifest) buildEnv() []string {
+	goarch := "amd64"
+	if lm.Config.Architecture == platform.ArchitectureArm64 {
+		goarch = "arm64"
+	}
+	return []string{"GOOS=linux", "GOARCH=" + goarch, "CGO_ENABLED=0"}
+}
+
+// codeHash identifies everything that goes into building the lambda, so that
+// a change to any of it, even in a shared package or a dependency, is pushed.
+func (lm *LambdaManifest) codeHash() (string, error) {
+	return file_hash.ComputeBuildInputsHash(lm.Config.FolderPath, filepath.Base(lm.Config.CodePath), lm.buildEnv())
+}
+
+// SetBuildCache sets the cache that the lambda is built into.
+func (lm *LambdaManifest) SetBuildCache(cache *build_cache.Cache) {
+	lm.buildCache = cache
+}
+
+// SetSecrets sets where the lambda's secrets are looked up.
+func (lm *LambdaManifest) SetSecrets(secrets Secrets) {
+	lm.secrets = secrets
+}
+
+// Leaving out file paths and the build ID, which hashes the source itself,
+// keeps the binary, and so the zip, the same wherever and whenever the same
+// code is built.
+var buildFlags = []string{"-trimpath", "-ldflags=-buildid="}
+
+// packageToDeploy builds the lambda, whose build inputs have the given hash,
+// and zips it. With a build cache, the zip is reused if the same inputs were
+// built before, and nothing is written to the lambda's folder. Without one,
+// the zip is also written to ZippedPath.
+func (lm *LambdaManifest) packageToDeploy(codeHash string, o *output.Output) (zipped []byte, err error) {
+	if lm.buildCache == nil {
+		return lm.packageInFolder(o)
+	}
+	key := lm.cacheKey(codeHash)
+	if zipped, ok := lm.buildCache.Get(key); ok {
+		o.Success("LambdaManifest - packageToDeploy - Reusing Cached Build %s", key)
+		return zipped, nil
+	}
+	if lm.Deploy.Platform == "GCP" {
+		var files []deploy_zip.File
+		if files, err = cloud_function_source.Files(lm.Config.CodePath); err != nil {
+			return
+		}
+		zipped, err = lm.zip(files, o)
+	} else {
+		var dir string
+		if dir, err = lm.buildCache.TempDir(); err != nil {
+			return
+		}
+		defer os.RemoveAll(dir)
+		builtPath := filepath.Join(dir, filepath.Base(lm.Config.BuiltPath))
+		if err = lm.build(builtPath, o); err != nil {
+			return
+		}
+		zipped, err = lm.zip([]deploy_zip.File{{Path: builtPath, Name: bootstrap, Executable: true}}, o)
+	}
+	if err != nil {
+		return
+	}
+	if err = lm.buildCache.Put(key, zipped); err != nil {
+		o.Warning("Couldn't cache the build: %v", err)
+		err = nil
+	}
+	return
+}
+
+// cacheKey identifies a build by its inputs, how it was built, and what it
+// was zipped as.
+func (lm *LambdaManifest) cacheKey(codeHash string) string {
+	parts := []string{codeHash, lm.Deploy.Platform, bootstrap}
+	if lm.Deploy.Platform == "GCP" {
+		parts[2] = cloud_function_source.EntryPoint
+	}
+	parts = append(parts, lm.buildEnv()...)
+	return build_cache.Key(append(parts, buildFlags...)...)
+}
+
+func (lm *LambdaManifest) packageInFolder(o *output.Output) (zipped []byte, err error) {
+	var files []deploy_zip.File
+	if lm.Deploy.Platform == "GCP" {
+		// Cloud Functions are built by GCP from source, so only the code is zipped.
+		if files, err = cloud_function_source.Files(lm.Config.CodePath); err != nil {
+			return
+		}
+	} else {
+		if err = lm.build(lm.Config.BuiltPath, o); err != nil {
+			return
+		}
+		files = []deploy_zip.File{{Path: lm.Config.BuiltPath, Name: bootstrap, Executable: true}}
+	}
+	zipped, err = lm.zip(files, o)
+	if err != nil {
+		return
+	}
+	err = atomic_file.WriteFile(lm.Config.ZippedPath, zipped, 0644)
+	return
+}
+
+func (lm *LambdaManifest) build(builtPath string, o *output.Output) (err error) {
 	o.Info("LambdaManifest - packageToDeploy - Build").Indent()
-	buildArgs := strings.Split(fmt.Sprintf("GOOS=linux GOARCH=amd64 CGO_ENABLED=0 go build -o %s %s", lm.Config.BuiltPath, lm.Config.CodePath), " ")
+	builtPath, err = filepath.Abs(builtPath)
+	if err != nil {
+		return
+	}
+	buildArgs := append(append([]string{"build"}, buildFlags...), "-o", builtPath, filepath.Base(lm.Config.CodePath))
 	ctx, cancelBuild := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancelBuild()
-	if result, err := exec.CommandContext(ctx, "env", buildArgs...).CombinedOutput(); err != nil {
-		o.Failure(string(result))
+	env := lm.buildEnv()
+	o.Debug("%s go %s", strings.Join(env, " "), strings.Join(buildArgs, " "))
+	build := exec.CommandContext(ctx, "go", buildArgs...)
+	// Built from the lambda's folder, like codeHash, so both see the same module.
+	build.Dir = lm.Config.FolderPath
+	build.Env = append(os.Environ(), env...)
+	if result, err := build.CombinedOutput(); err != nil {
+		o.Failure("%s", result)
 		return err
 	}
 	o.Dedent().Done()
+	return
+}
 
+func (lm *LambdaManifest) zip(files []deploy_zip.File, o *output.Output) (zipped []byte, err error) {
 	o.Info("LambdaManifest - packageToDeploy - Zip").Indent()
-	zipArgs := strings.Split(fmt.Sprintf("-j %s %s", lm.Config.ZippedPath, lm.Config.BuiltPath), " ")
-	ctx, cancelZip := context.WithTimeout(context.Background(), 10*time.Second)
-	defer cancelZip()
-	if result, err := exec.CommandContext(ctx, "zip", zipArgs...).CombinedOutput(); err != nil {
-		o.Failure(string(result))
-		return err
+	for _, file := range files {
+		if file.Path != "" {
+			o.Debug("zip %s", file.Path)
+		} else {
+			o.Debug("zip generated %s", file.Name)
+		}
 	}
+	zipped, err = deploy_zip.Create(files)
+	if err != nil {
+		return
+	}
+	o.Debug("Zip CodeSha256: %s", deploy_zip.CodeSha256(zipped))
 	o.Dedent().Done()
 	return
 }
 
-func (lm *LambdaManifest) PushToPlatform(o *output.Output) (err error) {
+func (lm *LambdaManifest) handler() string {
+	if lm.Deploy.Platform == "GCP" {
+		return cloud_function_source.EntryPoint
+	}
+	// Custom runtimes ignore the handler, and run the bootstrap executable.
+	return bootstrap
+}
+
+type settingChange struct {
+	field string
+	want  string
+	have  string
+}
+
+// describe lists the settings that differ. Settings the lambda doesn't
+// configure, such as the runtime on GCP, are left alone.
+func describe(settings []settingChange) (changes []string) {
+	for _, c := range settings {
+		if c.want != "" && c.want != c.have {
+			changes = append(changes, fmt.Sprintf("%s %q -> %q", c.field, c.have, c.want))
+		}
+	}
+	return
+}
+
+// changesSinceDeploy describes how the lambda's configuration differs from the
+// configuration it was last pushed with.
+func (lm *LambdaManifest) changesSinceDeploy() (changes []string) {
+	changes = describe([]settingChange{
+		{"runtime", lm.Config.Runtime, lm.Deploy.Runtime},
+		{"architecture", lm.Config.Architecture, lm.Deploy.Architecture},
+		{"handler", lm.handler(), lm.Deploy.Handler},
+		{"alias", lm.alias(), lm.Deploy.Alias},
+	})
+	if lm.settingsHash() != lm.Deploy.SettingsHash {
+		changes = append(changes, "function settings changed")
+	}
+	return
+}
+
+// configurationChanges describes how the function's configuration differs
+// from the lambda's, with the given settings, in the ways that
+// UpdateFunctionConfiguration can fix.
+func (lm *LambdaManifest) configurationChanges(f *platform.Function, want platform.FunctionSettings) (changes []string) {
+	changes = describe([]settingChange{
+		{"runtime", lm.Config.Runtime, f.Runtime},
+		{"handler", lm.handler(), f.Handler},
+	})
+	// Settings that aren't set are compared too, since they revert when pushed.
+	for _, c := range []settingChange{
+		{"description", want.Description, f.Description},
+		{"memory size", megabytes(want.MemorySize), megabytes(f.MemorySize)},
+		{"timeout", seconds(want.Timeout), seconds(f.Timeout)},
+		{"ephemeral storage", megabytes(want.EphemeralStorage), megabytes(f.EphemeralStorage)},
+		{"reserved concurrency", concurrency(want.ReservedConcurrency), concurrency(f.ReservedConcurrency)},
+	} {
+		if c.want != c.have {
+			changes = append(changes, fmt.Sprintf("%s %q -> %q", c.field, c.have, c.want))
+		}
+	}
+	// Only the names are shown, since the values can be secret.
+	if names := changedVariables(want.Environment, f.Environment); len(names) > 0 {
+		changes = append(changes, fmt.Sprintf("environment variables %s", strings.Join(names, ", ")))
+	}
+	return
+}
+
+func megabytes(n int64) string {
+	if n == 0 {
+		return ""
+	}
+	return fmt.Sprintf("%d MB", n)
+}
+
+func seconds(n int64) string {
+	if n == 0 {
+		return ""
+	}
+	return fmt.Sprintf("%ds", n)
+}
+
+func concurrency(n *int64) string {
+	if n == nil {
+		return ""
+	}
+	return fmt.Sprintf("%d", *n)
+}
+
+func changedVariables(want map[string]string, have map[string]string) (names []string) {
+	for name, value := range want {
+		if haveValue, ok := have[name]; !ok || haveValue != value {
+			names = append(names, name)
+		}
+	}
+	for name := range have {
+		if _, ok := want[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return
+}
+
+// settingsHash identifies the lambda's function settings, and the secrets in
+// its environment. Lambdas that don't set any have no hash, so that lambdas
+// pushed before settings existed aren't pushed again. Secrets are hashed by
+// their fingerprints, so that working out whether to push doesn't need the
+// passphrase.
+func (lm *LambdaManifest) settingsHash() string {
+	if reflect.DeepEqual(lm.Config.FunctionSettings, platform.FunctionSettings{}) && len(lm.Config.Secrets) == 0 {
+		return ""
+	}
+	data, _ := json.Marshal(lm.Config.FunctionSettings)
+	if len(lm.Config.Secrets) > 0 && lm.secrets != nil {
+		fingerprints := make(map[string]string)
+		for variable, secret := range lm.Config.Secrets {
+			// Missing secrets are caught by validateSettings.
+			fingerprints[variable], _ = lm.secrets.Fingerprint(secret)
+		}
+		secretData, _ := json.Marshal(fingerprints)
+		data = append(data, secretData...)
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// functionSettings are the settings to push, with the values of the lambda's
+// secrets added to its environment.
+func (lm *LambdaManifest) functionSettings() (settings platform.FunctionSettings, err error) {
+	settings = lm.Config.FunctionSettings
+	if settings.Description == "" {
+		settings.Description = fmt.Sprintf("Ecology-Generated Lambda %s.", lm.Config.FullyQualifiedName)
+	}
+	if len(lm.Config.Secrets) == 0 {
+		return
+	}
+	settings.Environment = make(map[string]string)
+	for name, value := range lm.Config.Environment {
+		settings.Environment[name] = value
+	}
+	for variable, secret := range lm.Config.Secrets {
+		if settings.Environment[variable], err = lm.secrets.Value(secret); err != nil {
+			return
+		}
+	}
+	return
+}
+
+// validateSettings checks the function settings against Lambda's limits,
+// before anything is pushed.
+func (lm *LambdaManifest) validateSettings() error {
+	settings := lm.Config.FunctionSettings
+	problems := make([]string, 0)
+	limits := []struct {
+		field string
+		value int64
+		min   int64
+		max   int64
+	}{
+		{"MemorySize", settings.MemorySize, 128, 10240},
+		{"Timeout", settings.Timeout, 1, 900},
+		{"EphemeralStorage", settings.EphemeralStorage, 512, 10240},
+	}
+	for _, l := range limits {
+		if l.value != 0 && (l.value < l.min || l.value > l.max) {
+			problems = append(problems, fmt.Sprintf("%s should be between %d and %d, not %d", l.field, l.min, l.max, l.value))
+		}
+	}
+	if settings.ReservedConcurrency != nil && *settings.ReservedConcurrency < 0 {
+		problems = append(problems, "ReservedConcurrency can't be negative")
+	}
+	for name := range settings.Environment {
+		if !environmentVariableRegex.MatchString(name) {
+			problems = append(problems, fmt.Sprintf("Environment variable %q should be letters, digits and underscores, starting with a letter", name))
+		}
+	}
+	for name, secret := range lm.Config.Secrets {
+		if !environmentVariableRegex.MatchString(name) {
+			problems = append(problems, fmt.Sprintf("Environment variable %q should be letters, digits and underscores, starting with a letter", name))
+		}
+		if _, ok := settings.Environment[name]; ok {
+			problems = append(problems, fmt.Sprintf("Environment variable %q is set both in Environment and from a secret", name))
+		}
+		if lm.secrets == nil {
+			problems = append(problems, fmt.Sprintf("Environment variable %q is set from secret %s, but there are no secrets to look it up in", name, secret))
+		} else if _, err := lm.secrets.Fingerprint(secret); err != nil {
+			problems = append(problems, fmt.Sprintf("Environment variable %q: %v", name, err))
+		}
+	}
+	if len(problems) > 0 {
+		sort.Strings(problems)
+		return errors.New(fmt.Sprintf("Lambda %s has invalid settings:\n%s", lm.Config.Name, strings.Join(problems, "\n")))
+	}
+	return nil
+}
+
+var environmentVariableRegex = regexp.MustCompile("^[a-zA-Z][a-zA-Z0-9_]*$")
+
+// Plan works out what PushToPlatform would do to the lambda and its executor
+// role, without changing anything.
+func (lm *LambdaManifest) Plan(p platform.Platform) (actions []plan_manifest.Action, err error) {
+	if err = lm.validateSettings(); err != nil {
+		return
+	}
+	roleAction, err := lm.ExecutorRoleManifest.Plan(p)
+	if err != nil {
+		return
+	}
+	action := plan_manifest.Action{
+		Resource: "lambda",
+		Name:     lm.Config.FullyQualifiedName,
+	}
+	action.CodeHash, err = lm.codeHash()
+	if err != nil {
+		return
+	}
+	changes := lm.changesSinceDeploy()
+	if action.CodeHash == lm.Deploy.LastDeployedHash && len(changes) == 0 {
+		action.Change = plan_manifest.ChangeNone
+		action.Reason = "code unchanged"
+		return []plan_manifest.Action{roleAction, action}, nil
+	}
+	_, err = p.GetFunction(lm.Config.FullyQualifiedName)
+	if err == nil {
+		action.Change = plan_manifest.ChangeUpdate
+		if action.CodeHash != lm.Deploy.LastDeployedHash {
+			changes = append([]string{"code changed"}, changes...)
+		}
+		action.Reason = strings.Join(changes, ", ")
+	} else if platform.IsNotFound(err) {
+		err = nil
+		action.Change = plan_manifest.ChangeCreate
+		action.Reason = "does not exist"
+	} else {
+		return
+	}
+	return []plan_manifest.Action{roleAction, action}, nil
+}
+
+func (lm *LambdaManifest) PushToPlatform(p platform.Platform, o *output.Output) (err error) {
 	o.Info("LambdaManifest - %s.PushToPlatform", lm.Config.Name).Indent()
 
-	err = lm.ExecutorRoleManifest.PushToPlatform(o)
+	if err = lm.validateSettings(); err != nil {
+		o.Error(err)
+		return
+	}
+
+	err = lm.ExecutorRoleManifest.PushToPlatform(p, o)
 	if err != nil {
 		o.Error(err)
 		return
@@ -100,103 +716,383 @@ func (lm *LambdaManifest) PushToPlatform(o *output.Output) (err error) {
 	var currentCodeHash string
 	if lm.Deploy.LastDeployedHash != "" {
 		o.Info("LambdaManifest - PushToPlatform - Checking if Nescessary").Indent()
-		currentCodeHash, err = file_hash.ComputeFileHash(lm.Config.CodePath)
+		currentCodeHash, err = lm.codeHash()
 		if err != nil {
 			return err
 		}
-		o.Info("Old Code Hash: %s", lm.Deploy.LastDeployedHash)
-		o.Info("New Code Hash: %s", currentCodeHash)
-		if currentCodeHash == lm.Deploy.LastDeployedHash {
+		o.Debug("Old Code Hash: %s", lm.Deploy.LastDeployedHash)
+		o.Debug("New Code Hash: %s", currentCodeHash)
+		changes := lm.changesSinceDeploy()
+		if currentCodeHash == lm.Deploy.LastDeployedHash && len(changes) == 0 {
 			o.Dedent().Success("Code hasn't changed since last push, no push needed.").Dedent().Done()
 			return nil
 		}
-		o.Dedent().Warning("Code has changed since last push.")
+		o.Dedent()
+		if currentCodeHash != lm.Deploy.LastDeployedHash {
+			o.Warning("Code has changed since last push.")
+		}
+		for _, change := range changes {
+			o.Warning("Configuration has changed since last push: %s", change)
+		}
 	} else {
 		o.Info("LambdaManifest - PushToPlatform - First Lambda Push")
-		if currentCodeHash, err = file_hash.ComputeFileHash(lm.Config.CodePath); err != nil {
+		if currentCodeHash, err = lm.codeHash(); err != nil {
 			return err
 		}
 	}
 
-	err = lm.packageToDeploy(o)
+	zipBytes, err := lm.packageToDeploy(currentCodeHash, o)
 	if err != nil {
 		return err
 	}
-
-	o.Info("LambdaManifest - PushToPlatform - Read Zip").Indent()
-	zipBytes, err := ioutil.ReadFile(lm.Config.ZippedPath)
+	settings, err := lm.functionSettings()
 	if err != nil {
-		return
+		o.Error(err)
+		return err
 	}
-	o.Dedent().Done()
-
-	svc := lambda.New(session.New(), aws.NewConfig().WithRegion(lm.Deploy.Region))
 
 	o.Info("LambdaManifest - PushToPlatform - Check If Lambda Exists").Indent()
-	_, err = svc.GetFunction(&lambda.GetFunctionInput{
-		FunctionName: aws.String(lm.Config.FullyQualifiedName),
-	})
-	var arn string
+	deployed, err := p.GetFunction(lm.Config.FullyQualifiedName)
 	if err == nil {
 		o.Warning("Lambda Already Exists.").Dedent().Done()
-		o.Info("LambdaManifest - PushToPlatform - Update Lambda").Indent()
-		updateFunctionRequest := &lambda.UpdateFunctionCodeInput{
-			ZipFile:      zipBytes,
-			FunctionName: aws.String(lm.Config.FullyQualifiedName),
-			Publish:      aws.Bool(true),
-		}
-		updateResult, err := svc.UpdateFunctionCode(updateFunctionRequest)
+		deployed, err = lm.update(p, deployed, zipBytes, settings, o)
 		if err != nil {
 			return err
 		}
-		arn = *updateResult.FunctionArn
-		o.Dedent().Done()
-	} else {
+	} else if platform.IsNotFound(err) {
 		o.Warning("Lambda Does Not Exist.").Dedent().Done()
 		o.Info("LambdaManifest - PushToPlatform - Create Lambda").Indent()
-		createFunctionRequest := &lambda.CreateFunctionInput{
-			Code: &lambda.FunctionCode{
-				ZipFile: zipBytes,
-			},
-			Description:  aws.String(fmt.Sprintf("Ecology-Generated Lambda %s.", lm.Config.FullyQualifiedName)),
-			FunctionName: aws.String(lm.Config.FullyQualifiedName),
-			Handler:      aws.String(lm.Config.FullyQualifiedName),
-			Publish:      aws.Bool(true),
-			Role:         aws.String(lm.ExecutorRoleManifest.Deploy.Arn),
-			Runtime:      aws.String("go1.x"),
-		}
-		createResult, err := svc.CreateFunction(createFunctionRequest)
+		deployed, err = p.CreateFunction(&platform.CreateFunctionInput{
+			Name:             lm.Config.FullyQualifiedName,
+			Handler:          lm.handler(),
+			Role:             lm.ExecutorRoleManifest.Deploy.Arn,
+			Runtime:          lm.Config.Runtime,
+			Architecture:     lm.Config.Architecture,
+			ZipFile:          zipBytes,
+			FunctionSettings: settings,
+		})
 		if err != nil {
 			return err
 		}
-		arn = *createResult.FunctionArn
 		o.Dedent().Done()
+	} else {
+		return err
 	}
 	lm.Deploy.LastDeployedHash = currentCodeHash
-	lm.Deploy.Arn = arn
+	lm.Deploy.CodeSha256 = deployed.CodeSha256
+	lm.Deploy.Arn = deployed.Arn
+	lm.Deploy.Runtime = deployed.Runtime
+	lm.Deploy.Architecture = deployed.Architecture
+	lm.Deploy.Handler = deployed.Handler
+	lm.Deploy.SettingsHash = lm.settingsHash()
+	if err = lm.publish(p, o); err != nil {
+		return err
+	}
+	o.Dedent().Done()
+	return nil
+}
+
+// publish publishes what was just pushed as a version, records it, and points
+// the alias at it, on platforms that keep versions. Publishing is always
+// asked for, since updating only the configuration doesn't publish a version,
+// and the platform returns the latest version if nothing changed.
+func (lm *LambdaManifest) publish(p platform.Platform, o *output.Output) (err error) {
+	ap, ok := p.(platform.AliasPlatform)
+	if !ok || lm.alias() == "" {
+		return nil
+	}
+	o.Info("LambdaManifest - PushToPlatform - Publish Version").Indent()
+	name := lm.Config.FullyQualifiedName
+	version, err := ap.PublishVersion(name)
+	if err != nil {
+		o.Error(err)
+		return
+	}
+	lm.recordVersion(version)
+	alias, err := ap.PutAlias(name, lm.alias(), version.Version)
+	if err != nil {
+		o.Error(err)
+		return
+	}
+	lm.Deploy.Alias = alias.Name
+	lm.Deploy.AliasArn = alias.Arn
+	lm.Deploy.AliasVersion = alias.FunctionVersion
+	o.Success("Alias %s Points At Version %s.", alias.Name, alias.FunctionVersion)
 	o.Dedent().Done()
 	return nil
 }
 
-func (lm *LambdaManifest) DeleteFromPlatform(o *output.Output) (err error) {
+func (lm *LambdaManifest) recordVersion(f *platform.Function) {
+	versions := lm.Deploy.Versions
+	if n := len(versions); n > 0 && versions[n-1].Version == f.Version {
+		return
+	}
+	versions = append(versions, LambdaVersion{
+		Version:      f.Version,
+		CodeHash:     lm.Deploy.LastDeployedHash,
+		CodeSha256:   f.CodeSha256,
+		SettingsHash: lm.Deploy.SettingsHash,
+		Published:    time.Now().UTC(),
+	})
+	if extra := len(versions) - maxRecordedVersions; extra > 0 {
+		versions = versions[extra:]
+	}
+	lm.Deploy.Versions = versions
+}
+
+// Rollback points the lambda's alias at one of its recorded versions, by
+// default the one before the version it points at now. Nothing is built or
+// uploaded, so it takes effect at once. The next push that changes the lambda
+// points the alias at the new version.
+func (lm *LambdaManifest) Rollback(p platform.Platform, toVersion string, o *output.Output) (err error) {
+	o.Info("LambdaManifest - Rollback - %s", lm.Config.FullyQualifiedName).Indent()
+	ap, ok := p.(platform.AliasPlatform)
+	if !ok || lm.alias() == "" {
+		err = errors.New(fmt.Sprintf("Platform %s doesn't keep versions of lambdas to roll back to", p.Name()))
+		o.Error(err)
+		return
+	}
+	if lm.Deploy.AliasVersion == "" {
+		err = errors.New(fmt.Sprintf("Lambda %s has no alias to roll back yet, push it first", lm.Config.Name))
+		o.Error(err)
+		return
+	}
+	versions := lm.Deploy.Versions
+	current, target := -1, -1
+	recorded := make([]string, 0)
+	for i, v := range versions {
+		if v.Version == lm.Deploy.AliasVersion {
+			current = i
+		}
+		if v.Version == toVersion {
+			target = i
+		}
+		recorded = append(recorded, v.Version)
+	}
+	if toVersion == "" {
+		if current < 1 {
+			err = errors.New(fmt.Sprintf("Lambda %s has no recorded version before %s to roll back to%s", lm.Config.Name, lm.Deploy.AliasVersion, lm.renamedNote()))
+			o.Error(err)
+			return
+		}
+		target = current - 1
+	} else if target == -1 {
+		err = errors.New(fmt.Sprintf("Lambda %s has no recorded version %s, its recorded versions are %s%s", lm.Config.Name, toVersion, strings.Join(recorded, ", "), lm.renamedNote()))
+		o.Error(err)
+		return
+	}
+	if target == current {
+		o.Warning("Alias %s Already Points At Version %s.", lm.Deploy.Alias, lm.Deploy.AliasVersion).Dedent().Done()
+		return nil
+	}
+	alias, err := ap.PutAlias(lm.Config.FullyQualifiedName, lm.Deploy.Alias, versions[target].Version)
+	if err != nil {
+		o.Error(err)
+		return
+	}
+	o.Success("Alias %s Now Points At Version %s, Instead Of %s.", alias.Name, alias.FunctionVersion, lm.Deploy.AliasVersion)
+	lm.Deploy.AliasArn = alias.Arn
+	lm.Deploy.AliasVersion = alias.FunctionVersion
+	o.Dedent().Done()
+	return nil
+}
+
+// renamedNote explains why versions from before a rename can't be rolled back
+// to, or is empty if the lambda was never renamed.
+func (lm *LambdaManifest) renamedNote() string {
+	if lm.Deploy.RenamedFrom == "" {
+		return ""
+	}
+	return fmt.Sprintf(", versions from before it was renamed were deleted with %s", lm.Deploy.RenamedFrom)
+}
+
+// update brings an existing function in line with the lambda. Its
+// configuration is updated first, since AWS no longer accepts code for
+// functions on retired runtimes such as go1.x.
+func (lm *LambdaManifest) update(p platform.Platform, f *platform.Function, zipBytes []byte, settings platform.FunctionSettings, o *output.Output) (updated *platform.Function, err error) {
+	updated = f
+	// Settings that were removed are compared with the defaults they revert to.
+	changes := lm.configurationChanges(f, platform.WithDefaults(settings, p.DefaultFunctionSettings()))
+	if len(changes) > 0 {
+		o.Info("LambdaManifest - PushToPlatform - Update Lambda Configuration").Indent()
+		for _, change := range changes {
+			o.Info("%s", change)
+		}
+		updated, err = p.UpdateFunctionConfiguration(&platform.UpdateFunctionConfigurationInput{
+			Name:             lm.Config.FullyQualifiedName,
+			Handler:          lm.handler(),
+			Runtime:          lm.Config.Runtime,
+			FunctionSettings: settings,
+		})
+		if err != nil {
+			return
+		}
+		o.Dedent().Done()
+	}
+	// Zips are reproducible, so a change that didn't change the binary, such
+	// as to a comment, doesn't need uploading.
+	sameArchitecture := lm.Config.Architecture == "" || lm.Config.Architecture == updated.Architecture
+	if updated.CodeSha256 == deploy_zip.CodeSha256(zipBytes) && sameArchitecture {
+		o.Success("Lambda Already Runs This Exact Zip, No Upload Needed.")
+		return
+	}
+	o.Info("LambdaManifest - PushToPlatform - Update Lambda").Indent()
+	updated, err = p.UpdateFunctionCode(lm.Config.FullyQualifiedName, zipBytes, lm.Config.Architecture)
+	if err != nil {
+		return
+	}
+	o.Dedent().Done()
+	return
+}
+
+func (lm *LambdaManifest) DeleteFromPlatform(p platform.Platform, o *output.Output) (err error) {
 	o.Info("LambdaManifest - DeleteFromPlatform - %s", lm.Config.FullyQualifiedName).Indent()
 
-	err = lm.ExecutorRoleManifest.DeleteFromPlatform(o)
+	err = lm.ExecutorRoleManifest.DeleteFromPlatform(p, o)
 	if err != nil {
 		o.Error(err)
 		return
 	}
 
-	deleteFunctionRequest := &lambda.DeleteFunctionInput{
-		FunctionName: aws.String(lm.Config.FullyQualifiedName),
+	err = p.DeleteFunction(lm.Config.FullyQualifiedName)
+	if platform.IsNotFound(err) {
+		o.Warning("Lambda %s was already gone from the platform.", lm.Config.FullyQualifiedName)
+		err = nil
 	}
-	svc := lambda.New(session.New(), aws.NewConfig().WithRegion(lm.Deploy.Region))
-	_, err = svc.DeleteFunction(deleteFunctionRequest)
 	if err != nil {
 		o.Error(err)
 		return err
 	}
 	lm.Deploy.Arn = ""
 	lm.Deploy.LastDeployedHash = ""
+	lm.Deploy.CodeSha256 = ""
+	lm.Deploy.Runtime = ""
+	lm.Deploy.Architecture = ""
+	lm.Deploy.Handler = ""
+	lm.Deploy.SettingsHash = ""
+	lm.Deploy.Versions = nil
+	lm.Deploy.Alias = ""
+	lm.Deploy.AliasArn = ""
+	lm.Deploy.AliasVersion = ""
 	return nil
 }
+
+// PullFromPlatform updates the deploy info of the lambda (and its executor
+// role) to match what is actually on the platform, and describes each change.
+func (lm *LambdaManifest) PullFromPlatform(p platform.Platform, o *output.Output) (changes []string, err error) {
+	o.Info("LambdaManifest - PullFromPlatform - %s", lm.Config.FullyQualifiedName).Indent()
+
+	changes, err = lm.ExecutorRoleManifest.PullFromPlatform(p, o)
+	if err != nil {
+		o.Error(err)
+		return
+	}
+
+	deployed, err := p.GetFunction(lm.Config.FullyQualifiedName)
+	if platform.IsNotFound(err) {
+		err = nil
+		if lm.Deploy.Arn != "" || lm.Deploy.LastDeployedHash != "" {
+			changes = append(changes, fmt.Sprintf("Lambda %s no longer exists on the platform, marking it undeployed", lm.Config.FullyQualifiedName))
+		}
+		lm.Deploy.Arn = ""
+		lm.Deploy.LastDeployedHash = ""
+		lm.Deploy.CodeSha256 = ""
+		lm.Deploy.SettingsHash = ""
+		lm.Deploy.Versions = nil
+		lm.Deploy.Alias = ""
+		lm.Deploy.AliasArn = ""
+		lm.Deploy.AliasVersion = ""
+		o.Dedent().Done()
+		return
+	} else if err != nil {
+		o.Error(err)
+		return
+	}
+
+	if deployed.Arn != lm.Deploy.Arn {
+		changes = append(changes, fmt.Sprintf("Lambda %s Arn changed from %q to %q", lm.Config.FullyQualifiedName, lm.Deploy.Arn, deployed.Arn))
+		lm.Deploy.Arn = deployed.Arn
+	}
+	if lm.Deploy.CodeSha256 == "" {
+		// Manifests written before the hash was recorded can't tell whether the
+		// code changed, so trust that it's what was last pushed.
+		lm.Deploy.CodeSha256 = deployed.CodeSha256
+	} else if deployed.CodeSha256 != lm.Deploy.CodeSha256 {
+		// The deployed code isn't what we last pushed, so the next push must redeploy.
+		changes = append(changes, fmt.Sprintf("Lambda %s code was changed on the platform, clearing its last deployed hash", lm.Config.FullyQualifiedName))
+		lm.Deploy.CodeSha256 = deployed.CodeSha256
+		lm.Deploy.LastDeployedHash = ""
+	}
+	if ap, ok := p.(platform.AliasPlatform); ok && lm.Deploy.Alias != "" {
+		alias, aliasErr := ap.GetAlias(lm.Config.FullyQualifiedName, lm.Deploy.Alias)
+		if platform.IsNotFound(aliasErr) {
+			// The next push creates the alias again.
+			changes = append(changes, fmt.Sprintf("Lambda %s alias %s no longer exists on the platform", lm.Config.FullyQualifiedName, lm.Deploy.Alias))
+			lm.Deploy.Alias = ""
+			lm.Deploy.AliasArn = ""
+			lm.Deploy.AliasVersion = ""
+		} else if aliasErr != nil {
+			err = aliasErr
+			o.Error(err)
+			return
+		} else if alias.FunctionVersion != lm.Deploy.AliasVersion {
+			changes = append(changes, fmt.Sprintf("Lambda %s alias %s was moved from version %s to %s on the platform", lm.Config.FullyQualifiedName, lm.Deploy.Alias, lm.Deploy.AliasVersion, alias.FunctionVersion))
+			lm.Deploy.AliasArn = alias.Arn
+			lm.Deploy.AliasVersion = alias.FunctionVersion
+		}
+	}
+	o.Dedent().Done()
+	return
+}
+
+// Drift compares the live configuration of the lambda and its executor role
+// with what was last deployed, and describes every out-of-band change.
+func (lm *LambdaManifest) Drift(p platform.Platform) (drift []string, err error) {
+	drift, err = lm.ExecutorRoleManifest.Drift(p)
+	if err != nil {
+		return
+	}
+	name := lm.Config.FullyQualifiedName
+	live, err := p.GetFunction(name)
+	if platform.IsNotFound(err) {
+		err = nil
+		if lm.IsDeployed() {
+			drift = append(drift, fmt.Sprintf("Lambda %s was deleted from the platform", name))
+		}
+		return
+	} else if err != nil {
+		return
+	}
+	if !lm.IsDeployed() {
+		drift = append(drift, fmt.Sprintf("Lambda %s exists on the platform but was never deployed", name))
+		return
+	}
+	expected := []struct {
+		field    string
+		believed string
+		live     string
+	}{
+		{"code SHA256", lm.Deploy.CodeSha256, live.CodeSha256},
+		{"runtime", lm.Deploy.Runtime, live.Runtime},
+		{"architecture", lm.Deploy.Architecture, live.Architecture},
+		{"handler", lm.Deploy.Handler, live.Handler},
+		{"role", lm.ExecutorRoleManifest.Deploy.Arn, live.Role},
+	}
+	for _, e := range expected {
+		// Fields that weren't recorded when the lambda was deployed can't drift.
+		if e.believed != "" && e.believed != e.live {
+			drift = append(drift, fmt.Sprintf("Lambda %s %s is %q, but %q was deployed", name, e.field, e.live, e.believed))
+		}
+	}
+	if ap, ok := p.(platform.AliasPlatform); ok && lm.Deploy.AliasVersion != "" {
+		alias, aliasErr := ap.GetAlias(name, lm.Deploy.Alias)
+		if platform.IsNotFound(aliasErr) {
+			drift = append(drift, fmt.Sprintf("Lambda %s alias %s was deleted from the platform", name, lm.Deploy.Alias))
+		} else if aliasErr != nil {
+			return drift, aliasErr
+		} else if alias.FunctionVersion != lm.Deploy.AliasVersion {
+			drift = append(drift, fmt.Sprintf("Lambda %s alias %s points at version %s, but %s was deployed", name, lm.Deploy.Alias, alias.FunctionVersion, lm.Deploy.AliasVersion))
+		}
+	}
+	return
+}
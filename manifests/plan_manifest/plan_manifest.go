@@ -0,0 +1,123 @@
+package plan_manifest
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/gbdubs/ecology/util/output"
+	"io/ioutil"
+)
+
+const (
+	ChangeCreate = "create"
+	ChangeUpdate = "update"
+	ChangeDelete = "delete"
+	ChangeNone   = "none"
+)
+
+type Action struct {
+	Resource string
+	Name     string
+	Change   string
+	Reason   string
+	// For lambdas, the hash of the code that the action will deploy.
+	CodeHash string
+}
+
+// PlanManifest records what a push would change, so that it can be reviewed
+// and later applied exactly as planned.
+type PlanManifest struct {
+	Project string
+	Actions []Action
+}
+
+func GetPlanManifestFromFile(planPath string) (plan *PlanManifest, err error) {
+	data, err := ioutil.ReadFile(planPath)
+	if err == nil {
+		err = json.Unmarshal(data, &plan)
+	}
+	return
+}
+
+func (plan *PlanManifest) Save(planPath string, o *output.Output) (err error) {
+	o.Info("Writing Plan to %s", planPath).Indent()
+	contents, _ := json.MarshalIndent(plan, "", "  ")
+	err = ioutil.WriteFile(planPath, contents, 0644)
+	if err != nil {
+		o.Error(err)
+	}
+	o.Dedent().Done()
+	return
+}
+
+func (plan *PlanManifest) HasChanges() bool {
+	for _, a := range plan.Actions {
+		if a.Change != ChangeNone {
+			return true
+		}
+	}
+	return false
+}
+
+// Changes returns the action planned for the named resource, if any.
+func (plan *PlanManifest) Changes(resource string, name string) (Action, bool) {
+	for _, a := range plan.Actions {
+		if a.Resource == resource && a.Name == name {
+			return a, a.Change != ChangeNone
+		}
+	}
+	return Action{}, false
+}
+
+// Print shows the plan as a diff, in the style of `terraform plan`.
+func (plan *PlanManifest) Print(o *output.Output) {
+	o.Info("Plan for Project %s:", plan.Project).Indent()
+	counts := make(map[string]int)
+	for _, a := range plan.Actions {
+		counts[a.Change] = counts[a.Change] + 1
+		line := fmt.Sprintf("%s %s (%s)", a.Resource, a.Name, a.Reason)
+		switch a.Change {
+		case ChangeCreate:
+			o.Success("+ %s", line)
+		case ChangeUpdate:
+			o.Warning("~ %s", line)
+		case ChangeDelete:
+			o.Failure("- %s", line)
+		default:
+			o.Info("  %s", line)
+		}
+	}
+	o.Dedent()
+	o.Info("Plan: %d to create, %d to update, %d to delete, %d unchanged.",
+		counts[ChangeCreate], counts[ChangeUpdate], counts[ChangeDelete], counts[ChangeNone])
+}
+
+// CheckMatches returns an error describing how the current plan differs
+// from an earlier one, or nil if they agree on every action.
+func (plan *PlanManifest) CheckMatches(earlier *PlanManifest) error {
+	if plan.Project != earlier.Project {
+		return errors.New(fmt.Sprintf("Plan is for project %s, not %s", earlier.Project, plan.Project))
+	}
+	differences := ""
+	current := make(map[string]Action)
+	for _, a := range plan.Actions {
+		current[a.Resource+" "+a.Name] = a
+	}
+	for _, e := range earlier.Actions {
+		key := e.Resource + " " + e.Name
+		a, ok := current[key]
+		if !ok {
+			differences = differences + fmt.Sprintf("\n  %s is no longer in the project", key)
+		} else if a.Change != e.Change || a.CodeHash != e.CodeHash {
+			differences = differences + fmt.Sprintf("\n  %s was planned to %s but would now %s (%s)", key, e.Change, a.Change, a.Reason)
+		}
+		delete(current, key)
+	}
+	for key, a := range current {
+		differences = differences + fmt.Sprintf("\n  %s wasn't in the plan but would now %s", key, a.Change)
+	}
+	if differences != "" {
+		return errors.New("Plan is out of date, re-run with --plan:" + differences)
+	}
+	return nil
+}
@@ -1,20 +1,37 @@
 package project_manifest
 
 import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"github.com/gbdubs/ecology/dns_providers/dns_provider"
 	"github.com/gbdubs/ecology/manifests/api_manifest"
 	"github.com/gbdubs/ecology/manifests/lambda_manifest"
+	"github.com/gbdubs/ecology/manifests/plan_manifest"
+	"github.com/gbdubs/ecology/manifests/routing_manifest"
+	"github.com/gbdubs/ecology/platforms/platform"
+	"github.com/gbdubs/ecology/util/atomic_file"
+	"github.com/gbdubs/ecology/util/build_cache"
+	"github.com/gbdubs/ecology/util/manifest_history"
+	"github.com/gbdubs/ecology/util/manifest_lock"
 	"github.com/gbdubs/ecology/util/output"
+	"github.com/gbdubs/ecology/util/parallel"
+	"github.com/gbdubs/ecology/util/schema_migration"
+	"github.com/gbdubs/ecology/util/secret_store"
 	"io/ioutil"
 	"os"
+	"path/filepath"
 	"strings"
 )
 
 type ProjectConfigInfo struct {
 	Name         string
 	ManifestPath string
+	// Set while a rename_project is in progress, so that it can be resumed.
+	RenamingTo string
 }
 
 type ProjectDeployInfo struct {
@@ -23,30 +40,174 @@ type ProjectDeployInfo struct {
 }
 
 type ProjectManifest struct {
+	SchemaVersion   int
 	Config          ProjectConfigInfo
 	Deploy          ProjectDeployInfo
 	LambdaManifests []lambda_manifest.LambdaManifest
 	ApiManifest     api_manifest.ApiManifest
+	RoutingManifest routing_manifest.RoutingManifest
+
+	locker manifest_lock.Locker
+	// Held from GetProjectManifestForUpdate until Unlock.
+	lock manifest_lock.Lock
+	// The hash of the manifest file as it was read, to detect other writers.
+	loadedHash string
+	// Whether the manifest was upgraded from an older schema as it was read.
+	migrated bool
+	// Where lambdas are built, or nil to build them in their own folders.
+	buildCache *build_cache.Cache
+	// Made on first use, so that the secrets file is only read if needed.
+	secrets *secret_store.Resolver
+}
+
+// ProjectSummary is what commands report about a project in their results.
+type ProjectSummary struct {
+	Name        string
+	Platform    string
+	Region      string
+	Lambdas     []lambda_manifest.LambdaSummary
+	ApiEndpoint string
+	Domain      string
+}
+
+func (pm *ProjectManifest) Summary() ProjectSummary {
+	summary := ProjectSummary{
+		Name:        pm.Config.Name,
+		Platform:    pm.Deploy.Platform,
+		Region:      pm.Deploy.Region,
+		Lambdas:     make([]lambda_manifest.LambdaSummary, 0),
+		ApiEndpoint: pm.ApiManifest.Deploy.Endpoint,
+		Domain:      pm.RoutingManifest.Config.Domain,
+	}
+	for i, _ := range pm.LambdaManifests {
+		summary.Lambdas = append(summary.Lambdas, pm.LambdaManifests[i].Summary())
+	}
+	return summary
+}
+
+// Migrations upgrade project manifests written by older versions of ecology.
+var Migrations = schema_migration.Migrations{
+	schema_migration.Unversioned,
+}
+
+var SchemaVersion = Migrations.Current()
+
+// Upgrade migrates the project manifest and every manifest nested in it.
+func Upgrade(doc schema_migration.Document) error {
+	if err := Migrations.Upgrade("Project", doc); err != nil {
+		return err
+	}
+	for _, lambda := range schema_migration.Children(doc, "LambdaManifests") {
+		if err := lambda_manifest.Upgrade(lambda); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
 func GetProjectManifestFromFile(projectManifestPath string) (projectManifest *ProjectManifest, err error) {
 	data, err := ioutil.ReadFile(projectManifestPath)
 	if err == nil {
-		err = json.Unmarshal(data, &projectManifest)
+		projectManifest, err = parse(data)
+	}
+	if err == nil {
+		projectManifest.loadedHash = contentHash(data)
+	}
+	return
+}
+
+// parse decodes a project manifest, migrating it to the current schema.
+func parse(data []byte) (projectManifest *ProjectManifest, err error) {
+	doc := schema_migration.Document{}
+	if err = json.Unmarshal(data, &doc); err != nil {
+		return
+	}
+	original, _ := json.Marshal(doc)
+	if err = Upgrade(doc); err != nil {
+		return
+	}
+	upgraded, _ := json.Marshal(doc)
+	if err = json.Unmarshal(upgraded, &projectManifest); err != nil {
+		return
+	}
+	projectManifest.migrated = !bytes.Equal(original, upgraded)
+	return
+}
+
+// Migrated reports whether the manifest was upgraded from an older schema
+// when it was read, and so differs from the file until it is saved.
+func (pm *ProjectManifest) Migrated() bool {
+	return pm.migrated
+}
+
+// GetProjectManifestForUpdate takes the project's lock before reading its
+// manifest and holds it until Unlock, so that no other ecology process can
+// change the project between the read and the final Save.
+func GetProjectManifestForUpdate(projectManifestPath string, projectName string, locker manifest_lock.Locker) (projectManifest *ProjectManifest, err error) {
+	lock, err := locker.Lock(lockKey(projectName))
+	if err != nil {
+		return
+	}
+	projectManifest, err = GetProjectManifestFromFile(projectManifestPath)
+	if err != nil {
+		lock.Unlock()
+		return nil, err
 	}
+	projectManifest.locker = locker
+	projectManifest.lock = lock
 	return
 }
 
+// SetLocker sets the locker that Save uses when the manifest wasn't read with
+// GetProjectManifestForUpdate.
+func (pm *ProjectManifest) SetLocker(locker manifest_lock.Locker) {
+	pm.locker = locker
+}
+
+// SetBuildCache sets the cache that the project's lambdas are built into.
+func (pm *ProjectManifest) SetBuildCache(cache *build_cache.Cache) {
+	pm.buildCache = cache
+}
+
+// Unlock releases the lock taken by GetProjectManifestForUpdate, if any.
+func (pm *ProjectManifest) Unlock() {
+	if pm == nil || pm.lock == nil {
+		return
+	}
+	pm.lock.Unlock()
+	pm.lock = nil
+}
+
+func lockKey(projectName string) string {
+	return "project-" + projectName
+}
+
+func contentHash(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
 func (pm *ProjectManifest) GetLambdaManifest(lambdaName string) (*lambda_manifest.LambdaManifest, error) {
 	// TRICKSY POINTERSES! FILTHY TRICKSY POINTERSESSESSS!
 	for i, l := range pm.LambdaManifests {
 		if l.Config.Name == lambdaName {
+			pm.prepare(&pm.LambdaManifests[i])
 			return &pm.LambdaManifests[i], nil
 		}
 	}
 	return nil, errors.New(fmt.Sprintf("No Lambda named %s in Project %s", lambdaName, pm.Config.Name))
 }
 
+// prepare gives the lambda what it needs from the project to be built and
+// pushed.
+func (pm *ProjectManifest) prepare(lm *lambda_manifest.LambdaManifest) {
+	if pm.secrets == nil {
+		pm.secrets = secret_store.NewResolver(pm.SecretsPath())
+	}
+	lm.SetBuildCache(pm.buildCache)
+	lm.SetSecrets(pm.secrets)
+}
+
 func (pm *ProjectManifest) RemoveLambdaManifest(ptr *lambda_manifest.LambdaManifest) error {
 	indexToRemove := -1
 	for i, _ := range pm.LambdaManifests {
@@ -63,49 +224,236 @@ func (pm *ProjectManifest) RemoveLambdaManifest(ptr *lambda_manifest.LambdaManif
 
 func (pm *ProjectManifest) Save(o *output.Output) (err error) {
 	o.Info("Writing Project Manifest to %s", pm.Config.ManifestPath).Indent()
-	contents, _ := json.MarshalIndent(pm, "", "  ")
-	filePath := pm.Config.ManifestPath
-	if strings.Index(filePath, "/") > -1 {
-		parentDir := filePath[:strings.LastIndex(filePath, "/")]
-		err = os.MkdirAll(parentDir, 0777)
+	if pm.lock == nil && pm.locker != nil {
+		lock, err := pm.locker.Lock(lockKey(pm.Config.Name))
+		if err != nil {
+			o.Error(err)
+			return err
+		}
+		defer lock.Unlock()
 	}
+	contents, _ := json.MarshalIndent(pm, "", "  ")
+	err = pm.checkUnchangedOnDisk()
 	if err == nil {
-		err = ioutil.WriteFile(filePath, contents, 0777)
+		err = atomic_file.WriteFile(pm.Config.ManifestPath, contents, 0644)
 	}
 	if err != nil {
 		o.Error(err)
+		return
+	}
+	pm.loadedHash = contentHash(contents)
+	pm.migrated = false
+	if historyErr := manifest_history.Record(pm.HistoryDir(), contents); historyErr != nil {
+		o.Warning("Couldn't snapshot the manifest: %v", historyErr)
 	}
 	o.Dedent().Done()
 	return
 }
 
-func (pm *ProjectManifest) PushToPlatform(o *output.Output) (err error) {
+// HistoryDir is where snapshots of every saved version of the manifest go.
+func (pm *ProjectManifest) HistoryDir() string {
+	return filepath.Join(filepath.Dir(pm.Config.ManifestPath), ".ecology", "history")
+}
+
+// SecretsPath is where the project's encrypted secrets are kept.
+func (pm *ProjectManifest) SecretsPath() string {
+	return filepath.Join(filepath.Dir(pm.Config.ManifestPath), ".ecology", "secrets.json")
+}
+
+// Restore replaces the manifest with one of its snapshots, and saves it.
+func (pm *ProjectManifest) Restore(snapshotId string, o *output.Output) (err error) {
+	o.Info("Restoring Project %s to Snapshot %s", pm.Config.Name, snapshotId).Indent()
+	data, err := manifest_history.Read(pm.HistoryDir(), snapshotId)
+	if err != nil {
+		o.Error(err)
+		return
+	}
+	restored, err := parse(data)
+	if err != nil {
+		o.Error(err)
+		return
+	}
+	if restored.Config.Name != pm.Config.Name {
+		err = errors.New(fmt.Sprintf("Snapshot %s is of Project %s, not %s", snapshotId, restored.Config.Name, pm.Config.Name))
+		o.Error(err)
+		return
+	}
+	restored.Config.ManifestPath = pm.Config.ManifestPath
+	restored.locker = pm.locker
+	restored.buildCache = pm.buildCache
+	restored.secrets = pm.secrets
+	restored.lock = pm.lock
+	restored.loadedHash = pm.loadedHash
+	*pm = *restored
+	if err = pm.Save(o); err != nil {
+		return
+	}
+	o.Dedent().Done()
+	return
+}
+
+// checkUnchangedOnDisk refuses to overwrite changes that another process
+// saved since this manifest was read.
+func (pm *ProjectManifest) checkUnchangedOnDisk() error {
+	data, err := ioutil.ReadFile(pm.Config.ManifestPath)
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	if contentHash(data) != pm.loadedHash {
+		return errors.New(fmt.Sprintf("Project Manifest %s was changed by another process since it was read, re-run the command to pick up its changes", pm.Config.ManifestPath))
+	}
+	return nil
+}
+
+// PushToPlatform pushes every lambda, with up to parallelism of them being
+// built and uploaded at once, and then the project's API.
+func (pm *ProjectManifest) PushToPlatform(p platform.Platform, parallelism int, o *output.Output) (err error) {
 	o.Info("Pushing Project %s to Platform", pm.Config.Name).Indent()
-	err = pm.pushLambdas(o)
+	indexes := make([]int, len(pm.LambdaManifests))
+	for i, _ := range pm.LambdaManifests {
+		indexes[i] = i
+	}
+	err = pm.pushLambdas(indexes, p, parallelism, o)
+	if err != nil {
+		return
+	}
+	err = pm.pushApi(p, o)
+	if err != nil {
+		return
+	}
 	o.Dedent().Done()
 	return
 }
 
-func (pm *ProjectManifest) pushLambdas(o *output.Output) (err error) {
-	o.Info("Pushing Lambdas").Indent()
+// Plan works out what PushToPlatform would do, without changing anything.
+func (pm *ProjectManifest) Plan(p platform.Platform, o *output.Output) (plan *plan_manifest.PlanManifest, err error) {
+	o.Info("Planning Push of Project %s", pm.Config.Name).Indent()
+	plan = &plan_manifest.PlanManifest{
+		Project: pm.Config.Name,
+		Actions: make([]plan_manifest.Action, 0),
+	}
 	for i, _ := range pm.LambdaManifests {
-		lm := &pm.LambdaManifests[i]
-		err = lm.PushToPlatform(o)
+		pm.prepare(&pm.LambdaManifests[i])
+		actions, err := pm.LambdaManifests[i].Plan(p)
 		if err != nil {
 			o.Error(err)
-			return err
+			return nil, err
+		}
+		plan.Actions = append(plan.Actions, actions...)
+	}
+	functionArns := make(map[string]string)
+	for i, _ := range pm.LambdaManifests {
+		lm := &pm.LambdaManifests[i]
+		if arn := lm.PlannedInvokeArn(); arn != "" {
+			functionArns[lm.Config.Name] = arn
+		}
+	}
+	apiAction, err := pm.ApiManifest.Plan(p, pm.Config.Name, functionArns)
+	if err != nil {
+		o.Error(err)
+		return nil, err
+	}
+	plan.Actions = append(plan.Actions, apiAction)
+	o.Dedent().Done()
+	return
+}
+
+// ApplyPlan pushes exactly the changes in the plan, after checking that the
+// plan still matches what a push would do now.
+func (pm *ProjectManifest) ApplyPlan(plan *plan_manifest.PlanManifest, p platform.Platform, parallelism int, o *output.Output) (err error) {
+	o.Info("Applying Plan to Project %s", pm.Config.Name).Indent()
+	current, err := pm.Plan(p, o)
+	if err != nil {
+		return
+	}
+	if err = current.CheckMatches(plan); err != nil {
+		return
+	}
+	indexes := make([]int, 0)
+	for i, lm := range pm.LambdaManifests {
+		_, lambdaChanged := plan.Changes("lambda", lm.Config.FullyQualifiedName)
+		_, roleChanged := plan.Changes("role", lm.ExecutorRoleManifest.Config.Name)
+		if lambdaChanged || roleChanged {
+			indexes = append(indexes, i)
+		}
+	}
+	if err = pm.pushLambdas(indexes, p, parallelism, o); err != nil {
+		return
+	}
+	if _, changed := plan.Changes("api", pm.Config.Name); changed {
+		if err = pm.pushApi(p, o); err != nil {
+			return
 		}
 	}
 	o.Dedent().Done()
 	return
 }
 
-func (pm *ProjectManifest) DeleteFromPlatform(o *output.Output) (err error) {
+func (pm *ProjectManifest) pushApi(p platform.Platform, o *output.Output) (err error) {
+	functionArns := make(map[string]string)
+	for _, lm := range pm.LambdaManifests {
+		if lm.IsDeployed() {
+			functionArns[lm.Config.Name] = lm.InvokeArn()
+		}
+	}
+	return pm.ApiManifest.PushToPlatform(p, pm.Config.Name, functionArns, o)
+}
+
+// pushLambdas pushes the lambdas at the given indexes, with up to parallelism
+// pushes running at once. Every executor role is pushed before any function,
+// since functions can't be created without them. Every lambda is attempted,
+// and all of the failures are reported together.
+func (pm *ProjectManifest) pushLambdas(indexes []int, p platform.Platform, parallelism int, o *output.Output) (err error) {
+	o.Info("Pushing Lambdas").Indent()
+	taskOutput := func(name string) *output.Output {
+		if parallelism > 1 {
+			return o.Task(name)
+		}
+		return o
+	}
+	for _, i := range indexes {
+		pm.prepare(&pm.LambdaManifests[i])
+	}
+	roleErrs := parallel.Run(len(indexes), parallelism, func(i int) error {
+		lm := &pm.LambdaManifests[indexes[i]]
+		return lm.ExecutorRoleManifest.PushToPlatform(p, taskOutput(lm.Config.Name))
+	})
+	errs := parallel.Run(len(indexes), parallelism, func(i int) error {
+		if roleErrs[i] != nil {
+			return roleErrs[i]
+		}
+		lm := &pm.LambdaManifests[indexes[i]]
+		return lm.PushToPlatform(p, taskOutput(lm.Config.Name))
+	})
+	failures := make([]string, 0)
+	for i, e := range errs {
+		if e != nil {
+			failures = append(failures, fmt.Sprintf("%s: %v", pm.LambdaManifests[indexes[i]].Config.Name, e))
+		}
+	}
+	if len(failures) > 0 {
+		err = errors.New(fmt.Sprintf("%d of %d lambdas failed to push:\n%s", len(failures), len(indexes), strings.Join(failures, "\n")))
+		o.Error(err)
+		return
+	}
+	o.Dedent().Done()
+	return
+}
+
+func (pm *ProjectManifest) DeleteFromPlatform(p platform.Platform, o *output.Output) (err error) {
 	o.Info("Deleting Project %s", pm.Config.Name).Indent()
+	err = pm.ApiManifest.DeleteFromPlatform(p, o)
+	if err != nil {
+		o.Error(err)
+		pm.Save(o)
+		return
+	}
 	o.Info("Deleting Lambdas").Indent()
-	for i, _ := range pm.LambdaManifests {
-		lm := &pm.LambdaManifests[i]
-		err = lm.DeleteFromPlatform(o)
+	for len(pm.LambdaManifests) > 0 {
+		lm := &pm.LambdaManifests[0]
+		err = lm.DeleteFromPlatform(p, o)
 		if err != nil {
 			o.Error(err)
 			pm.Save(o) // Saves partial deletion progress in case we fail midway.
@@ -117,3 +465,187 @@ func (pm *ProjectManifest) DeleteFromPlatform(o *output.Output) (err error) {
 	o.Dedent().Done()
 	return
 }
+
+// PullFromPlatform reconciles the deploy info of every lambda and role in the
+// project with the platform, which is treated as the source of truth.
+func (pm *ProjectManifest) PullFromPlatform(p platform.Platform, o *output.Output) (changes []string, err error) {
+	o.Info("Pulling Project %s from Platform", pm.Config.Name).Indent()
+	for i, _ := range pm.LambdaManifests {
+		lm := &pm.LambdaManifests[i]
+		lambdaChanges, err := lm.PullFromPlatform(p, o)
+		if err != nil {
+			o.Error(err)
+			return changes, err
+		}
+		changes = append(changes, lambdaChanges...)
+	}
+	o.Dedent().Done()
+	return
+}
+
+// Rename moves every lambda and role in the project over to the new project
+// name, recreating deployed resources under their new names before deleting
+// the old ones. The manifest is saved after each lambda, so a failed rename
+// can be resumed by calling Rename again with the same name.
+func (pm *ProjectManifest) Rename(newName string, p platform.Platform, o *output.Output) (err error) {
+	o.Info("Renaming Project %s to %s", pm.Config.Name, newName).Indent()
+	if pm.Config.RenamingTo != "" && pm.Config.RenamingTo != newName {
+		return errors.New(fmt.Sprintf("Project %s is partway through being renamed to %s", pm.Config.Name, pm.Config.RenamingTo))
+	}
+	pm.Config.RenamingTo = newName
+	if err = pm.Save(o); err != nil {
+		return
+	}
+
+	for i, _ := range pm.LambdaManifests {
+		lm := &pm.LambdaManifests[i]
+		if strings.HasPrefix(lm.Config.FullyQualifiedName, newName+"-") {
+			o.Info("Lambda %s was already renamed.", lm.Config.Name)
+			continue
+		}
+		o.Info("Renaming Lambda %s", lm.Config.Name).Indent()
+		old := *lm
+		*lm = lm.Renamed(newName, lm.Config.Name)
+		if err = pm.replaceRenamed(&old, lm, p, o); err != nil {
+			return
+		}
+		if err = pm.Save(o); err != nil {
+			return
+		}
+		o.Dedent().Done()
+	}
+
+	pm.Config.Name = newName
+	pm.Config.RenamingTo = ""
+	err = pm.Save(o)
+	o.Dedent().Done()
+	return
+}
+
+// RenameLambda moves a lambda's files to its new name and deploys it under its
+// new fully qualified name before removing the old function and role, so that
+// it stays available throughout. Each step tolerates having already been done,
+// so a failed rename can be resumed by calling RenameLambda again.
+func (pm *ProjectManifest) RenameLambda(lambdaName string, newLambdaName string, p platform.Platform, o *output.Output) (err error) {
+	o.Info("Renaming Lambda %s to %s", lambdaName, newLambdaName).Indent()
+	lm, err := pm.GetLambdaManifest(lambdaName)
+	if err != nil {
+		return
+	}
+	renamed := lm.Renamed(pm.Config.Name, newLambdaName)
+	if err = lm.MoveFilesTo(&renamed, o); err != nil {
+		o.Error(err)
+		return
+	}
+	old := *lm
+	*lm = renamed
+	pm.ApiManifest.RenameLambda(lambdaName, newLambdaName)
+	if err = pm.replaceRenamed(&old, lm, p, o); err != nil {
+		return
+	}
+	err = pm.Save(o)
+	o.Dedent().Done()
+	return
+}
+
+// RollbackLambda points the lambda's alias at an earlier version, and makes
+// sure that the API's routes invoke the alias, so that they roll back with it.
+func (pm *ProjectManifest) RollbackLambda(lambdaName string, toVersion string, p platform.Platform, o *output.Output) (err error) {
+	o.Info("Rolling Back Lambda %s", lambdaName).Indent()
+	lm, err := pm.GetLambdaManifest(lambdaName)
+	if err != nil {
+		o.Error(err)
+		return
+	}
+	if err = lm.Rollback(p, toVersion, o); err != nil {
+		return
+	}
+	if pm.ApiManifest.IsDeployed() {
+		if err = pm.pushApi(p, o); err != nil {
+			return
+		}
+	}
+	err = pm.Save(o)
+	o.Dedent().Done()
+	return
+}
+
+// replaceRenamed replaces what was deployed for a lambda with its renamed
+// copy. A deployed lambda is redeployed under its new name. Otherwise only its
+// executor role can be on the platform, and it is deleted, since the renamed
+// lambda gets a role of its own.
+func (pm *ProjectManifest) replaceRenamed(old *lambda_manifest.LambdaManifest, renamed *lambda_manifest.LambdaManifest, p platform.Platform, o *output.Output) (err error) {
+	if old.IsDeployed() {
+		return pm.redeployRenamed(old, renamed, p, o)
+	}
+	if err = old.ExecutorRoleManifest.DeleteFromPlatform(p, o); err != nil {
+		o.Error(err)
+		return
+	}
+	return nil
+}
+
+// redeployRenamed pushes a renamed lambda, points the API's routes at it, and
+// only then deletes the old lambda, so that routes never lose their target.
+func (pm *ProjectManifest) redeployRenamed(old *lambda_manifest.LambdaManifest, renamed *lambda_manifest.LambdaManifest, p platform.Platform, o *output.Output) (err error) {
+	if err = renamed.PushToPlatform(p, o); err != nil {
+		o.Error(err)
+		return
+	}
+	if pm.ApiManifest.IsDeployed() {
+		if err = pm.pushApi(p, o); err != nil {
+			o.Error(err)
+			return
+		}
+	}
+	if err = old.DeleteFromPlatform(p, o); err != nil {
+		o.Error(err)
+		return
+	}
+	return nil
+}
+
+// Drift compares the live configuration of every lambda and role in the
+// project with what was last deployed, and describes every out-of-band change.
+func (pm *ProjectManifest) Drift(p platform.Platform, o *output.Output) (drift []string, err error) {
+	o.Info("Checking Project %s for Drift", pm.Config.Name).Indent()
+	for i, _ := range pm.LambdaManifests {
+		lambdaDrift, err := pm.LambdaManifests[i].Drift(p)
+		if err != nil {
+			o.Error(err)
+			return drift, err
+		}
+		drift = append(drift, lambdaDrift...)
+	}
+	o.Dedent().Done()
+	return
+}
+
+// UpdateDomainRecords reads the API endpoint back from the platform and points
+// the project's custom domain at it through the DNS provider.
+func (pm *ProjectManifest) UpdateDomainRecords(p platform.Platform, dp dns_provider.DnsProvider, o *output.Output) (err error) {
+	o.Info("Updating Domain Records for Project %s", pm.Config.Name).Indent()
+	if !pm.RoutingManifest.IsInitialized() {
+		return errors.New(fmt.Sprintf("Routing hasn't been initialized for project %s", pm.Config.Name))
+	}
+	if !pm.ApiManifest.IsDeployed() {
+		o.Warning("Project %s has no deployed API to route to yet.", pm.Config.Name).Dedent()
+		return nil
+	}
+	ap, ok := p.(platform.ApiPlatform)
+	if !ok {
+		return errors.New(fmt.Sprintf("Platform %s doesn't support API routes", p.Name()))
+	}
+	api, err := ap.GetApi(pm.ApiManifest.Deploy.ApiId)
+	if err != nil {
+		o.Error(err)
+		return
+	}
+	pm.ApiManifest.Deploy.Endpoint = api.Endpoint
+	err = pm.RoutingManifest.PushToProvider(dp, api.Endpoint, o)
+	if err != nil {
+		return
+	}
+	o.Dedent().Done()
+	return
+}
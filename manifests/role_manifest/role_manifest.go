@@ -1,10 +1,13 @@
 package role_manifest
 
 import (
-	"github.com/aws/aws-sdk-go/aws"
-	"github.com/aws/aws-sdk-go/aws/session"
-	"github.com/aws/aws-sdk-go/service/iam"
+	"encoding/json"
+	"fmt"
+	"github.com/gbdubs/ecology/manifests/plan_manifest"
+	"github.com/gbdubs/ecology/platforms/platform"
 	"github.com/gbdubs/ecology/util/output"
+	"github.com/gbdubs/ecology/util/schema_migration"
+	"reflect"
 )
 
 type RoleConfigInfo struct {
@@ -12,18 +15,32 @@ type RoleConfigInfo struct {
 }
 
 type RoleDeployInfo struct {
-	RoleId           string
-	Arn              string
-	ExistsOnPlatform bool
+	RoleId                   string
+	Arn                      string
+	ExistsOnPlatform         bool
+	AssumeRolePolicyDocument string
 }
 
 type RoleManifest struct {
-	Config RoleConfigInfo
-	Deploy RoleDeployInfo
+	SchemaVersion int
+	Config        RoleConfigInfo
+	Deploy        RoleDeployInfo
+}
+
+// Migrations upgrade role manifests written by older versions of ecology.
+var Migrations = schema_migration.Migrations{
+	schema_migration.Unversioned,
+}
+
+var SchemaVersion = Migrations.Current()
+
+func Upgrade(doc schema_migration.Document) error {
+	return Migrations.Upgrade("Role", doc)
 }
 
 func New(roleName string) RoleManifest {
 	rm := RoleManifest{
+		SchemaVersion: SchemaVersion,
 		Config: RoleConfigInfo{
 			Name: roleName,
 		},
@@ -36,60 +53,78 @@ func New(roleName string) RoleManifest {
 	return rm
 }
 
-func (rm *RoleManifest) PushToPlatform(o *output.Output) (err error) {
+func (rm *RoleManifest) PushToPlatform(p platform.Platform, o *output.Output) (err error) {
 	o.Info("Pushing Role %s To Platform", rm.Config.Name).Indent()
 	if rm.Deploy.ExistsOnPlatform {
 		o.Info("No Push Needed.").Dedent().Done()
 		return nil
 	}
-	svc := iam.New(session.New())
 	o.Info("Checking to see if Role %s already exists...", rm.Config.Name).Indent()
-	getRoleRequest := &iam.GetRoleInput{
-		RoleName: aws.String(rm.Config.Name),
-	}
-	role, err := svc.GetRole(getRoleRequest)
+	role, err := p.GetRole(rm.Config.Name)
 	if err == nil {
 		o.Info("Role already exists.").Dedent().Done().Dedent().Done()
 		rm.Deploy.ExistsOnPlatform = true
-		rm.Deploy.Arn = *role.Role.Arn
-		rm.Deploy.RoleId = *role.Role.RoleId
+		rm.Deploy.Arn = role.Arn
+		rm.Deploy.RoleId = role.RoleId
+		rm.Deploy.AssumeRolePolicyDocument = role.AssumeRolePolicyDocument
+		return
+	} else if !platform.IsNotFound(err) {
+		o.Error(err)
 		return
 	} else {
 		o.Warning("Role does not exist.").Dedent()
 	}
 
 	o.Info("Creating Role %s on Platform", rm.Config.Name).Indent()
-	createRoleRequest := &iam.CreateRoleInput{
-		AssumeRolePolicyDocument: aws.String(allowAmazonToRunLambdaPolicy),
-		Path:                     aws.String("/"),
-		RoleName:                 aws.String(rm.Config.Name),
-	}
-	result, err := svc.CreateRole(createRoleRequest)
+	role, err = p.CreateRole(rm.Config.Name, allowAmazonToRunLambdaPolicy)
 	if err != nil {
 		o.Error(err)
 		return
 	}
-	rm.Deploy.Arn = *result.Role.Arn
-	rm.Deploy.RoleId = *result.Role.RoleId
+	rm.Deploy.Arn = role.Arn
+	rm.Deploy.RoleId = role.RoleId
 	rm.Deploy.ExistsOnPlatform = true
+	rm.Deploy.AssumeRolePolicyDocument = allowAmazonToRunLambdaPolicy
 	o.Info("Role ARN = %s", rm.Deploy.Arn)
 	o.Info("Role Id = %s", rm.Deploy.RoleId)
 	o.Dedent().Done().Dedent().Done()
 	return
 }
 
-func (rm *RoleManifest) DeleteFromPlatform(o *output.Output) (err error) {
+// Plan works out what PushToPlatform would do, without changing anything.
+func (rm *RoleManifest) Plan(p platform.Platform) (action plan_manifest.Action, err error) {
+	action = plan_manifest.Action{
+		Resource: "role",
+		Name:     rm.Config.Name,
+		Change:   plan_manifest.ChangeNone,
+		Reason:   "already deployed",
+	}
+	if rm.Deploy.ExistsOnPlatform {
+		return
+	}
+	_, err = p.GetRole(rm.Config.Name)
+	if err == nil {
+		action.Reason = "already exists on the platform"
+	} else if platform.IsNotFound(err) {
+		err = nil
+		action.Change = plan_manifest.ChangeCreate
+		action.Reason = "does not exist"
+	}
+	return
+}
+
+func (rm *RoleManifest) DeleteFromPlatform(p platform.Platform, o *output.Output) (err error) {
 	o.Info("Removing Role %s From Platform", rm.Config.Name).Indent()
 	if !rm.Deploy.ExistsOnPlatform {
 		o.Info("No Removal Needed.").Dedent().Done()
 		return nil
 	}
-	svc := iam.New(session.New())
 	o.Info("Deleting Role %s from Platform", rm.Config.Name)
-	deleteRoleRequest := &iam.DeleteRoleInput{
-		RoleName: aws.String(rm.Config.Name),
+	err = p.DeleteRole(rm.Config.Name)
+	if platform.IsNotFound(err) {
+		o.Warning("Role %s was already gone from the platform.", rm.Config.Name)
+		err = nil
 	}
-	_, err = svc.DeleteRole(deleteRoleRequest)
 	if err != nil {
 		o.Error(err)
 		return
@@ -97,11 +132,80 @@ func (rm *RoleManifest) DeleteFromPlatform(o *output.Output) (err error) {
 	rm.Deploy.Arn = ""
 	rm.Deploy.RoleId = ""
 	rm.Deploy.ExistsOnPlatform = false
+	rm.Deploy.AssumeRolePolicyDocument = ""
 	o.Success("Deleted Successfully.")
 	o.Dedent().Done()
 	return
 }
 
+// PullFromPlatform updates the deploy info of the role to match what is
+// actually on the platform, and describes each change.
+func (rm *RoleManifest) PullFromPlatform(p platform.Platform, o *output.Output) (changes []string, err error) {
+	o.Info("Pulling Role %s From Platform", rm.Config.Name).Indent()
+	role, err := p.GetRole(rm.Config.Name)
+	if platform.IsNotFound(err) {
+		err = nil
+		if rm.Deploy.ExistsOnPlatform {
+			changes = append(changes, fmt.Sprintf("Role %s no longer exists on the platform, marking it undeployed", rm.Config.Name))
+		}
+		rm.Deploy.ExistsOnPlatform = false
+		rm.Deploy.Arn = ""
+		rm.Deploy.RoleId = ""
+		rm.Deploy.AssumeRolePolicyDocument = ""
+		o.Dedent().Done()
+		return
+	} else if err != nil {
+		o.Error(err)
+		return
+	}
+	if !rm.Deploy.ExistsOnPlatform {
+		changes = append(changes, fmt.Sprintf("Role %s exists on the platform, marking it deployed", rm.Config.Name))
+	} else if rm.Deploy.Arn != role.Arn || rm.Deploy.RoleId != role.RoleId {
+		changes = append(changes, fmt.Sprintf("Role %s was recreated on the platform with Arn %q", rm.Config.Name, role.Arn))
+	}
+	rm.Deploy.ExistsOnPlatform = true
+	rm.Deploy.Arn = role.Arn
+	rm.Deploy.RoleId = role.RoleId
+	rm.Deploy.AssumeRolePolicyDocument = role.AssumeRolePolicyDocument
+	o.Dedent().Done()
+	return
+}
+
+// Drift compares the live role with what was last deployed, and describes
+// every out-of-band change.
+func (rm *RoleManifest) Drift(p platform.Platform) (drift []string, err error) {
+	role, err := p.GetRole(rm.Config.Name)
+	if platform.IsNotFound(err) {
+		err = nil
+		if rm.Deploy.ExistsOnPlatform {
+			drift = append(drift, fmt.Sprintf("Role %s was deleted from the platform", rm.Config.Name))
+		}
+		return
+	} else if err != nil {
+		return
+	}
+	if !rm.Deploy.ExistsOnPlatform {
+		drift = append(drift, fmt.Sprintf("Role %s exists on the platform but was never deployed", rm.Config.Name))
+		return
+	}
+	if role.Arn != rm.Deploy.Arn || role.RoleId != rm.Deploy.RoleId {
+		drift = append(drift, fmt.Sprintf("Role %s was recreated on the platform with Arn %q", rm.Config.Name, role.Arn))
+	}
+	if rm.Deploy.AssumeRolePolicyDocument != "" && !samePolicy(rm.Deploy.AssumeRolePolicyDocument, role.AssumeRolePolicyDocument) {
+		drift = append(drift, fmt.Sprintf("Role %s trust policy was changed to %s", rm.Config.Name, role.AssumeRolePolicyDocument))
+	}
+	return
+}
+
+// samePolicy compares policy documents as JSON, ignoring formatting.
+func samePolicy(a string, b string) bool {
+	var aJson, bJson interface{}
+	if json.Unmarshal([]byte(a), &aJson) != nil || json.Unmarshal([]byte(b), &bJson) != nil {
+		return a == b
+	}
+	return reflect.DeepEqual(aJson, bJson)
+}
+
 const allowAmazonToRunLambdaPolicy = `{
   "Version": "2012-10-17",
   "Statement": [
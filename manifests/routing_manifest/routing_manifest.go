@@ -0,0 +1,101 @@
+package routing_manifest
+
+import (
+	"errors"
+	"fmt"
+	"github.com/gbdubs/ecology/dns_providers/dns_provider"
+	"github.com/gbdubs/ecology/util/output"
+	"net/url"
+	"strings"
+)
+
+const defaultTtl = 300
+
+type RoutingConfigInfo struct {
+	Domain    string
+	Zone      string
+	Registrar string
+	// The DNS server to send updates to, for the rfc2136 registrar.
+	Server string
+	// The zone file to write records into, for the zone_file registrar.
+	ZoneFilePath string
+	Ttl          int64
+}
+
+type RoutingDeployInfo struct {
+	Records []dns_provider.Record
+}
+
+type RoutingManifest struct {
+	Config RoutingConfigInfo
+	Deploy RoutingDeployInfo
+}
+
+// New maps a custom domain onto a project. If no zone is given, the domain's
+// parent is assumed to be the zone that the registrar manages.
+func New(domain string, zone string, registrar string, server string, zoneFilePath string) RoutingManifest {
+	if zone == "" && strings.Index(domain, ".") > -1 {
+		zone = domain[strings.Index(domain, ".")+1:]
+	}
+	return RoutingManifest{
+		Config: RoutingConfigInfo{
+			Domain:       domain,
+			Zone:         zone,
+			Registrar:    registrar,
+			Server:       server,
+			ZoneFilePath: zoneFilePath,
+			Ttl:          defaultTtl,
+		},
+		Deploy: RoutingDeployInfo{
+			Records: make([]dns_provider.Record, 0),
+		},
+	}
+}
+
+func (rm *RoutingManifest) IsInitialized() bool {
+	return rm.Config.Domain != ""
+}
+
+// PushToProvider points the domain at the given API endpoint, and removes any
+// records that were previously pushed but are no longer wanted.
+func (rm *RoutingManifest) PushToProvider(dp dns_provider.DnsProvider, endpoint string, o *output.Output) (err error) {
+	o.Info("RoutingManifest - PushToProvider - %s", rm.Config.Domain).Indent()
+	if rm.Config.Domain == rm.Config.Zone {
+		return errors.New(fmt.Sprintf("Can't point the zone apex %s at an API, use a subdomain", rm.Config.Domain))
+	}
+	endpointUrl, err := url.Parse(endpoint)
+	if err != nil || endpointUrl.Hostname() == "" {
+		return errors.New(fmt.Sprintf("Couldn't find a host in the API endpoint %q", endpoint))
+	}
+	wanted := []dns_provider.Record{
+		{
+			Name:  rm.Config.Domain,
+			Type:  "CNAME",
+			Ttl:   rm.Config.Ttl,
+			Value: endpointUrl.Hostname(),
+		},
+	}
+	for _, r := range wanted {
+		o.Info("Upserting %s %s -> %s", r.Type, r.Name, r.Value)
+		if err = dp.UpsertRecord(rm.Config.Zone, r); err != nil {
+			o.Error(err)
+			return
+		}
+	}
+	for _, old := range rm.Deploy.Records {
+		stillWanted := false
+		for _, r := range wanted {
+			stillWanted = stillWanted || old.SameRecordSet(r)
+		}
+		if !stillWanted {
+			o.Info("Deleting %s %s -> %s", old.Type, old.Name, old.Value)
+			if err = dp.DeleteRecord(rm.Config.Zone, old); err != nil {
+				o.Error(err)
+				return
+			}
+		}
+	}
+	rm.Deploy.Records = wanted
+	o.Dedent().Done()
+	return nil
+}
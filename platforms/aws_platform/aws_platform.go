@@ -0,0 +1,522 @@
+package aws_platform
+
+import (
+	"fmt"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/arn"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/apigatewayv2"
+	"github.com/aws/aws-sdk-go/service/apigatewayv2/apigatewayv2iface"
+	"github.com/aws/aws-sdk-go/service/iam"
+	"github.com/aws/aws-sdk-go/service/iam/iamiface"
+	"github.com/aws/aws-sdk-go/service/lambda"
+	"github.com/aws/aws-sdk-go/service/lambda/lambdaiface"
+	"github.com/gbdubs/ecology/platforms/platform"
+	"net/url"
+	"strings"
+)
+
+type AwsPlatform struct {
+	Region    string
+	lambdaSvc lambdaiface.LambdaAPI
+	iamSvc    iamiface.IAMAPI
+	apiSvc    apigatewayv2iface.ApiGatewayV2API
+}
+
+func New(region string) *AwsPlatform {
+	sess := session.New()
+	return &AwsPlatform{
+		Region:    region,
+		lambdaSvc: lambda.New(sess, aws.NewConfig().WithRegion(region)),
+		iamSvc:    iam.New(sess),
+		apiSvc:    apigatewayv2.New(sess, aws.NewConfig().WithRegion(region)),
+	}
+}
+
+func (ap *AwsPlatform) Name() string {
+	return "AWS"
+}
+
+// DefaultFunctionSettings are Lambda's defaults.
+func (ap *AwsPlatform) DefaultFunctionSettings() platform.FunctionSettings {
+	return platform.FunctionSettings{
+		MemorySize:       128,
+		Timeout:          3,
+		EphemeralStorage: 512,
+	}
+}
+
+func (ap *AwsPlatform) GetFunction(name string) (*platform.Function, error) {
+	result, err := ap.lambdaSvc.GetFunction(&lambda.GetFunctionInput{
+		FunctionName: aws.String(name),
+	})
+	if err != nil {
+		return nil, err
+	}
+	f := toFunction(result.Configuration)
+	if result.Concurrency != nil {
+		f.ReservedConcurrency = result.Concurrency.ReservedConcurrentExecutions
+	}
+	return f, nil
+}
+
+func (ap *AwsPlatform) CreateFunction(input *platform.CreateFunctionInput) (*platform.Function, error) {
+	result, err := ap.lambdaSvc.CreateFunction(&lambda.CreateFunctionInput{
+		Code: &lambda.FunctionCode{
+			ZipFile: input.ZipFile,
+		},
+		Description:      aws.String(input.Description),
+		FunctionName:     aws.String(input.Name),
+		Handler:          aws.String(input.Handler),
+		Publish:          aws.Bool(true),
+		Role:             aws.String(input.Role),
+		Runtime:          aws.String(input.Runtime),
+		Architectures:    architectures(input.Architecture),
+		MemorySize:       positive(input.MemorySize),
+		Timeout:          positive(input.Timeout),
+		Environment:      environment(input.Environment),
+		EphemeralStorage: ephemeralStorage(input.EphemeralStorage),
+	})
+	if err != nil {
+		return nil, err
+	}
+	f := toFunction(result)
+	if input.ReservedConcurrency != nil {
+		if err = ap.putConcurrency(input.Name, input.ReservedConcurrency); err != nil {
+			return nil, err
+		}
+		f.ReservedConcurrency = input.ReservedConcurrency
+	}
+	return f, nil
+}
+
+func (ap *AwsPlatform) UpdateFunctionCode(name string, zipFile []byte, architecture string) (*platform.Function, error) {
+	result, err := ap.lambdaSvc.UpdateFunctionCode(&lambda.UpdateFunctionCodeInput{
+		ZipFile:       zipFile,
+		FunctionName:  aws.String(name),
+		Publish:       aws.Bool(true),
+		Architectures: architectures(architecture),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return toFunction(result), nil
+}
+
+// UpdateFunctionConfiguration waits for the update to finish, since Lambda
+// refuses to update the code of a function whose configuration is updating.
+// Lambda leaves settings that it isn't sent alone, so every setting is sent,
+// with the defaults for those that aren't given.
+func (ap *AwsPlatform) UpdateFunctionConfiguration(input *platform.UpdateFunctionConfigurationInput) (*platform.Function, error) {
+	settings := platform.WithDefaults(input.FunctionSettings, ap.DefaultFunctionSettings())
+	_, err := ap.lambdaSvc.UpdateFunctionConfiguration(&lambda.UpdateFunctionConfigurationInput{
+		FunctionName:     aws.String(input.Name),
+		Handler:          aws.String(input.Handler),
+		Runtime:          aws.String(input.Runtime),
+		Description:      aws.String(settings.Description),
+		MemorySize:       aws.Int64(settings.MemorySize),
+		Timeout:          aws.Int64(settings.Timeout),
+		Environment:      &lambda.Environment{Variables: aws.StringMap(settings.Environment)},
+		EphemeralStorage: &lambda.EphemeralStorage{Size: aws.Int64(settings.EphemeralStorage)},
+	})
+	if err != nil {
+		return nil, err
+	}
+	if settings.ReservedConcurrency != nil {
+		err = ap.putConcurrency(input.Name, settings.ReservedConcurrency)
+	} else {
+		// Succeeds whether or not the function had a reservation.
+		_, err = ap.lambdaSvc.DeleteFunctionConcurrency(&lambda.DeleteFunctionConcurrencyInput{
+			FunctionName: aws.String(input.Name),
+		})
+	}
+	if err != nil {
+		return nil, err
+	}
+	err = ap.lambdaSvc.WaitUntilFunctionUpdatedV2(&lambda.GetFunctionInput{
+		FunctionName: aws.String(input.Name),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return ap.GetFunction(input.Name)
+}
+
+func (ap *AwsPlatform) putConcurrency(name string, reserved *int64) error {
+	_, err := ap.lambdaSvc.PutFunctionConcurrency(&lambda.PutFunctionConcurrencyInput{
+		FunctionName:                 aws.String(name),
+		ReservedConcurrentExecutions: reserved,
+	})
+	return err
+}
+
+// positive leaves settings that aren't set to Lambda.
+func positive(n int64) *int64 {
+	if n <= 0 {
+		return nil
+	}
+	return aws.Int64(n)
+}
+
+func environment(variables map[string]string) *lambda.Environment {
+	if variables == nil {
+		return nil
+	}
+	return &lambda.Environment{Variables: aws.StringMap(variables)}
+}
+
+func ephemeralStorage(size int64) *lambda.EphemeralStorage {
+	if size <= 0 {
+		return nil
+	}
+	return &lambda.EphemeralStorage{Size: aws.Int64(size)}
+}
+
+// architectures leaves the architecture to Lambda's default if it isn't set.
+func architectures(architecture string) []*string {
+	if architecture == "" {
+		return nil
+	}
+	return []*string{aws.String(architecture)}
+}
+
+func (ap *AwsPlatform) PublishVersion(name string) (*platform.Function, error) {
+	// A version can't be published while an update is still in progress.
+	err := ap.lambdaSvc.WaitUntilFunctionUpdatedV2(&lambda.GetFunctionInput{
+		FunctionName: aws.String(name),
+	})
+	if err != nil {
+		return nil, err
+	}
+	result, err := ap.lambdaSvc.PublishVersion(&lambda.PublishVersionInput{
+		FunctionName: aws.String(name),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return toFunction(result), nil
+}
+
+func (ap *AwsPlatform) GetAlias(functionName string, alias string) (*platform.Alias, error) {
+	result, err := ap.lambdaSvc.GetAlias(&lambda.GetAliasInput{
+		FunctionName: aws.String(functionName),
+		Name:         aws.String(alias),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return toAlias(result), nil
+}
+
+func (ap *AwsPlatform) PutAlias(functionName string, alias string, version string) (*platform.Alias, error) {
+	result, err := ap.lambdaSvc.UpdateAlias(&lambda.UpdateAliasInput{
+		FunctionName:    aws.String(functionName),
+		Name:            aws.String(alias),
+		FunctionVersion: aws.String(version),
+	})
+	if platform.IsNotFound(err) {
+		result, err = ap.lambdaSvc.CreateAlias(&lambda.CreateAliasInput{
+			FunctionName:    aws.String(functionName),
+			Name:            aws.String(alias),
+			FunctionVersion: aws.String(version),
+		})
+	}
+	if err != nil {
+		return nil, err
+	}
+	return toAlias(result), nil
+}
+
+func (ap *AwsPlatform) DeleteFunction(name string) error {
+	_, err := ap.lambdaSvc.DeleteFunction(&lambda.DeleteFunctionInput{
+		FunctionName: aws.String(name),
+	})
+	return err
+}
+
+func (ap *AwsPlatform) GetRole(name string) (*platform.Role, error) {
+	result, err := ap.iamSvc.GetRole(&iam.GetRoleInput{
+		RoleName: aws.String(name),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return toRole(result.Role), nil
+}
+
+func (ap *AwsPlatform) CreateRole(name string, assumeRolePolicyDocument string) (*platform.Role, error) {
+	result, err := ap.iamSvc.CreateRole(&iam.CreateRoleInput{
+		AssumeRolePolicyDocument: aws.String(assumeRolePolicyDocument),
+		Path:                     aws.String("/"),
+		RoleName:                 aws.String(name),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return toRole(result.Role), nil
+}
+
+func (ap *AwsPlatform) DeleteRole(name string) error {
+	_, err := ap.iamSvc.DeleteRole(&iam.DeleteRoleInput{
+		RoleName: aws.String(name),
+	})
+	return err
+}
+
+func (ap *AwsPlatform) GetApi(apiId string) (*platform.Api, error) {
+	result, err := ap.apiSvc.GetApi(&apigatewayv2.GetApiInput{
+		ApiId: aws.String(apiId),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &platform.Api{
+		Id:       aws.StringValue(result.ApiId),
+		Name:     aws.StringValue(result.Name),
+		Endpoint: aws.StringValue(result.ApiEndpoint),
+	}, nil
+}
+
+// CreateApi creates an HTTP API with a default stage that deploys every change.
+func (ap *AwsPlatform) CreateApi(name string) (*platform.Api, error) {
+	result, err := ap.apiSvc.CreateApi(&apigatewayv2.CreateApiInput{
+		Name:         aws.String(name),
+		ProtocolType: aws.String(apigatewayv2.ProtocolTypeHttp),
+	})
+	if err != nil {
+		return nil, err
+	}
+	_, err = ap.apiSvc.CreateStage(&apigatewayv2.CreateStageInput{
+		ApiId:      result.ApiId,
+		StageName:  aws.String("$default"),
+		AutoDeploy: aws.Bool(true),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &platform.Api{
+		Id:       aws.StringValue(result.ApiId),
+		Name:     aws.StringValue(result.Name),
+		Endpoint: aws.StringValue(result.ApiEndpoint),
+	}, nil
+}
+
+func (ap *AwsPlatform) DeleteApi(apiId string) error {
+	_, err := ap.apiSvc.DeleteApi(&apigatewayv2.DeleteApiInput{
+		ApiId: aws.String(apiId),
+	})
+	return err
+}
+
+func (ap *AwsPlatform) GetRoutes(apiId string) ([]*platform.ApiRoute, error) {
+	integrations, err := ap.apiSvc.GetIntegrations(&apigatewayv2.GetIntegrationsInput{
+		ApiId: aws.String(apiId),
+	})
+	if err != nil {
+		return nil, err
+	}
+	integrationUris := make(map[string]string)
+	for _, i := range integrations.Items {
+		integrationUris[integrationTarget+aws.StringValue(i.IntegrationId)] = aws.StringValue(i.IntegrationUri)
+	}
+	routes, err := ap.apiSvc.GetRoutes(&apigatewayv2.GetRoutesInput{
+		ApiId: aws.String(apiId),
+	})
+	if err != nil {
+		return nil, err
+	}
+	result := make([]*platform.ApiRoute, 0)
+	for _, r := range routes.Items {
+		result = append(result, &platform.ApiRoute{
+			RouteId:           aws.StringValue(r.RouteId),
+			RouteKey:          aws.StringValue(r.RouteKey),
+			FunctionArn:       integrationUris[aws.StringValue(r.Target)],
+			AuthorizationType: aws.StringValue(r.AuthorizationType),
+		})
+	}
+	return result, nil
+}
+
+// PutRoute gives each route an integration of its own, which is updated along
+// with the route, and deleted with it.
+func (ap *AwsPlatform) PutRoute(apiId string, route *platform.ApiRoute) (*platform.ApiRoute, error) {
+	result := *route
+	if route.RouteId == "" {
+		integrationId, err := ap.createIntegration(apiId, route.FunctionArn)
+		if err != nil {
+			return nil, err
+		}
+		created, err := ap.apiSvc.CreateRoute(&apigatewayv2.CreateRouteInput{
+			ApiId:             aws.String(apiId),
+			RouteKey:          aws.String(route.RouteKey),
+			Target:            aws.String(integrationTarget + integrationId),
+			AuthorizationType: aws.String(route.AuthorizationType),
+		})
+		if err != nil {
+			ap.deleteIntegration(apiId, integrationId)
+			return nil, err
+		}
+		result.RouteId = aws.StringValue(created.RouteId)
+		return &result, nil
+	}
+	existing, err := ap.apiSvc.GetRoute(&apigatewayv2.GetRouteInput{
+		ApiId:   aws.String(apiId),
+		RouteId: aws.String(route.RouteId),
+	})
+	if err != nil {
+		return nil, err
+	}
+	integrationId, ok := integrationOf(existing.Target)
+	if ok {
+		_, err = ap.apiSvc.UpdateIntegration(&apigatewayv2.UpdateIntegrationInput{
+			ApiId:          aws.String(apiId),
+			IntegrationId:  aws.String(integrationId),
+			IntegrationUri: aws.String(route.FunctionArn),
+		})
+	} else {
+		// Routes made outside of ecology might not target an integration.
+		integrationId, err = ap.createIntegration(apiId, route.FunctionArn)
+	}
+	if err != nil {
+		return nil, err
+	}
+	_, err = ap.apiSvc.UpdateRoute(&apigatewayv2.UpdateRouteInput{
+		ApiId:             aws.String(apiId),
+		RouteId:           aws.String(route.RouteId),
+		Target:            aws.String(integrationTarget + integrationId),
+		AuthorizationType: aws.String(route.AuthorizationType),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+func (ap *AwsPlatform) DeleteRoute(apiId string, routeId string) error {
+	existing, err := ap.apiSvc.GetRoute(&apigatewayv2.GetRouteInput{
+		ApiId:   aws.String(apiId),
+		RouteId: aws.String(routeId),
+	})
+	if err != nil {
+		return err
+	}
+	_, err = ap.apiSvc.DeleteRoute(&apigatewayv2.DeleteRouteInput{
+		ApiId:   aws.String(apiId),
+		RouteId: aws.String(routeId),
+	})
+	if err != nil {
+		return err
+	}
+	if integrationId, ok := integrationOf(existing.Target); ok {
+		return ap.deleteIntegration(apiId, integrationId)
+	}
+	return nil
+}
+
+// Routes target integrations as "integrations/<IntegrationId>".
+const integrationTarget = "integrations/"
+
+func integrationOf(target *string) (integrationId string, ok bool) {
+	if !strings.HasPrefix(aws.StringValue(target), integrationTarget) {
+		return "", false
+	}
+	return strings.TrimPrefix(aws.StringValue(target), integrationTarget), true
+}
+
+func (ap *AwsPlatform) createIntegration(apiId string, functionArn string) (integrationId string, err error) {
+	integration, err := ap.apiSvc.CreateIntegration(&apigatewayv2.CreateIntegrationInput{
+		ApiId:                aws.String(apiId),
+		IntegrationType:      aws.String(apigatewayv2.IntegrationTypeAwsProxy),
+		IntegrationUri:       aws.String(functionArn),
+		PayloadFormatVersion: aws.String("2.0"),
+	})
+	if err != nil {
+		return "", err
+	}
+	return aws.StringValue(integration.IntegrationId), nil
+}
+
+// deleteIntegration succeeds if the integration is already gone.
+func (ap *AwsPlatform) deleteIntegration(apiId string, integrationId string) error {
+	_, err := ap.apiSvc.DeleteIntegration(&apigatewayv2.DeleteIntegrationInput{
+		ApiId:         aws.String(apiId),
+		IntegrationId: aws.String(integrationId),
+	})
+	if platform.IsNotFound(err) {
+		return nil
+	}
+	return err
+}
+
+func (ap *AwsPlatform) AddInvokePermission(functionArn string, apiId string) error {
+	parsed, err := arn.Parse(functionArn)
+	if err != nil {
+		return err
+	}
+	_, err = ap.lambdaSvc.AddPermission(&lambda.AddPermissionInput{
+		FunctionName: aws.String(functionArn),
+		StatementId:  aws.String("ecology-api-" + apiId),
+		Action:       aws.String("lambda:InvokeFunction"),
+		Principal:    aws.String("apigateway.amazonaws.com"),
+		SourceArn:    aws.String(fmt.Sprintf("arn:aws:execute-api:%s:%s:%s/*", parsed.Region, parsed.AccountID, apiId)),
+	})
+	if aerr, ok := err.(awserr.Error); ok && aerr.Code() == lambda.ErrCodeResourceConflictException {
+		return nil
+	}
+	return err
+}
+
+func toFunction(c *lambda.FunctionConfiguration) *platform.Function {
+	architecture := platform.ArchitectureX86_64
+	if len(c.Architectures) > 0 {
+		architecture = aws.StringValue(c.Architectures[0])
+	}
+	var variables map[string]string
+	if c.Environment != nil {
+		variables = aws.StringValueMap(c.Environment.Variables)
+	}
+	var storage int64
+	if c.EphemeralStorage != nil {
+		storage = aws.Int64Value(c.EphemeralStorage.Size)
+	}
+	return &platform.Function{
+		Name:         aws.StringValue(c.FunctionName),
+		Arn:          aws.StringValue(c.FunctionArn),
+		Version:      aws.StringValue(c.Version),
+		CodeSha256:   aws.StringValue(c.CodeSha256),
+		Runtime:      aws.StringValue(c.Runtime),
+		Architecture: architecture,
+		Handler:      aws.StringValue(c.Handler),
+		Role:         aws.StringValue(c.Role),
+		FunctionSettings: platform.FunctionSettings{
+			Description:      aws.StringValue(c.Description),
+			MemorySize:       aws.Int64Value(c.MemorySize),
+			Timeout:          aws.Int64Value(c.Timeout),
+			Environment:      variables,
+			EphemeralStorage: storage,
+		},
+	}
+}
+
+func toAlias(a *lambda.AliasConfiguration) *platform.Alias {
+	return &platform.Alias{
+		Name:            aws.StringValue(a.Name),
+		Arn:             aws.StringValue(a.AliasArn),
+		FunctionVersion: aws.StringValue(a.FunctionVersion),
+	}
+}
+
+func toRole(r *iam.Role) *platform.Role {
+	// IAM returns policy documents URL encoded.
+	policy, err := url.QueryUnescape(aws.StringValue(r.AssumeRolePolicyDocument))
+	if err != nil {
+		policy = aws.StringValue(r.AssumeRolePolicyDocument)
+	}
+	return &platform.Role{
+		Name:                     aws.StringValue(r.RoleName),
+		Arn:                      aws.StringValue(r.Arn),
+		RoleId:                   aws.StringValue(r.RoleId),
+		AssumeRolePolicyDocument: policy,
+	}
+}
@@ -0,0 +1,493 @@
+package fake_platform
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/iam"
+	"github.com/aws/aws-sdk-go/service/lambda"
+	"github.com/gbdubs/ecology/platforms/platform"
+	"net/http"
+	"reflect"
+	"strconv"
+	"sync"
+)
+
+const fakeAccountId = "123456789012"
+
+// lambdaDefaults are Lambda's defaults, which fake functions share.
+var lambdaDefaults = platform.FunctionSettings{
+	MemorySize:       128,
+	Timeout:          3,
+	EphemeralStorage: 512,
+}
+
+// FakePlatform is an in-memory Platform for hermetic tests. It keeps track of
+// functions, their published versions and roles, and returns errors with the
+// same codes and status codes that AWS does. Failures can be injected per
+// method with FailNext.
+type FakePlatform struct {
+	Region    string
+	Functions map[string]*FakeFunction
+	Roles     map[string]*platform.Role
+	Apis      map[string]*FakeApi
+	// Calls records the name of every method called, in order.
+	Calls []string
+
+	mu       sync.Mutex
+	failures map[string][]error
+}
+
+type FakeFunction struct {
+	Config   platform.Function
+	ZipFile  []byte
+	Versions []platform.Function
+	Aliases  map[string]*platform.Alias
+	// The ids of the APIs that may invoke the function.
+	InvokePermissions map[string]bool
+}
+
+type FakeApi struct {
+	Api    platform.Api
+	Routes map[string]*platform.ApiRoute
+}
+
+func New(region string) *FakePlatform {
+	return &FakePlatform{
+		Region:    region,
+		Functions: make(map[string]*FakeFunction),
+		Roles:     make(map[string]*platform.Role),
+		Apis:      make(map[string]*FakeApi),
+		Calls:     make([]string, 0),
+		failures:  make(map[string][]error),
+	}
+}
+
+// FailNext makes the next call to the named method (e.g. "CreateFunction")
+// return err instead of doing anything. Repeated calls queue up failures.
+func (fp *FakePlatform) FailNext(method string, err error) {
+	fp.mu.Lock()
+	defer fp.mu.Unlock()
+	fp.failures[method] = append(fp.failures[method], err)
+}
+
+// NewServiceError builds an error shaped like the ones the AWS SDK returns.
+func NewServiceError(code string, statusCode int, message string) error {
+	return awserr.NewRequestFailure(awserr.New(code, message, nil), statusCode, "fake-request-id")
+}
+
+func (fp *FakePlatform) Name() string {
+	return "FAKE"
+}
+
+func (fp *FakePlatform) DefaultFunctionSettings() platform.FunctionSettings {
+	return lambdaDefaults
+}
+
+func (fp *FakePlatform) GetFunction(name string) (*platform.Function, error) {
+	fp.mu.Lock()
+	defer fp.mu.Unlock()
+	if err := fp.call("GetFunction"); err != nil {
+		return nil, err
+	}
+	f, ok := fp.Functions[name]
+	if !ok {
+		return nil, functionNotFound(name)
+	}
+	result := f.Config
+	return &result, nil
+}
+
+func (fp *FakePlatform) CreateFunction(input *platform.CreateFunctionInput) (*platform.Function, error) {
+	fp.mu.Lock()
+	defer fp.mu.Unlock()
+	if err := fp.call("CreateFunction"); err != nil {
+		return nil, err
+	}
+	if _, ok := fp.Functions[input.Name]; ok {
+		return nil, NewServiceError(lambda.ErrCodeResourceConflictException, http.StatusConflict,
+			fmt.Sprintf("Function already exist: %s", input.Name))
+	}
+	if _, ok := fp.roleByArn(input.Role); !ok {
+		return nil, NewServiceError(lambda.ErrCodeInvalidParameterValueException, http.StatusBadRequest,
+			"The role defined for the function cannot be assumed by Lambda.")
+	}
+	f := &FakeFunction{
+		Config: platform.Function{
+			Name:         input.Name,
+			Arn:          fmt.Sprintf("arn:aws:lambda:%s:%s:function:%s", fp.Region, fakeAccountId, input.Name),
+			Runtime:      input.Runtime,
+			Architecture: architectureOrDefault(input.Architecture),
+			Handler:      input.Handler,
+			Role:         input.Role,
+		},
+		Versions:          make([]platform.Function, 0),
+		Aliases:           make(map[string]*platform.Alias),
+		InvokePermissions: make(map[string]bool),
+	}
+	f.applySettings(input.FunctionSettings)
+	fp.Functions[input.Name] = f
+	return f.publish(input.ZipFile), nil
+}
+
+func (fp *FakePlatform) UpdateFunctionCode(name string, zipFile []byte, architecture string) (*platform.Function, error) {
+	fp.mu.Lock()
+	defer fp.mu.Unlock()
+	if err := fp.call("UpdateFunctionCode"); err != nil {
+		return nil, err
+	}
+	f, ok := fp.Functions[name]
+	if !ok {
+		return nil, functionNotFound(name)
+	}
+	f.Config.Architecture = architectureOrDefault(architecture)
+	return f.publish(zipFile), nil
+}
+
+func (fp *FakePlatform) UpdateFunctionConfiguration(input *platform.UpdateFunctionConfigurationInput) (*platform.Function, error) {
+	fp.mu.Lock()
+	defer fp.mu.Unlock()
+	if err := fp.call("UpdateFunctionConfiguration"); err != nil {
+		return nil, err
+	}
+	f, ok := fp.Functions[input.Name]
+	if !ok {
+		return nil, functionNotFound(input.Name)
+	}
+	f.Config.Runtime = input.Runtime
+	f.Config.Handler = input.Handler
+	f.applySettings(input.FunctionSettings)
+	result := f.Config
+	return &result, nil
+}
+
+// applySettings mirrors Lambda, with the defaults for settings that aren't
+// given.
+func (f *FakeFunction) applySettings(settings platform.FunctionSettings) {
+	settings = platform.WithDefaults(settings, lambdaDefaults)
+	f.Config.Description = settings.Description
+	f.Config.MemorySize = settings.MemorySize
+	f.Config.Timeout = settings.Timeout
+	f.Config.Environment = nil
+	if len(settings.Environment) > 0 {
+		f.Config.Environment = make(map[string]string)
+		for k, v := range settings.Environment {
+			f.Config.Environment[k] = v
+		}
+	}
+	f.Config.EphemeralStorage = settings.EphemeralStorage
+	f.Config.ReservedConcurrency = nil
+	if settings.ReservedConcurrency != nil {
+		reserved := *settings.ReservedConcurrency
+		f.Config.ReservedConcurrency = &reserved
+	}
+}
+
+// architectureOrDefault mirrors Lambda, which runs on x86_64 unless told not to.
+func architectureOrDefault(architecture string) string {
+	if architecture == "" {
+		return platform.ArchitectureX86_64
+	}
+	return architecture
+}
+
+func (fp *FakePlatform) PublishVersion(name string) (*platform.Function, error) {
+	fp.mu.Lock()
+	defer fp.mu.Unlock()
+	if err := fp.call("PublishVersion"); err != nil {
+		return nil, err
+	}
+	f, ok := fp.Functions[name]
+	if !ok {
+		return nil, functionNotFound(name)
+	}
+	// Like Lambda, nothing is published if nothing changed.
+	if n := len(f.Versions); n > 0 {
+		latest := f.Versions[n-1]
+		current := f.Config
+		current.Version = latest.Version
+		if reflect.DeepEqual(current, latest) {
+			return &latest, nil
+		}
+	}
+	return f.publish(f.ZipFile), nil
+}
+
+func (fp *FakePlatform) GetAlias(functionName string, alias string) (*platform.Alias, error) {
+	fp.mu.Lock()
+	defer fp.mu.Unlock()
+	if err := fp.call("GetAlias"); err != nil {
+		return nil, err
+	}
+	f, ok := fp.Functions[functionName]
+	if !ok {
+		return nil, functionNotFound(functionName)
+	}
+	a, ok := f.Aliases[alias]
+	if !ok {
+		return nil, functionNotFound(functionName + ":" + alias)
+	}
+	result := *a
+	return &result, nil
+}
+
+func (fp *FakePlatform) PutAlias(functionName string, alias string, version string) (*platform.Alias, error) {
+	fp.mu.Lock()
+	defer fp.mu.Unlock()
+	if err := fp.call("PutAlias"); err != nil {
+		return nil, err
+	}
+	f, ok := fp.Functions[functionName]
+	if !ok {
+		return nil, functionNotFound(functionName)
+	}
+	if n, err := strconv.Atoi(version); err != nil || n < 1 || n > len(f.Versions) {
+		return nil, functionNotFound(functionName + ":" + version)
+	}
+	a := &platform.Alias{
+		Name:            alias,
+		Arn:             f.Config.Arn + ":" + alias,
+		FunctionVersion: version,
+	}
+	f.Aliases[alias] = a
+	result := *a
+	return &result, nil
+}
+
+func (fp *FakePlatform) DeleteFunction(name string) error {
+	fp.mu.Lock()
+	defer fp.mu.Unlock()
+	if err := fp.call("DeleteFunction"); err != nil {
+		return err
+	}
+	if _, ok := fp.Functions[name]; !ok {
+		return functionNotFound(name)
+	}
+	delete(fp.Functions, name)
+	return nil
+}
+
+func (fp *FakePlatform) GetRole(name string) (*platform.Role, error) {
+	fp.mu.Lock()
+	defer fp.mu.Unlock()
+	if err := fp.call("GetRole"); err != nil {
+		return nil, err
+	}
+	r, ok := fp.Roles[name]
+	if !ok {
+		return nil, roleNotFound(name)
+	}
+	result := *r
+	return &result, nil
+}
+
+func (fp *FakePlatform) CreateRole(name string, assumeRolePolicyDocument string) (*platform.Role, error) {
+	fp.mu.Lock()
+	defer fp.mu.Unlock()
+	if err := fp.call("CreateRole"); err != nil {
+		return nil, err
+	}
+	if _, ok := fp.Roles[name]; ok {
+		return nil, NewServiceError(iam.ErrCodeEntityAlreadyExistsException, http.StatusConflict,
+			fmt.Sprintf("Role with name %s already exists.", name))
+	}
+	r := &platform.Role{
+		Name:                     name,
+		Arn:                      fmt.Sprintf("arn:aws:iam::%s:role/%s", fakeAccountId, name),
+		RoleId:                   fmt.Sprintf("AROAFAKE%012d", len(fp.Calls)),
+		AssumeRolePolicyDocument: assumeRolePolicyDocument,
+	}
+	fp.Roles[name] = r
+	result := *r
+	return &result, nil
+}
+
+func (fp *FakePlatform) DeleteRole(name string) error {
+	fp.mu.Lock()
+	defer fp.mu.Unlock()
+	if err := fp.call("DeleteRole"); err != nil {
+		return err
+	}
+	if _, ok := fp.Roles[name]; !ok {
+		return roleNotFound(name)
+	}
+	delete(fp.Roles, name)
+	return nil
+}
+
+func (fp *FakePlatform) GetApi(apiId string) (*platform.Api, error) {
+	fp.mu.Lock()
+	defer fp.mu.Unlock()
+	if err := fp.call("GetApi"); err != nil {
+		return nil, err
+	}
+	a, ok := fp.Apis[apiId]
+	if !ok {
+		return nil, apiNotFound(apiId)
+	}
+	result := a.Api
+	return &result, nil
+}
+
+func (fp *FakePlatform) CreateApi(name string) (*platform.Api, error) {
+	fp.mu.Lock()
+	defer fp.mu.Unlock()
+	if err := fp.call("CreateApi"); err != nil {
+		return nil, err
+	}
+	id := fmt.Sprintf("fakeapi%03d", len(fp.Calls))
+	a := &FakeApi{
+		Api: platform.Api{
+			Id:       id,
+			Name:     name,
+			Endpoint: fmt.Sprintf("https://%s.execute-api.%s.amazonaws.com", id, fp.Region),
+		},
+		Routes: make(map[string]*platform.ApiRoute),
+	}
+	fp.Apis[id] = a
+	result := a.Api
+	return &result, nil
+}
+
+func (fp *FakePlatform) DeleteApi(apiId string) error {
+	fp.mu.Lock()
+	defer fp.mu.Unlock()
+	if err := fp.call("DeleteApi"); err != nil {
+		return err
+	}
+	if _, ok := fp.Apis[apiId]; !ok {
+		return apiNotFound(apiId)
+	}
+	delete(fp.Apis, apiId)
+	return nil
+}
+
+func (fp *FakePlatform) GetRoutes(apiId string) ([]*platform.ApiRoute, error) {
+	fp.mu.Lock()
+	defer fp.mu.Unlock()
+	if err := fp.call("GetRoutes"); err != nil {
+		return nil, err
+	}
+	a, ok := fp.Apis[apiId]
+	if !ok {
+		return nil, apiNotFound(apiId)
+	}
+	result := make([]*platform.ApiRoute, 0)
+	for _, r := range a.Routes {
+		route := *r
+		result = append(result, &route)
+	}
+	return result, nil
+}
+
+func (fp *FakePlatform) PutRoute(apiId string, route *platform.ApiRoute) (*platform.ApiRoute, error) {
+	fp.mu.Lock()
+	defer fp.mu.Unlock()
+	if err := fp.call("PutRoute"); err != nil {
+		return nil, err
+	}
+	a, ok := fp.Apis[apiId]
+	if !ok {
+		return nil, apiNotFound(apiId)
+	}
+	stored := *route
+	if stored.RouteId == "" {
+		for _, r := range a.Routes {
+			if r.RouteKey == route.RouteKey {
+				return nil, NewServiceError("ConflictException", http.StatusConflict,
+					fmt.Sprintf("Route %s already exists", route.RouteKey))
+			}
+		}
+		stored.RouteId = fmt.Sprintf("fakeroute%03d", len(fp.Calls))
+	} else if _, ok := a.Routes[stored.RouteId]; !ok {
+		return nil, apiNotFound(stored.RouteId)
+	}
+	a.Routes[stored.RouteId] = &stored
+	result := stored
+	return &result, nil
+}
+
+func (fp *FakePlatform) DeleteRoute(apiId string, routeId string) error {
+	fp.mu.Lock()
+	defer fp.mu.Unlock()
+	if err := fp.call("DeleteRoute"); err != nil {
+		return err
+	}
+	a, ok := fp.Apis[apiId]
+	if !ok {
+		return apiNotFound(apiId)
+	}
+	if _, ok := a.Routes[routeId]; !ok {
+		return apiNotFound(routeId)
+	}
+	delete(a.Routes, routeId)
+	return nil
+}
+
+func (fp *FakePlatform) AddInvokePermission(functionArn string, apiId string) error {
+	fp.mu.Lock()
+	defer fp.mu.Unlock()
+	if err := fp.call("AddInvokePermission"); err != nil {
+		return err
+	}
+	for _, f := range fp.Functions {
+		if f.Config.Arn == functionArn {
+			f.InvokePermissions[apiId] = true
+			return nil
+		}
+		for _, a := range f.Aliases {
+			if a.Arn == functionArn {
+				f.InvokePermissions[apiId] = true
+				return nil
+			}
+		}
+	}
+	return functionNotFound(functionArn)
+}
+
+// call records a method call and pops any failure queued up for it.
+// Callers must hold fp.mu.
+func (fp *FakePlatform) call(method string) error {
+	fp.Calls = append(fp.Calls, method)
+	queued := fp.failures[method]
+	if len(queued) == 0 {
+		return nil
+	}
+	fp.failures[method] = queued[1:]
+	return queued[0]
+}
+
+func (fp *FakePlatform) roleByArn(arn string) (*platform.Role, bool) {
+	for _, r := range fp.Roles {
+		if r.Arn == arn {
+			return r, true
+		}
+	}
+	return nil, false
+}
+
+func (f *FakeFunction) publish(zipFile []byte) *platform.Function {
+	sum := sha256.Sum256(zipFile)
+	f.ZipFile = zipFile
+	f.Config.CodeSha256 = base64.StdEncoding.EncodeToString(sum[:])
+	f.Config.Version = strconv.Itoa(len(f.Versions) + 1)
+	f.Versions = append(f.Versions, f.Config)
+	result := f.Config
+	return &result
+}
+
+func functionNotFound(name string) error {
+	return NewServiceError(platform.ErrCodeFunctionNotFound, http.StatusNotFound,
+		fmt.Sprintf("Function not found: arn:aws:lambda:function:%s", name))
+}
+
+func roleNotFound(name string) error {
+	return NewServiceError(platform.ErrCodeRoleNotFound, http.StatusNotFound,
+		fmt.Sprintf("The role with name %s cannot be found.", name))
+}
+
+func apiNotFound(id string) error {
+	return NewServiceError(platform.ErrCodeApiNotFound, http.StatusNotFound,
+		fmt.Sprintf("Invalid identifier specified: %s", id))
+}
@@ -0,0 +1,424 @@
+package gcp_platform
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/gbdubs/ecology/platforms/platform"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"os/exec"
+	"regexp"
+	"strings"
+	"time"
+)
+
+const defaultFunctionsEndpoint = "https://cloudfunctions.googleapis.com"
+const defaultIamEndpoint = "https://iam.googleapis.com"
+
+// Lambdas are written against AWS runtime names, so translate them.
+const defaultRuntime = "go121"
+
+// GcpPlatform deploys lambdas as Cloud Functions, and creates service
+// accounts in place of executor roles. The endpoints can be pointed at a local
+// HTTP stand-in for the Cloud Functions and IAM APIs.
+type GcpPlatform struct {
+	Project           string
+	Region            string
+	AccessToken       string
+	FunctionsEndpoint string
+	IamEndpoint       string
+	HttpClient        *http.Client
+	PollInterval      time.Duration
+	OperationTimeout  time.Duration
+}
+
+type apiError struct {
+	StatusCode int
+	Status     string
+	Message    string
+}
+
+func (e *apiError) Error() string {
+	return fmt.Sprintf("GCP %d %s: %s", e.StatusCode, e.Status, e.Message)
+}
+
+func (e *apiError) Unwrap() error {
+	if e.StatusCode == http.StatusNotFound {
+		return platform.ErrNotFound
+	}
+	return nil
+}
+
+type cloudFunction struct {
+	Name                 string            `json:"name,omitempty"`
+	Description          string            `json:"description,omitempty"`
+	EntryPoint           string            `json:"entryPoint,omitempty"`
+	Runtime              string            `json:"runtime,omitempty"`
+	ServiceAccountEmail  string            `json:"serviceAccountEmail,omitempty"`
+	SourceUploadUrl      string            `json:"sourceUploadUrl,omitempty"`
+	HttpsTrigger         *httpsTrigger     `json:"httpsTrigger,omitempty"`
+	VersionId            string            `json:"versionId,omitempty"`
+	Status               string            `json:"status,omitempty"`
+	Labels               map[string]string `json:"labels,omitempty"`
+	AvailableMemoryMb    int64             `json:"availableMemoryMb,omitempty"`
+	Timeout              string            `json:"timeout,omitempty"`
+	EnvironmentVariables map[string]string `json:"environmentVariables,omitempty"`
+}
+
+type httpsTrigger struct {
+	Url string `json:"url,omitempty"`
+}
+
+type operation struct {
+	Name  string `json:"name"`
+	Done  bool   `json:"done"`
+	Error *struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+	} `json:"error,omitempty"`
+}
+
+type serviceAccount struct {
+	Name        string `json:"name,omitempty"`
+	Email       string `json:"email,omitempty"`
+	UniqueId    string `json:"uniqueId,omitempty"`
+	DisplayName string `json:"displayName,omitempty"`
+	Description string `json:"description,omitempty"`
+}
+
+// New builds a GcpPlatform using the GCP project and access token from the
+// environment (GOOGLE_CLOUD_PROJECT and GOOGLE_OAUTH_ACCESS_TOKEN), falling
+// back to the active gcloud configuration.
+func New(region string) (*GcpPlatform, error) {
+	project, err := fromEnvOrGcloud("GOOGLE_CLOUD_PROJECT", "config", "get-value", "project")
+	if err != nil {
+		return nil, err
+	}
+	token, err := fromEnvOrGcloud("GOOGLE_OAUTH_ACCESS_TOKEN", "auth", "print-access-token")
+	if err != nil {
+		return nil, err
+	}
+	return &GcpPlatform{
+		Project:           project,
+		Region:            region,
+		AccessToken:       token,
+		FunctionsEndpoint: defaultFunctionsEndpoint,
+		IamEndpoint:       defaultIamEndpoint,
+		HttpClient:        http.DefaultClient,
+		PollInterval:      2 * time.Second,
+		OperationTimeout:  5 * time.Minute,
+	}, nil
+}
+
+func fromEnvOrGcloud(envVar string, gcloudArgs ...string) (string, error) {
+	if value := os.Getenv(envVar); value != "" {
+		return value, nil
+	}
+	result, err := exec.Command("gcloud", gcloudArgs...).Output()
+	if err != nil {
+		return "", errors.New(fmt.Sprintf("Couldn't determine %s from the environment or gcloud: %v", envVar, err))
+	}
+	value := strings.TrimSpace(string(result))
+	if value == "" {
+		return "", errors.New(fmt.Sprintf("Must set %s or configure gcloud", envVar))
+	}
+	return value, nil
+}
+
+func (gp *GcpPlatform) Name() string {
+	return "GCP"
+}
+
+// DefaultFunctionSettings are Cloud Functions' defaults.
+func (gp *GcpPlatform) DefaultFunctionSettings() platform.FunctionSettings {
+	return platform.FunctionSettings{
+		MemorySize: 256,
+		Timeout:    60,
+	}
+}
+
+func (gp *GcpPlatform) GetFunction(name string) (*platform.Function, error) {
+	cf := &cloudFunction{}
+	if err := gp.do("GET", gp.functionUrl(name), nil, cf); err != nil {
+		return nil, err
+	}
+	return toFunction(cf), nil
+}
+
+func (gp *GcpPlatform) CreateFunction(input *platform.CreateFunctionInput) (*platform.Function, error) {
+	uploadUrl, err := gp.uploadSource(input.ZipFile)
+	if err != nil {
+		return nil, err
+	}
+	cf := &cloudFunction{
+		Name:                gp.functionName(input.Name),
+		Description:         input.Description,
+		EntryPoint:          input.Handler,
+		Runtime:             gcpRuntime(input.Runtime),
+		ServiceAccountEmail: input.Role,
+		SourceUploadUrl:     uploadUrl,
+		HttpsTrigger:        &httpsTrigger{},
+		Labels:              map[string]string{"deployed-by": "ecology"},
+	}
+	setSettings(cf, input.FunctionSettings)
+	op := &operation{}
+	if err = gp.do("POST", gp.locationUrl()+"/functions", cf, op); err != nil {
+		return nil, err
+	}
+	if err = gp.wait(op); err != nil {
+		return nil, err
+	}
+	return gp.GetFunction(input.Name)
+}
+
+// UpdateFunctionCode ignores the architecture, which Cloud Functions choose.
+func (gp *GcpPlatform) UpdateFunctionCode(name string, zipFile []byte, architecture string) (*platform.Function, error) {
+	uploadUrl, err := gp.uploadSource(zipFile)
+	if err != nil {
+		return nil, err
+	}
+	cf := &cloudFunction{
+		SourceUploadUrl: uploadUrl,
+	}
+	op := &operation{}
+	if err = gp.do("PATCH", gp.functionUrl(name)+"?updateMask=sourceUploadUrl", cf, op); err != nil {
+		return nil, err
+	}
+	if err = gp.wait(op); err != nil {
+		return nil, err
+	}
+	return gp.GetFunction(name)
+}
+
+// UpdateFunctionConfiguration ignores the ephemeral storage and reserved
+// concurrency, which Cloud Functions don't have. Every setting is in the update
+// mask, so that those which aren't given revert to their defaults.
+func (gp *GcpPlatform) UpdateFunctionConfiguration(input *platform.UpdateFunctionConfigurationInput) (*platform.Function, error) {
+	cf := &cloudFunction{
+		EntryPoint: input.Handler,
+		Runtime:    gcpRuntime(input.Runtime),
+	}
+	setSettings(cf, input.FunctionSettings)
+	mask := []string{"entryPoint", "runtime", "description", "availableMemoryMb", "timeout", "environmentVariables"}
+	op := &operation{}
+	if err := gp.do("PATCH", gp.functionUrl(input.Name)+"?updateMask="+strings.Join(mask, ","), cf, op); err != nil {
+		return nil, err
+	}
+	if err := gp.wait(op); err != nil {
+		return nil, err
+	}
+	return gp.GetFunction(input.Name)
+}
+
+// setSettings copies the settings that Cloud Functions have.
+func setSettings(cf *cloudFunction, settings platform.FunctionSettings) {
+	cf.Description = settings.Description
+	if settings.MemorySize > 0 {
+		cf.AvailableMemoryMb = settings.MemorySize
+	}
+	if settings.Timeout > 0 {
+		cf.Timeout = fmt.Sprintf("%ds", settings.Timeout)
+	}
+	cf.EnvironmentVariables = settings.Environment
+}
+
+// gcpRuntime translates AWS runtime names, which GCP doesn't have.
+func gcpRuntime(runtime string) string {
+	if runtime == "" || strings.HasPrefix(runtime, "go1.") || strings.HasPrefix(runtime, "provided.") {
+		return defaultRuntime
+	}
+	return runtime
+}
+
+func (gp *GcpPlatform) DeleteFunction(name string) error {
+	op := &operation{}
+	if err := gp.do("DELETE", gp.functionUrl(name), nil, op); err != nil {
+		return err
+	}
+	return gp.wait(op)
+}
+
+func (gp *GcpPlatform) GetRole(name string) (*platform.Role, error) {
+	sa := &serviceAccount{}
+	if err := gp.do("GET", gp.serviceAccountUrl(name), nil, sa); err != nil {
+		return nil, err
+	}
+	return toRole(name, sa), nil
+}
+
+// CreateRole creates a service account to run functions as. GCP has no
+// equivalent of an assume role policy, so the document is ignored.
+func (gp *GcpPlatform) CreateRole(name string, assumeRolePolicyDocument string) (*platform.Role, error) {
+	request := map[string]interface{}{
+		"accountId": serviceAccountId(name),
+		"serviceAccount": &serviceAccount{
+			DisplayName: name,
+			Description: fmt.Sprintf("Ecology-Generated executor %s.", name),
+		},
+	}
+	sa := &serviceAccount{}
+	if err := gp.do("POST", fmt.Sprintf("%s/v1/projects/%s/serviceAccounts", gp.IamEndpoint, gp.Project), request, sa); err != nil {
+		return nil, err
+	}
+	return toRole(name, sa), nil
+}
+
+func (gp *GcpPlatform) DeleteRole(name string) error {
+	return gp.do("DELETE", gp.serviceAccountUrl(name), nil, nil)
+}
+
+func (gp *GcpPlatform) uploadSource(zipFile []byte) (uploadUrl string, err error) {
+	generated := &struct {
+		UploadUrl string `json:"uploadUrl"`
+	}{}
+	err = gp.do("POST", gp.locationUrl()+"/functions:generateUploadUrl", struct{}{}, generated)
+	if err != nil {
+		return
+	}
+	request, err := http.NewRequest("PUT", generated.UploadUrl, bytes.NewReader(zipFile))
+	if err != nil {
+		return
+	}
+	request.Header.Set("Content-Type", "application/zip")
+	request.Header.Set("x-goog-content-length-range", "0,104857600")
+	response, err := gp.HttpClient.Do(request)
+	if err != nil {
+		return
+	}
+	defer response.Body.Close()
+	if response.StatusCode >= 300 {
+		body, _ := ioutil.ReadAll(response.Body)
+		return "", &apiError{StatusCode: response.StatusCode, Status: response.Status, Message: string(body)}
+	}
+	return generated.UploadUrl, nil
+}
+
+func (gp *GcpPlatform) wait(op *operation) error {
+	deadline := time.Now().Add(gp.OperationTimeout)
+	for !op.Done {
+		if time.Now().After(deadline) {
+			return errors.New(fmt.Sprintf("Timed out waiting for GCP operation %s", op.Name))
+		}
+		time.Sleep(gp.PollInterval)
+		if err := gp.do("GET", fmt.Sprintf("%s/v1/%s", gp.FunctionsEndpoint, op.Name), nil, op); err != nil {
+			return err
+		}
+	}
+	if op.Error != nil {
+		return &apiError{StatusCode: op.Error.Code, Status: "OPERATION_FAILED", Message: op.Error.Message}
+	}
+	return nil
+}
+
+func (gp *GcpPlatform) do(method string, url string, body interface{}, result interface{}) error {
+	var requestBody []byte
+	if body != nil {
+		var err error
+		if requestBody, err = json.Marshal(body); err != nil {
+			return err
+		}
+	}
+	request, err := http.NewRequest(method, url, bytes.NewReader(requestBody))
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Authorization", "Bearer "+gp.AccessToken)
+	request.Header.Set("Content-Type", "application/json")
+	response, err := gp.HttpClient.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+	responseBody, err := ioutil.ReadAll(response.Body)
+	if err != nil {
+		return err
+	}
+	if response.StatusCode >= 300 {
+		googleError := &struct {
+			Error struct {
+				Message string `json:"message"`
+				Status  string `json:"status"`
+			} `json:"error"`
+		}{}
+		json.Unmarshal(responseBody, googleError)
+		return &apiError{
+			StatusCode: response.StatusCode,
+			Status:     googleError.Error.Status,
+			Message:    googleError.Error.Message,
+		}
+	}
+	if result == nil || len(responseBody) == 0 {
+		return nil
+	}
+	return json.Unmarshal(responseBody, result)
+}
+
+func (gp *GcpPlatform) locationUrl() string {
+	return fmt.Sprintf("%s/v1/projects/%s/locations/%s", gp.FunctionsEndpoint, gp.Project, gp.Region)
+}
+
+func (gp *GcpPlatform) functionName(name string) string {
+	return fmt.Sprintf("projects/%s/locations/%s/functions/%s", gp.Project, gp.Region, name)
+}
+
+func (gp *GcpPlatform) functionUrl(name string) string {
+	return fmt.Sprintf("%s/v1/%s", gp.FunctionsEndpoint, gp.functionName(name))
+}
+
+func (gp *GcpPlatform) serviceAccountEmail(roleName string) string {
+	return fmt.Sprintf("%s@%s.iam.gserviceaccount.com", serviceAccountId(roleName), gp.Project)
+}
+
+func (gp *GcpPlatform) serviceAccountUrl(roleName string) string {
+	return fmt.Sprintf("%s/v1/projects/%s/serviceAccounts/%s", gp.IamEndpoint, gp.Project, gp.serviceAccountEmail(roleName))
+}
+
+var invalidServiceAccountChars = regexp.MustCompile("[^a-z0-9-]+")
+
+// Service account ids must be 6-30 lowercase letters, digits or hyphens, and
+// start with a letter, so role names are squashed to fit.
+func serviceAccountId(roleName string) string {
+	id := invalidServiceAccountChars.ReplaceAllString(strings.ToLower(roleName), "-")
+	id = strings.Trim(id, "-")
+	if id == "" || id[0] < 'a' || id[0] > 'z' {
+		id = "e-" + id
+	}
+	if len(id) > 30 {
+		hash := fmt.Sprintf("%x", sha256.Sum256([]byte(roleName)))
+		id = strings.TrimRight(id[:21], "-") + "-" + hash[:8]
+	}
+	for len(id) < 6 {
+		id = id + "-x"
+	}
+	return id
+}
+
+func toFunction(cf *cloudFunction) *platform.Function {
+	timeout, _ := time.ParseDuration(cf.Timeout)
+	return &platform.Function{
+		Name:    cf.Name[strings.LastIndex(cf.Name, "/")+1:],
+		Arn:     cf.Name,
+		Version: cf.VersionId,
+		Runtime: cf.Runtime,
+		Handler: cf.EntryPoint,
+		Role:    cf.ServiceAccountEmail,
+		FunctionSettings: platform.FunctionSettings{
+			Description: cf.Description,
+			MemorySize:  cf.AvailableMemoryMb,
+			Timeout:     int64(timeout.Seconds()),
+			Environment: cf.EnvironmentVariables,
+		},
+	}
+}
+
+func toRole(name string, sa *serviceAccount) *platform.Role {
+	return &platform.Role{
+		Name:   name,
+		Arn:    sa.Email,
+		RoleId: sa.UniqueId,
+	}
+}
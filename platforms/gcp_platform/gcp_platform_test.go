@@ -0,0 +1,366 @@
+package gcp_platform
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/gbdubs/ecology/manifests/lambda_manifest"
+	"github.com/gbdubs/ecology/platforms/platform"
+	"github.com/gbdubs/ecology/util/cloud_function_source"
+	"github.com/gbdubs/ecology/util/output"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeGcp stands in for the Cloud Functions and IAM APIs, as far as
+// GcpPlatform uses them. Operations finish on the first poll.
+type fakeGcp struct {
+	server    *httptest.Server
+	mu        sync.Mutex
+	functions map[string]*cloudFunction
+	accounts  map[string]*serviceAccount
+	uploads   map[string][]byte
+	// Every upload url that a function was created or updated from.
+	deployed []string
+	// Fails the next operation that is polled with this message.
+	failNext string
+	requests []string
+}
+
+const testProject = "proj"
+const testRegion = "us-central1"
+
+func newFakeGcp(t *testing.T) (*fakeGcp, *GcpPlatform) {
+	fg := &fakeGcp{
+		functions: make(map[string]*cloudFunction),
+		accounts:  make(map[string]*serviceAccount),
+		uploads:   make(map[string][]byte),
+	}
+	fg.server = httptest.NewServer(http.HandlerFunc(fg.serve))
+	t.Cleanup(fg.server.Close)
+	gp := &GcpPlatform{
+		Project:           testProject,
+		Region:            testRegion,
+		AccessToken:       "token",
+		FunctionsEndpoint: fg.server.URL,
+		IamEndpoint:       fg.server.URL,
+		HttpClient:        fg.server.Client(),
+		PollInterval:      time.Millisecond,
+		OperationTimeout:  time.Second,
+	}
+	return fg, gp
+}
+
+func (fg *fakeGcp) serve(w http.ResponseWriter, r *http.Request) {
+	fg.mu.Lock()
+	defer fg.mu.Unlock()
+	fg.requests = append(fg.requests, r.Method+" "+r.URL.Path)
+	body, _ := ioutil.ReadAll(r.Body)
+	functions := fmt.Sprintf("/v1/projects/%s/locations/%s/functions", testProject, testRegion)
+	accounts := fmt.Sprintf("/v1/projects/%s/serviceAccounts", testProject)
+	if strings.HasPrefix(r.URL.Path, "/upload/") {
+		if r.Method != "PUT" || r.Header.Get("Content-Type") != "application/zip" {
+			http.Error(w, "uploads are zipped PUTs", http.StatusBadRequest)
+			return
+		}
+		fg.uploads[fg.server.URL+r.URL.Path] = body
+		return
+	}
+	if r.Header.Get("Authorization") != "Bearer token" {
+		fg.fail(w, http.StatusUnauthorized, "UNAUTHENTICATED", "no token")
+		return
+	}
+	switch {
+	case r.Method == "POST" && r.URL.Path == functions+":generateUploadUrl":
+		fg.reply(w, map[string]string{"uploadUrl": fmt.Sprintf("%s/upload/%d", fg.server.URL, len(fg.requests))})
+	case r.Method == "POST" && r.URL.Path == functions:
+		cf := &cloudFunction{}
+		json.Unmarshal(body, cf)
+		if _, ok := fg.functions[cf.Name]; ok {
+			fg.fail(w, http.StatusConflict, "ALREADY_EXISTS", cf.Name)
+			return
+		}
+		if !fg.uploaded(w, cf.SourceUploadUrl) {
+			return
+		}
+		cf.VersionId = "1"
+		fg.functions[cf.Name] = withDefaults(cf)
+		fg.operation(w)
+	case strings.HasPrefix(r.URL.Path, functions+"/"):
+		name := strings.TrimPrefix(r.URL.Path, "/v1/")
+		cf, ok := fg.functions[name]
+		if !ok {
+			fg.fail(w, http.StatusNotFound, "NOT_FOUND", name+" not found")
+			return
+		}
+		switch r.Method {
+		case "GET":
+			fg.reply(w, cf)
+		case "DELETE":
+			delete(fg.functions, name)
+			fg.operation(w)
+		case "PATCH":
+			update := &cloudFunction{}
+			json.Unmarshal(body, update)
+			if !fg.patch(w, cf, update, strings.Split(r.URL.Query().Get("updateMask"), ",")) {
+				return
+			}
+			fg.operation(w)
+		}
+	case r.Method == "GET" && strings.HasPrefix(r.URL.Path, "/v1/operations/"):
+		op := map[string]interface{}{"name": strings.TrimPrefix(r.URL.Path, "/v1/"), "done": true}
+		if fg.failNext != "" {
+			op["error"] = map[string]interface{}{"code": 3, "message": fg.failNext}
+			fg.failNext = ""
+		}
+		fg.reply(w, op)
+	case r.Method == "POST" && r.URL.Path == accounts:
+		request := &struct {
+			AccountId      string         `json:"accountId"`
+			ServiceAccount serviceAccount `json:"serviceAccount"`
+		}{}
+		json.Unmarshal(body, request)
+		sa := request.ServiceAccount
+		sa.Email = fmt.Sprintf("%s@%s.iam.gserviceaccount.com", request.AccountId, testProject)
+		sa.UniqueId = fmt.Sprintf("%d", len(fg.accounts)+1)
+		fg.accounts[sa.Email] = &sa
+		fg.reply(w, sa)
+	case strings.HasPrefix(r.URL.Path, accounts+"/"):
+		email := strings.TrimPrefix(r.URL.Path, accounts+"/")
+		sa, ok := fg.accounts[email]
+		if !ok {
+			fg.fail(w, http.StatusNotFound, "NOT_FOUND", email+" not found")
+			return
+		}
+		if r.Method == "DELETE" {
+			delete(fg.accounts, email)
+		}
+		fg.reply(w, sa)
+	default:
+		fg.fail(w, http.StatusNotFound, "NOT_FOUND", r.Method+" "+r.URL.Path)
+	}
+}
+
+// patch updates the fields in the mask, clearing those that aren't in the
+// update, as the Cloud Functions API does.
+func (fg *fakeGcp) patch(w http.ResponseWriter, cf *cloudFunction, update *cloudFunction, mask []string) bool {
+	for _, field := range mask {
+		switch field {
+		case "sourceUploadUrl":
+			if !fg.uploaded(w, update.SourceUploadUrl) {
+				return false
+			}
+			cf.SourceUploadUrl = update.SourceUploadUrl
+		case "entryPoint":
+			cf.EntryPoint = update.EntryPoint
+		case "runtime":
+			cf.Runtime = update.Runtime
+		case "description":
+			cf.Description = update.Description
+		case "availableMemoryMb":
+			cf.AvailableMemoryMb = update.AvailableMemoryMb
+		case "timeout":
+			cf.Timeout = update.Timeout
+		case "environmentVariables":
+			cf.EnvironmentVariables = update.EnvironmentVariables
+		default:
+			fg.fail(w, http.StatusBadRequest, "INVALID_ARGUMENT", "unknown field "+field)
+			return false
+		}
+	}
+	withDefaults(cf)
+	cf.VersionId = fmt.Sprintf("%d", len(fg.deployed))
+	return true
+}
+
+func withDefaults(cf *cloudFunction) *cloudFunction {
+	if cf.AvailableMemoryMb == 0 {
+		cf.AvailableMemoryMb = 256
+	}
+	if cf.Timeout == "" {
+		cf.Timeout = "60s"
+	}
+	return cf
+}
+
+func (fg *fakeGcp) uploaded(w http.ResponseWriter, uploadUrl string) bool {
+	if _, ok := fg.uploads[uploadUrl]; !ok {
+		fg.fail(w, http.StatusBadRequest, "INVALID_ARGUMENT", "nothing was uploaded to "+uploadUrl)
+		return false
+	}
+	fg.deployed = append(fg.deployed, uploadUrl)
+	return true
+}
+
+func (fg *fakeGcp) operation(w http.ResponseWriter) {
+	fg.reply(w, &operation{Name: fmt.Sprintf("operations/%d", len(fg.requests))})
+}
+
+func (fg *fakeGcp) reply(w http.ResponseWriter, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(body)
+}
+
+func (fg *fakeGcp) fail(w http.ResponseWriter, code int, status string, message string) {
+	w.WriteHeader(code)
+	fg.reply(w, map[string]interface{}{"error": map[string]interface{}{"code": code, "status": status, "message": message}})
+}
+
+func (fg *fakeGcp) function(name string) *cloudFunction {
+	return fg.functions[fmt.Sprintf("projects/%s/locations/%s/functions/%s", testProject, testRegion, name)]
+}
+
+func TestFunctionLifecycle(t *testing.T) {
+	fg, gp := newFakeGcp(t)
+	role, err := gp.CreateRole("P-A-executor", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if role.Arn != "p-a-executor@proj.iam.gserviceaccount.com" {
+		t.Fatalf("CreateRole() = %+v", role)
+	}
+	f, err := gp.CreateFunction(&platform.CreateFunctionInput{
+		Name:    "P-A",
+		Handler: cloud_function_source.EntryPoint,
+		Role:    role.Arn,
+		Runtime: "provided.al2023",
+		ZipFile: []byte("zip"),
+		FunctionSettings: platform.FunctionSettings{
+			Description: "A",
+			MemorySize:  512,
+			Timeout:     30,
+			Environment: map[string]string{"STAGE": "prod"},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f.Name != "P-A" || f.Handler != cloud_function_source.EntryPoint || f.Runtime != defaultRuntime || f.Role != role.Arn ||
+		f.MemorySize != 512 || f.Timeout != 30 || f.Environment["STAGE"] != "prod" {
+		t.Fatalf("CreateFunction() = %+v", f)
+	}
+	if cf := fg.function("P-A"); cf.HttpsTrigger == nil || cf.Labels["deployed-by"] != "ecology" || string(fg.uploads[cf.SourceUploadUrl]) != "zip" {
+		t.Fatalf("created %+v", cf)
+	}
+
+	// Settings that are left out revert to their defaults.
+	f, err = gp.UpdateFunctionConfiguration(&platform.UpdateFunctionConfigurationInput{
+		Name:    "P-A",
+		Handler: cloud_function_source.EntryPoint,
+		Runtime: "provided.al2023",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defaults := gp.DefaultFunctionSettings()
+	if f.Description != "" || f.MemorySize != defaults.MemorySize || f.Timeout != defaults.Timeout || len(f.Environment) != 0 {
+		t.Fatalf("UpdateFunctionConfiguration() = %+v", f)
+	}
+
+	if f, err = gp.UpdateFunctionCode("P-A", []byte("new zip"), platform.ArchitectureArm64); err != nil {
+		t.Fatal(err)
+	}
+	if cf := fg.function("P-A"); string(fg.uploads[cf.SourceUploadUrl]) != "new zip" || len(fg.deployed) != 2 {
+		t.Fatalf("UpdateFunctionCode() deployed %v", fg.deployed)
+	}
+
+	if err = gp.DeleteFunction("P-A"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = gp.GetFunction("P-A"); !platform.IsNotFound(err) {
+		t.Fatalf("GetFunction() of a deleted function = %v, want not found", err)
+	}
+	if err = gp.DeleteRole("P-A-executor"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = gp.GetRole("P-A-executor"); !platform.IsNotFound(err) {
+		t.Fatalf("GetRole() of a deleted role = %v, want not found", err)
+	}
+}
+
+func TestFailedOperation(t *testing.T) {
+	fg, gp := newFakeGcp(t)
+	fg.failNext = "Build failed: no main package"
+	_, err := gp.CreateFunction(&platform.CreateFunctionInput{Name: "P-A", ZipFile: []byte("zip")})
+	if err == nil || !strings.Contains(err.Error(), "Build failed: no main package") {
+		t.Fatalf("CreateFunction() = %v, want the operation's error", err)
+	}
+}
+
+func TestServiceAccountIds(t *testing.T) {
+	tests := map[string]string{
+		"P-A-executor":  "p-a-executor",
+		"P":             "p-x-x-x",
+		"9-executor":    "e-9-executor",
+		"My_Project-Mx": "my-project-mx",
+	}
+	for roleName, want := range tests {
+		if got := serviceAccountId(roleName); got != want {
+			t.Errorf("serviceAccountId(%q) = %q, want %q", roleName, got, want)
+		}
+	}
+	long := serviceAccountId("AVeryLongProjectName-AVeryLongLambdaName-executor")
+	if len(long) > 30 || long == serviceAccountId("AVeryLongProjectName-AVeryLongLambdaName-executor2") {
+		t.Errorf("serviceAccountId() of long names = %q, want at most 30 characters that stay unique", long)
+	}
+}
+
+// A lambda pushed to GCP is deployed as a Cloud Function module that calls its
+// HandleRequest.
+func TestPushLambda(t *testing.T) {
+	fg, gp := newFakeGcp(t)
+	projectDir := t.TempDir()
+	o := output.NewForTesting()
+	lm, err := lambda_manifest.New(projectDir, "P", "A", "GCP", testRegion, "", "", o)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = os.MkdirAll(lm.Config.FolderPath, 0755); err != nil {
+		t.Fatal(err)
+	}
+	files := map[string]string{
+		filepath.Join(projectDir, "go.mod"): "module example.com/p\n\ngo 1.21\n",
+		lm.Config.CodePath:                  "package main\n\nimport \"context\"\n\nfunc HandleRequest(ctx context.Context, request struct{}) (string, error) {\n\treturn \"A\", nil\n}\n\nfunc main() {}\n",
+	}
+	for path, contents := range files {
+		if err = ioutil.WriteFile(path, []byte(contents), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err = lm.PushToPlatform(gp, o); err != nil {
+		t.Fatal(err)
+	}
+	cf := fg.function("P-A")
+	if cf == nil || cf.EntryPoint != cloud_function_source.EntryPoint || cf.ServiceAccountEmail != lm.ExecutorRoleManifest.Deploy.Arn {
+		t.Fatalf("pushed %+v", cf)
+	}
+	zipped := fg.uploads[cf.SourceUploadUrl]
+	reader, err := zip.NewReader(bytes.NewReader(zipped), int64(len(zipped)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	names := make([]string, 0)
+	for _, f := range reader.File {
+		names = append(names, f.Name)
+		if f.Name == "A.go" {
+			source, _ := f.Open()
+			contents, _ := ioutil.ReadAll(source)
+			if !strings.HasPrefix(string(contents), "package function\n") {
+				t.Errorf("A.go is still in its own package:\n%s", contents)
+			}
+		}
+	}
+	sort.Strings(names)
+	if got := strings.Join(names, ","); got != "A.go,ecology_entry_point.go,go.mod" {
+		t.Fatalf("zip holds %s", got)
+	}
+}
@@ -0,0 +1,191 @@
+package platform
+
+import (
+	"errors"
+)
+
+// Platform is the set of operations that ecology needs from a cloud provider
+// in order to deploy lambdas and the roles that execute them. Each supported
+// provider (AWS, GCP, in-memory fakes) supplies its own implementation.
+type Platform interface {
+	Name() string
+
+	GetFunction(name string) (*Function, error)
+	CreateFunction(input *CreateFunctionInput) (*Function, error)
+	// UpdateFunctionCode also sets the architecture, which can only be changed
+	// along with the code built for it.
+	UpdateFunctionCode(name string, zipFile []byte, architecture string) (*Function, error)
+	// UpdateFunctionConfiguration reverts settings that aren't given to their
+	// defaults.
+	UpdateFunctionConfiguration(input *UpdateFunctionConfigurationInput) (*Function, error)
+	DeleteFunction(name string) error
+	// DefaultFunctionSettings are the settings that functions have unless they
+	// set others.
+	DefaultFunctionSettings() FunctionSettings
+
+	GetRole(name string) (*Role, error)
+	CreateRole(name string, assumeRolePolicyDocument string) (*Role, error)
+	DeleteRole(name string) error
+}
+
+// ApiPlatform is implemented by platforms that can route HTTP requests to
+// functions. Routes are identified by their route key, e.g. "GET /hello".
+type ApiPlatform interface {
+	GetApi(apiId string) (*Api, error)
+	CreateApi(name string) (*Api, error)
+	DeleteApi(apiId string) error
+
+	GetRoutes(apiId string) ([]*ApiRoute, error)
+	// PutRoute creates the route if it has no RouteId, and updates it otherwise.
+	PutRoute(apiId string, route *ApiRoute) (*ApiRoute, error)
+	DeleteRoute(apiId string, routeId string) error
+
+	// AddInvokePermission allows the API to invoke the function. It succeeds
+	// if the permission was already granted.
+	AddInvokePermission(functionArn string, apiId string) error
+}
+
+// AliasPlatform is implemented by platforms that keep the published versions
+// of functions, and let named aliases point at one of them, so that callers of
+// the alias can be moved between versions without redeploying anything.
+type AliasPlatform interface {
+	// PublishVersion publishes the function's current code and configuration,
+	// or returns the latest version if neither has changed since it.
+	PublishVersion(name string) (*Function, error)
+	GetAlias(functionName string, alias string) (*Alias, error)
+	// PutAlias creates the alias if it doesn't exist, and repoints it otherwise.
+	PutAlias(functionName string, alias string, version string) (*Alias, error)
+}
+
+type Function struct {
+	Name         string
+	Arn          string
+	Version      string
+	CodeSha256   string
+	Runtime      string
+	Architecture string
+	Handler      string
+	Role         string
+	FunctionSettings
+}
+
+// FunctionSettings are the settings of a function that a lambda manifest can
+// declare. Zero values mean the platform's default, so that removing a setting
+// reverts it. Platforms ignore the settings that they don't have.
+type FunctionSettings struct {
+	Description string
+	// In MB.
+	MemorySize int64
+	// In seconds.
+	Timeout     int64
+	Environment map[string]string
+	// The size of /tmp, in MB.
+	EphemeralStorage int64
+	// How many concurrent executions to reserve for the function.
+	ReservedConcurrency *int64
+}
+
+// WithDefaults fills in the settings that aren't set from the defaults.
+func WithDefaults(settings FunctionSettings, defaults FunctionSettings) FunctionSettings {
+	if settings.Description == "" {
+		settings.Description = defaults.Description
+	}
+	if settings.MemorySize == 0 {
+		settings.MemorySize = defaults.MemorySize
+	}
+	if settings.Timeout == 0 {
+		settings.Timeout = defaults.Timeout
+	}
+	if settings.Environment == nil {
+		settings.Environment = defaults.Environment
+	}
+	if settings.EphemeralStorage == 0 {
+		settings.EphemeralStorage = defaults.EphemeralStorage
+	}
+	if settings.ReservedConcurrency == nil {
+		settings.ReservedConcurrency = defaults.ReservedConcurrency
+	}
+	return settings
+}
+
+// Architectures that functions can run on. Platforms that don't let functions
+// choose ignore them.
+const (
+	ArchitectureX86_64 = "x86_64"
+	ArchitectureArm64  = "arm64"
+)
+
+type CreateFunctionInput struct {
+	Name         string
+	Handler      string
+	Role         string
+	Runtime      string
+	Architecture string
+	ZipFile      []byte
+	FunctionSettings
+}
+
+type UpdateFunctionConfigurationInput struct {
+	Name    string
+	Handler string
+	Runtime string
+	FunctionSettings
+}
+
+type Alias struct {
+	Name string
+	// Invoking this ARN invokes the version that the alias points at.
+	Arn             string
+	FunctionVersion string
+}
+
+type Role struct {
+	Name                     string
+	Arn                      string
+	RoleId                   string
+	AssumeRolePolicyDocument string
+}
+
+type Api struct {
+	Id       string
+	Name     string
+	Endpoint string
+}
+
+type ApiRoute struct {
+	RouteId           string
+	RouteKey          string
+	FunctionArn       string
+	AuthorizationType string
+}
+
+// Error codes returned by platforms when a resource can't be found. These
+// mirror the codes that AWS uses, so errors straight from the AWS SDK match.
+const (
+	ErrCodeFunctionNotFound = "ResourceNotFoundException"
+	ErrCodeRoleNotFound     = "NoSuchEntity"
+	ErrCodeApiNotFound      = "NotFoundException"
+)
+
+// ErrNotFound can be returned (or wrapped) by platforms that don't have coded
+// errors of their own.
+var ErrNotFound = errors.New("resource not found on platform")
+
+type codedError interface {
+	error
+	Code() string
+}
+
+func IsNotFound(err error) bool {
+	if err == nil {
+		return false
+	}
+	if errors.Is(err, ErrNotFound) {
+		return true
+	}
+	var ce codedError
+	if errors.As(err, &ce) {
+		return ce.Code() == ErrCodeFunctionNotFound || ce.Code() == ErrCodeRoleNotFound || ce.Code() == ErrCodeApiNotFound
+	}
+	return false
+}
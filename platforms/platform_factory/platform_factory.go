@@ -0,0 +1,24 @@
+package platform_factory
+
+import (
+	"errors"
+	"fmt"
+	"github.com/gbdubs/ecology/platforms/aws_platform"
+	"github.com/gbdubs/ecology/platforms/gcp_platform"
+	"github.com/gbdubs/ecology/platforms/platform"
+)
+
+// New constructs the Platform named by a manifest's Deploy.Platform field.
+func New(platformName string, region string) (platform.Platform, error) {
+	switch platformName {
+	case "AWS":
+		return aws_platform.New(region), nil
+	case "GCP":
+		gp, err := gcp_platform.New(region)
+		if err != nil {
+			return nil, err
+		}
+		return gp, nil
+	}
+	return nil, errors.New(fmt.Sprintf("Unknown platform %s", platformName))
+}
@@ -0,0 +1,41 @@
+package atomic_file
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+// WriteFile writes data to a temporary file next to path and renames it into
+// place, so that a crash leaves either the old contents or the new ones,
+// never a truncated file.
+func WriteFile(path string, data []byte, perm os.FileMode) (err error) {
+	dir := filepath.Dir(path)
+	if err = os.MkdirAll(dir, 0755); err != nil {
+		return
+	}
+	temp, err := ioutil.TempFile(dir, "."+filepath.Base(path)+".tmp-")
+	if err != nil {
+		return
+	}
+	defer func() {
+		if err != nil {
+			os.Remove(temp.Name())
+		}
+	}()
+	if _, err = temp.Write(data); err != nil {
+		temp.Close()
+		return
+	}
+	if err = temp.Sync(); err != nil {
+		temp.Close()
+		return
+	}
+	if err = temp.Close(); err != nil {
+		return
+	}
+	if err = os.Chmod(temp.Name(), perm); err != nil {
+		return
+	}
+	return os.Rename(temp.Name(), path)
+}
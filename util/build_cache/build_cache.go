@@ -0,0 +1,123 @@
+package build_cache
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"github.com/gbdubs/ecology/util/atomic_file"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+const artifactSuffix = ".zip"
+
+// Cache holds built lambda zips, named by the hash of everything that went
+// into building them, so that the same code is only built once, whichever
+// branch, project or region it is pushed from.
+type Cache struct {
+	Dir string
+}
+
+// Entry is an artifact in the cache.
+type Entry struct {
+	Key      string
+	Size     int64
+	LastUsed time.Time
+}
+
+func New(dir string) *Cache {
+	return &Cache{Dir: dir}
+}
+
+// Key combines the parts that identify a build into a cache key.
+func Key(parts ...string) string {
+	sum := sha256.Sum256([]byte(strings.Join(parts, "\n")))
+	return hex.EncodeToString(sum[:])
+}
+
+func (c *Cache) path(key string) string {
+	return filepath.Join(c.Dir, key[:2], key+artifactSuffix)
+}
+
+// Get returns the artifact stored under key, if there is one, and marks it as
+// used so that Prune keeps it longer.
+func (c *Cache) Get(key string) (data []byte, ok bool) {
+	path := c.path(key)
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, false
+	}
+	now := time.Now()
+	os.Chtimes(path, now, now)
+	return data, true
+}
+
+func (c *Cache) Put(key string, data []byte) error {
+	return atomic_file.WriteFile(c.path(key), data, 0644)
+}
+
+// TempDir makes a directory to build in, next to the artifacts so that it is
+// out of the source tree. The caller removes it.
+func (c *Cache) TempDir() (string, error) {
+	if err := os.MkdirAll(c.Dir, 0755); err != nil {
+		return "", err
+	}
+	return ioutil.TempDir(c.Dir, ".build-")
+}
+
+// Entries lists the artifacts in the cache, least recently used first.
+func (c *Cache) Entries() (entries []Entry, err error) {
+	entries = make([]Entry, 0)
+	paths, err := filepath.Glob(filepath.Join(c.Dir, "*", "*"+artifactSuffix))
+	if err != nil {
+		return
+	}
+	for _, path := range paths {
+		info, statErr := os.Stat(path)
+		if statErr != nil {
+			continue
+		}
+		entries = append(entries, Entry{
+			Key:      strings.TrimSuffix(filepath.Base(path), artifactSuffix),
+			Size:     info.Size(),
+			LastUsed: info.ModTime(),
+		})
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].LastUsed.Before(entries[j].LastUsed)
+	})
+	return
+}
+
+// Prune removes artifacts that haven't been used for maxAge, and then the
+// least recently used ones until the cache is no bigger than maxBytes. A zero
+// maxAge or maxBytes doesn't limit the cache by that measure.
+func (c *Cache) Prune(maxAge time.Duration, maxBytes int64) (removed []Entry, err error) {
+	removed = make([]Entry, 0)
+	entries, err := c.Entries()
+	if err != nil {
+		return
+	}
+	var total int64
+	for _, e := range entries {
+		total += e.Size
+	}
+	cutoff := time.Now().Add(-maxAge)
+	for _, e := range entries {
+		tooOld := maxAge > 0 && e.LastUsed.Before(cutoff)
+		tooBig := maxBytes > 0 && total > maxBytes
+		if !tooOld && !tooBig {
+			continue
+		}
+		if err = os.Remove(c.path(e.Key)); err != nil && !os.IsNotExist(err) {
+			return
+		}
+		err = nil
+		total -= e.Size
+		removed = append(removed, e)
+	}
+	return
+}
@@ -0,0 +1,129 @@
+package cloud_function_source
+
+import (
+	"errors"
+	"fmt"
+	"github.com/gbdubs/ecology/util/deploy_zip"
+	"go/parser"
+	"go/token"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+// EntryPoint is the function that Cloud Functions call, which the wrapper
+// defines.
+const EntryPoint = "EcologyHandleRequest"
+
+// Cloud Functions build the package at the root of the module, which can't be
+// main.
+const packageName = "function"
+
+// The Go version of the go.mod written for lambdas that aren't in a module,
+// which is the oldest that Cloud Functions run and that has generics.
+const goVersion = "1.21"
+
+const wrapperName = "ecology_entry_point.go"
+
+const wrapper = `package ` + packageName + `
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+)
+
+// ` + EntryPoint + ` serves HTTP requests with the lambda's HandleRequest,
+// decoding the request body as its request, and encoding its response as JSON.
+func ` + EntryPoint + `(w http.ResponseWriter, r *http.Request) {
+	serve(w, r, HandleRequest)
+}
+
+func serve[Request any, Response any](w http.ResponseWriter, r *http.Request, handle func(context.Context, Request) (Response, error)) {
+	var request Request
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil && err != io.EOF {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	response, err := handle(r.Context(), request)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+`
+
+// Files returns the source of a Cloud Function that runs the lambda whose
+// source is at codePath. The lambda's package becomes package function, next
+// to a wrapper that serves HTTP requests with its HandleRequest, at the root
+// of a module. The module has the go.mod and go.sum of the lambda's own module,
+// so that its dependencies keep their versions, or a go.mod of its own if the
+// lambda isn't in a module.
+func Files(codePath string) (files []deploy_zip.File, err error) {
+	source, err := ioutil.ReadFile(codePath)
+	if err != nil {
+		return
+	}
+	if source, err = renamePackage(codePath, source); err != nil {
+		return
+	}
+	files = []deploy_zip.File{
+		{Path: codePath, Contents: source},
+		{Name: wrapperName, Contents: []byte(wrapper)},
+	}
+	goMod, err := findGoMod(filepath.Dir(codePath))
+	if err != nil {
+		return
+	}
+	if goMod == "" {
+		contents := fmt.Sprintf("module %s\n\ngo %s\n", packageName, goVersion)
+		return append(files, deploy_zip.File{Name: "go.mod", Contents: []byte(contents)}), nil
+	}
+	files = append(files, deploy_zip.File{Path: goMod})
+	goSum := filepath.Join(filepath.Dir(goMod), "go.sum")
+	if _, err = os.Stat(goSum); err == nil {
+		files = append(files, deploy_zip.File{Path: goSum})
+	} else if os.IsNotExist(err) {
+		err = nil
+	}
+	return
+}
+
+// renamePackage moves the source into package function, whatever package it
+// was in.
+func renamePackage(codePath string, source []byte) ([]byte, error) {
+	parsed, err := parser.ParseFile(token.NewFileSet(), codePath, source, parser.PackageClauseOnly)
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("Couldn't read the package of %s: %v", codePath, err))
+	}
+	start := int(parsed.Name.Pos()) - 1
+	end := int(parsed.Name.End()) - 1
+	renamed := append([]byte{}, source[:start]...)
+	renamed = append(renamed, packageName...)
+	return append(renamed, source[end:]...), nil
+}
+
+// findGoMod finds the go.mod of the module that dir is in, or "" if it isn't
+// in one.
+func findGoMod(dir string) (string, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return "", err
+	}
+	for {
+		goMod := filepath.Join(dir, "go.mod")
+		if _, err = os.Stat(goMod); err == nil {
+			return goMod, nil
+		} else if !os.IsNotExist(err) {
+			return "", err
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", nil
+		}
+		dir = parent
+	}
+}
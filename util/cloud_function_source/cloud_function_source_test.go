@@ -0,0 +1,159 @@
+package cloud_function_source
+
+import (
+	"github.com/gbdubs/ecology/util/deploy_zip"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const lambdaSource = `// A lambda, as create_lambda writes it, without the AWS dependency.
+package main
+
+import (
+	"context"
+	"errors"
+)
+
+type ARequest struct {
+	Input string
+}
+
+func HandleRequest(ctx context.Context, request ARequest) (string, error) {
+	if request.Input == "fail" {
+		return "", errors.New("failed")
+	}
+	return "This is the lambda A! request.Input=" + request.Input, nil
+}
+
+func main() {
+}
+`
+
+// Served through the wrapper, as Cloud Functions would serve it.
+const entryPointTest = `package function
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestEntryPoint(t *testing.T) {
+	tests := []struct {
+		body   string
+		status int
+		want   string
+	}{
+		{"{\"Input\":\"hi\"}", http.StatusOK, "\"This is the lambda A! request.Input=hi\"\n"},
+		{"", http.StatusOK, "\"This is the lambda A! request.Input=\"\n"},
+		{"{\"Input\":\"fail\"}", http.StatusInternalServerError, "failed\n"},
+		{"not json", http.StatusBadRequest, ""},
+	}
+	for _, test := range tests {
+		w := httptest.NewRecorder()
+		EcologyHandleRequest(w, httptest.NewRequest("POST", "/", strings.NewReader(test.body)))
+		if w.Code != test.status || (test.want != "" && w.Body.String() != test.want) {
+			t.Errorf("%q: got %d %q, want %d %q", test.body, w.Code, w.Body.String(), test.status, test.want)
+		}
+	}
+}
+`
+
+func writeFile(t *testing.T, path string, contents string) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(path, []byte(contents), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func nameInZip(f deploy_zip.File) string {
+	if f.Name == "" {
+		return filepath.Base(f.Path)
+	}
+	return f.Name
+}
+
+// unpack writes the files where Cloud Functions would unzip them.
+func unpack(t *testing.T, files []deploy_zip.File) string {
+	dir := t.TempDir()
+	for _, f := range files {
+		contents := f.Contents
+		if contents == nil {
+			var err error
+			if contents, err = ioutil.ReadFile(f.Path); err != nil {
+				t.Fatal(err)
+			}
+		}
+		writeFile(t, filepath.Join(dir, nameInZip(f)), string(contents))
+	}
+	return dir
+}
+
+func goCommand(t *testing.T, dir string, args ...string) {
+	cmd := exec.Command("go", args...)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), "GOFLAGS=-mod=mod", "GOPROXY=off", "GOTOOLCHAIN=local")
+	if result, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("go %s: %v\n%s", strings.Join(args, " "), err, result)
+	}
+}
+
+func TestFilesServeTheLambda(t *testing.T) {
+	project := t.TempDir()
+	writeFile(t, filepath.Join(project, "go.mod"), "module example.com/project\n\ngo 1.21\n")
+	writeFile(t, filepath.Join(project, "go.sum"), "")
+	codePath := filepath.Join(project, "lambda", "A", "A.go")
+	writeFile(t, codePath, lambdaSource)
+
+	files, err := Files(codePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	names := make([]string, 0)
+	for _, f := range files {
+		names = append(names, nameInZip(f))
+	}
+	if got := strings.Join(names, ","); got != "A.go,"+wrapperName+",go.mod,go.sum" {
+		t.Fatalf("Files() = %s", got)
+	}
+	dir := unpack(t, files)
+	source, _ := ioutil.ReadFile(filepath.Join(dir, "A.go"))
+	if !strings.HasPrefix(string(source), "// A lambda, as create_lambda writes it, without the AWS dependency.\npackage function\n") {
+		t.Fatalf("source wasn't moved to package function:\n%s", source)
+	}
+	writeFile(t, filepath.Join(dir, "entry_point_test.go"), entryPointTest)
+	goCommand(t, dir, "test", ".")
+}
+
+func TestFilesWriteGoModOutsideModules(t *testing.T) {
+	codePath := filepath.Join(t.TempDir(), "A.go")
+	writeFile(t, codePath, lambdaSource)
+	if goMod, _ := findGoMod(filepath.Dir(codePath)); goMod != "" {
+		t.Skipf("the temporary directory is in the module at %s", goMod)
+	}
+	files, err := Files(codePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := unpack(t, files)
+	goMod, _ := ioutil.ReadFile(filepath.Join(dir, "go.mod"))
+	if string(goMod) != "module function\n\ngo 1.21\n" {
+		t.Fatalf("go.mod = %q", goMod)
+	}
+	goCommand(t, dir, "vet", ".")
+}
+
+func TestFilesRejectsUnparsableSource(t *testing.T) {
+	codePath := filepath.Join(t.TempDir(), "A.go")
+	writeFile(t, codePath, "func main() {}\n")
+	if _, err := Files(codePath); err == nil || !strings.Contains(err.Error(), "Couldn't read the package") {
+		t.Fatalf("Files() = %v", err)
+	}
+}
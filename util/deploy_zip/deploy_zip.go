@@ -0,0 +1,81 @@
+package deploy_zip
+
+import (
+	"archive/zip"
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// modified is the timestamp of every entry. It is the earliest time a zip can
+// hold, so that archives only differ when their contents do.
+var modified = time.Date(1980, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// File is a file to put in the root of a deployment zip.
+type File struct {
+	// The path of the file to read.
+	Path string
+	// The contents of the file, which are read from Path if nil.
+	Contents []byte
+	// The name of the file inside the zip, which defaults to the base of Path.
+	Name string
+	// Executables, such as a lambda's binary, need to be marked as such.
+	Executable bool
+}
+
+// Create zips the files in name order, with fixed timestamps and permissions,
+// so that the same files always give byte-identical zips.
+func Create(files []File) (zipped []byte, err error) {
+	sorted := make([]File, len(files))
+	copy(sorted, files)
+	for i := range sorted {
+		if sorted[i].Name == "" {
+			sorted[i].Name = filepath.Base(sorted[i].Path)
+		}
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].Name < sorted[j].Name
+	})
+	var buffer bytes.Buffer
+	writer := zip.NewWriter(&buffer)
+	for _, f := range sorted {
+		data := f.Contents
+		if data == nil {
+			if data, err = ioutil.ReadFile(f.Path); err != nil {
+				return nil, err
+			}
+		}
+		header := &zip.FileHeader{
+			Name:     f.Name,
+			Method:   zip.Deflate,
+			Modified: modified,
+		}
+		if f.Executable {
+			header.SetMode(0755)
+		} else {
+			header.SetMode(0644)
+		}
+		entry, err := writer.CreateHeader(header)
+		if err != nil {
+			return nil, err
+		}
+		if _, err = entry.Write(data); err != nil {
+			return nil, err
+		}
+	}
+	if err = writer.Close(); err != nil {
+		return
+	}
+	return buffer.Bytes(), nil
+}
+
+// CodeSha256 hashes a zip the way AWS Lambda reports the CodeSha256 of the
+// package it is running, so the two can be compared.
+func CodeSha256(zipped []byte) string {
+	sum := sha256.Sum256(zipped)
+	return base64.StdEncoding.EncodeToString(sum[:])
+}
@@ -0,0 +1,93 @@
+package deploy_zip
+
+import (
+	"archive/zip"
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// writeFiles writes a bootstrap and a config file to a new directory, with the
+// given modification time and permissions.
+func writeFiles(t *testing.T, mtime time.Time, perm os.FileMode) []File {
+	t.Helper()
+	dir := t.TempDir()
+	contents := map[string]string{
+		"bootstrap":   "#!/bin/sh\necho hello\n",
+		"config.json": "{\"Greeting\": \"hello\"}\n",
+	}
+	files := make([]File, 0)
+	for _, name := range []string{"config.json", "bootstrap"} {
+		path := filepath.Join(dir, name)
+		if err := ioutil.WriteFile(path, []byte(contents[name]), perm); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chmod(path, perm); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(path, mtime, mtime); err != nil {
+			t.Fatal(err)
+		}
+		files = append(files, File{Path: path, Executable: name == "bootstrap"})
+	}
+	return files
+}
+
+func TestCreateIsReproducible(t *testing.T) {
+	first, err := Create(writeFiles(t, time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC), 0600))
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := Create(writeFiles(t, time.Now(), 0777))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(first, second) {
+		t.Fatal("zips of the same files with different mtimes and modes differ")
+	}
+	if CodeSha256(first) != CodeSha256(second) {
+		t.Errorf("CodeSha256 %s != %s", CodeSha256(first), CodeSha256(second))
+	}
+}
+
+func TestCreate(t *testing.T) {
+	zipped, err := Create(append(writeFiles(t, time.Now(), 0644), File{Name: "generated.txt", Contents: []byte("generated")}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	reader, err := zip.NewReader(bytes.NewReader(zipped), int64(len(zipped)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []struct {
+		name string
+		mode os.FileMode
+	}{
+		{"bootstrap", 0755},
+		{"config.json", 0644},
+		{"generated.txt", 0644},
+	}
+	if len(reader.File) != len(want) {
+		t.Fatalf("zip has %d files, want %d", len(reader.File), len(want))
+	}
+	for i, f := range reader.File {
+		if f.Name != want[i].name || f.Mode().Perm() != want[i].mode {
+			t.Errorf("file %d is %s with mode %v, want %s with mode %v", i, f.Name, f.Mode().Perm(), want[i].name, want[i].mode)
+		}
+		if !f.Modified.Equal(modified) {
+			t.Errorf("%s was modified at %v, want %v", f.Name, f.Modified, modified)
+		}
+	}
+}
+
+// Lambda reports CodeSha256 as the base64 of the SHA-256 of the zip, as
+// `openssl dgst -sha256 -binary | base64` prints it.
+func TestCodeSha256(t *testing.T) {
+	want := "SnD+mqZDbgLC3qNA+9HjUuTvLYzmylKtJdS5VHH8i/I="
+	if got := CodeSha256([]byte("zip")); got != want {
+		t.Errorf("CodeSha256 = %s, want %s", got, want)
+	}
+}
@@ -0,0 +1,111 @@
+package ecology_home
+
+import (
+	"errors"
+	"fmt"
+	"github.com/gbdubs/ecology/util/atomic_file"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+const EnvironmentVariable = "ECOLOGY_HOME"
+
+const DefaultWorkspace = "default"
+
+const manifestFileName = "ecology.json"
+const workspaceFileName = "workspace"
+const workspacesDirName = "workspaces"
+const cacheDirName = "cache"
+
+var workspaceNameRegex = regexp.MustCompile("^[a-zA-Z0-9_-]+$")
+
+// Resolve finds the ecology home directory, from the --ecology_home flag, then
+// the ECOLOGY_HOME environment variable, then the XDG config directory. A
+// ~/.ecology directory from older versions of ecology is still used if the
+// XDG one hasn't been created.
+func Resolve(flagValue string) (string, error) {
+	if flagValue != "" {
+		return filepath.Abs(flagValue)
+	}
+	if env := os.Getenv(EnvironmentVariable); env != "" {
+		return filepath.Abs(env)
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", errors.New(fmt.Sprintf("Couldn't find a home directory, set --ecology_home or %s: %v", EnvironmentVariable, err))
+	}
+	configDir := os.Getenv("XDG_CONFIG_HOME")
+	if configDir == "" {
+		configDir = filepath.Join(home, ".config")
+	}
+	xdgHome := filepath.Join(configDir, "ecology")
+	legacyHome := filepath.Join(home, ".ecology")
+	if !exists(xdgHome) && exists(filepath.Join(legacyHome, manifestFileName)) {
+		return legacyHome, nil
+	}
+	return xdgHome, nil
+}
+
+// ManifestPath is where the ecology manifest of a workspace lives. The default
+// workspace lives directly in the ecology home, so that homes created before
+// workspaces existed keep working.
+func ManifestPath(home string, workspace string) string {
+	if workspace == DefaultWorkspace {
+		return filepath.Join(home, manifestFileName)
+	}
+	return filepath.Join(home, workspacesDirName, workspace, manifestFileName)
+}
+
+// CacheDir holds build artifacts, which are shared by every workspace since
+// they are named by their contents.
+func CacheDir(home string) string {
+	return filepath.Join(home, cacheDirName)
+}
+
+// CurrentWorkspace returns the workspace last chosen with UseWorkspace.
+func CurrentWorkspace(home string) string {
+	data, err := ioutil.ReadFile(filepath.Join(home, workspaceFileName))
+	if err != nil {
+		return DefaultWorkspace
+	}
+	if workspace := strings.TrimSpace(string(data)); workspace != "" {
+		return workspace
+	}
+	return DefaultWorkspace
+}
+
+func UseWorkspace(home string, workspace string) error {
+	if err := ValidateWorkspace(workspace); err != nil {
+		return err
+	}
+	return atomic_file.WriteFile(filepath.Join(home, workspaceFileName), []byte(workspace+"\n"), 0644)
+}
+
+// Workspaces lists the workspaces that have been saved in the ecology home.
+func Workspaces(home string) (workspaces []string) {
+	workspaces = append(workspaces, DefaultWorkspace)
+	dirs, _ := ioutil.ReadDir(filepath.Join(home, workspacesDirName))
+	for _, d := range dirs {
+		if d.IsDir() && exists(ManifestPath(home, d.Name())) {
+			workspaces = append(workspaces, d.Name())
+		}
+	}
+	sort.Strings(workspaces[1:])
+	return
+}
+
+func ValidateWorkspace(workspace string) error {
+	if !workspaceNameRegex.MatchString(workspace) {
+		return errors.New(fmt.Sprintf("Workspace name %q should only contain letters, numbers, dashes and underscores", workspace))
+	}
+	return nil
+}
+
+func exists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
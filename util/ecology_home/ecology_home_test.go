@@ -0,0 +1,61 @@
+package ecology_home
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestResolve(t *testing.T) {
+	userHome := t.TempDir()
+	configDir := t.TempDir()
+	env := t.TempDir()
+	flag := t.TempDir()
+	t.Setenv("HOME", userHome)
+	t.Setenv("XDG_CONFIG_HOME", configDir)
+
+	t.Setenv(EnvironmentVariable, env)
+	if home, err := Resolve(flag); err != nil || home != flag {
+		t.Errorf("Resolve(flag) = %s, %v, want the flag %s", home, err, flag)
+	}
+	if home, err := Resolve(""); err != nil || home != env {
+		t.Errorf("Resolve(\"\") = %s, %v, want the environment variable %s", home, err, env)
+	}
+
+	t.Setenv(EnvironmentVariable, "")
+	xdgHome := filepath.Join(configDir, "ecology")
+	if home, err := Resolve(""); err != nil || home != xdgHome {
+		t.Errorf("Resolve(\"\") = %s, %v, want the XDG home %s", home, err, xdgHome)
+	}
+
+	// A home from older versions is kept until the XDG one is created.
+	legacyHome := filepath.Join(userHome, ".ecology")
+	if err := os.MkdirAll(legacyHome, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(legacyHome, manifestFileName), []byte("{}"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if home, err := Resolve(""); err != nil || home != legacyHome {
+		t.Errorf("Resolve(\"\") = %s, %v, want the legacy home %s", home, err, legacyHome)
+	}
+	if err := os.MkdirAll(xdgHome, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if home, err := Resolve(""); err != nil || home != xdgHome {
+		t.Errorf("Resolve(\"\") = %s, %v, want the XDG home %s once it exists", home, err, xdgHome)
+	}
+}
+
+func TestResolveMakesPathsAbsolute(t *testing.T) {
+	t.Setenv(EnvironmentVariable, "")
+	home, err := Resolve("relative")
+	if err != nil {
+		t.Fatal(err)
+	}
+	wd, _ := os.Getwd()
+	if home != filepath.Join(wd, "relative") {
+		t.Errorf("Resolve(relative) = %s", home)
+	}
+}
@@ -1,9 +1,18 @@
 package file_hash
 
 import (
+	"bytes"
 	"crypto/sha256"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
 )
 
 func ComputeFileHash(filePath string) (hashResult string, err error) {
@@ -16,3 +25,147 @@ func ComputeFileHash(filePath string) (hashResult string, err error) {
 	hashResult = fmt.Sprintf("%x", hashFn.Sum(nil))
 	return
 }
+
+// listedPackage is the part of `go list -json` output that affects a build.
+type listedPackage struct {
+	Dir        string
+	ImportPath string
+	Standard   bool
+	Module     *listedModule
+	GoFiles    []string
+	CgoFiles   []string
+	CFiles     []string
+	CXXFiles   []string
+	HFiles     []string
+	SFiles     []string
+	SysoFiles  []string
+	EmbedFiles []string
+}
+
+type listedModule struct {
+	Path    string
+	Version string
+	Sum     string
+	GoMod   string
+	Main    bool
+	Replace *listedModule
+}
+
+// ComputeBuildInputsHash hashes everything that goes into building the Go
+// target from dir with the given extra environment: the Go toolchain version,
+// the build environment, every local source file of the target and its
+// transitive dependencies, the versions and sums of dependency modules, and
+// the main module's go.mod and go.sum. Paths are hashed relative to dir, so
+// that moving a project doesn't change it.
+func ComputeBuildInputsHash(dir string, target string, env []string) (hashResult string, err error) {
+	if dir, err = filepath.Abs(dir); err != nil {
+		return
+	}
+	goVersion, err := goCommand(dir, env, "env", "GOVERSION")
+	if err != nil {
+		return
+	}
+	listed, err := goCommand(dir, env, "list", "-deps", "-json", target)
+	if err != nil {
+		return
+	}
+	inputs := []string{"go " + strings.TrimSpace(string(goVersion))}
+	for _, e := range env {
+		inputs = append(inputs, "env "+e)
+	}
+	seen := make(map[string]bool)
+	addFile := func(path string) error {
+		if seen[path] {
+			return nil
+		}
+		seen[path] = true
+		fileHash, err := ComputeFileHash(path)
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			rel = path
+		}
+		inputs = append(inputs, fmt.Sprintf("file %s %s", filepath.ToSlash(rel), fileHash))
+		return nil
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(listed))
+	for {
+		pkg := listedPackage{}
+		if err = decoder.Decode(&pkg); err == io.EOF {
+			err = nil
+			break
+		} else if err != nil {
+			return
+		}
+		if pkg.Standard {
+			continue
+		}
+		if m := versionedModule(pkg.Module); m != nil {
+			// Downloaded modules are immutable, so their version and sum stand
+			// in for their files.
+			key := "module " + m.Path + " " + m.Version + " " + m.Sum
+			if !seen[key] {
+				seen[key] = true
+				inputs = append(inputs, key)
+			}
+			continue
+		}
+		if pkg.Module != nil && pkg.Module.Main && pkg.Module.GoMod != "" {
+			if err = addFile(pkg.Module.GoMod); err != nil {
+				return
+			}
+			goSum := filepath.Join(filepath.Dir(pkg.Module.GoMod), "go.sum")
+			if _, statErr := os.Stat(goSum); statErr == nil {
+				if err = addFile(goSum); err != nil {
+					return
+				}
+			}
+		}
+		for _, files := range [][]string{pkg.GoFiles, pkg.CgoFiles, pkg.CFiles, pkg.CXXFiles, pkg.HFiles, pkg.SFiles, pkg.SysoFiles, pkg.EmbedFiles} {
+			for _, f := range files {
+				if err = addFile(filepath.Join(pkg.Dir, f)); err != nil {
+					return
+				}
+			}
+		}
+	}
+
+	sort.Strings(inputs)
+	hashFn := sha256.New()
+	for _, input := range inputs {
+		hashFn.Write([]byte(input + "\n"))
+	}
+	hashResult = fmt.Sprintf("%x", hashFn.Sum(nil))
+	return
+}
+
+// versionedModule returns the downloaded module that a package comes from, or
+// nil if the package comes from local files.
+func versionedModule(m *listedModule) *listedModule {
+	if m == nil || m.Main {
+		return nil
+	}
+	if m.Replace != nil {
+		m = m.Replace
+	}
+	if m.Version == "" {
+		return nil
+	}
+	return m
+}
+
+func goCommand(dir string, env []string, args ...string) ([]byte, error) {
+	cmd := exec.Command("go", args...)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), env...)
+	stderr := bytes.Buffer{}
+	cmd.Stderr = &stderr
+	result, err := cmd.Output()
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("go %s failed: %v\n%s", strings.Join(args, " "), err, stderr.String()))
+	}
+	return result, nil
+}
@@ -0,0 +1,93 @@
+package file_hash
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+var env = []string{"GOOS=linux", "GOARCH=amd64", "CGO_ENABLED=0", "GOFLAGS=-mod=mod", "GOWORK=off"}
+
+// writeProject writes a module whose lambda imports a package of the module.
+func writeProject(t *testing.T, dir string) {
+	t.Helper()
+	files := map[string]string{
+		"go.mod":         "module example.com/project\n\ngo 1.21\n",
+		"go.sum":         "",
+		"lib/lib.go":     "package lib\n\nfunc Greeting() string { return \"hello\" }\n",
+		"lambda/main.go": "package main\n\nimport \"example.com/project/lib\"\n\nfunc main() { println(lib.Greeting()) }\n",
+	}
+	for name, contents := range files {
+		writeFile(t, filepath.Join(dir, name), contents)
+	}
+}
+
+func writeFile(t *testing.T, path string, contents string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(path, []byte(contents), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func hash(t *testing.T, dir string) string {
+	t.Helper()
+	h, err := ComputeBuildInputsHash(filepath.Join(dir, "lambda"), ".", env)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return h
+}
+
+func TestComputeBuildInputsHashChangesWithInputs(t *testing.T) {
+	dir := t.TempDir()
+	writeProject(t, dir)
+	changes := []struct {
+		name     string
+		file     string
+		contents string
+	}{
+		{"imported package", "lib/lib.go", "package lib\n\nfunc Greeting() string { return \"goodbye\" }\n"},
+		{"go.mod", "go.mod", "module example.com/project\n\ngo 1.22\n"},
+		{"go.sum", "go.sum", "example.com/other v1.0.0/go.mod h1:47DEQpj8HBSa+/TImW+5JCeuQeRkm5NMpJWZG3hSuFU=\n"},
+	}
+	previous := hash(t, dir)
+	if again := hash(t, dir); again != previous {
+		t.Fatalf("hash changed from %s to %s without any change", previous, again)
+	}
+	for _, change := range changes {
+		writeFile(t, filepath.Join(dir, change.file), change.contents)
+		h := hash(t, dir)
+		if h == previous {
+			t.Errorf("changing the %s didn't change the hash", change.name)
+		}
+		previous = h
+	}
+}
+
+func TestComputeBuildInputsHashIgnoresUnusedFiles(t *testing.T) {
+	dir := t.TempDir()
+	writeProject(t, dir)
+	before := hash(t, dir)
+	writeFile(t, filepath.Join(dir, "unused", "unused.go"), "package unused\n")
+	writeFile(t, filepath.Join(dir, "README.md"), "# project\n")
+	if after := hash(t, dir); after != before {
+		t.Errorf("files the lambda doesn't import changed the hash")
+	}
+}
+
+func TestComputeBuildInputsHashDoesntDependOnLocation(t *testing.T) {
+	dir := t.TempDir()
+	writeProject(t, dir)
+	before := hash(t, dir)
+	moved := filepath.Join(t.TempDir(), "moved")
+	if err := os.Rename(dir, moved); err != nil {
+		t.Fatal(err)
+	}
+	if after := hash(t, moved); after != before {
+		t.Errorf("moving the project changed the hash from %s to %s", before, after)
+	}
+}
@@ -3,19 +3,27 @@ package flag_validation
 import (
 	"errors"
 	"fmt"
+	"github.com/gbdubs/ecology/dns_providers/dns_provider_factory"
 	"github.com/gbdubs/ecology/manifests/ecology_manifest"
+	"github.com/gbdubs/ecology/manifests/lambda_manifest"
 	"github.com/gbdubs/ecology/manifests/project_manifest"
+	"github.com/gbdubs/ecology/util/secret_store"
 	"io/ioutil"
 	"regexp"
+	"strings"
 )
 
 const alphanumericRegex = "^[a-zA-Z0-9]+$"
 const alphanumericWithSlashesRegex = "^[a-zA-Z0-9/]+$"
+const domainRegex = "^([a-zA-Z0-9]([a-zA-Z0-9-]*[a-zA-Z0-9])?\\.)+[a-zA-Z]{2,}$"
 
 // Whether the given platform is currently supported
-var platforms = map[string]bool{"GCP": false, "AWS": true}
+var platforms = map[string]bool{"GCP": true, "AWS": true}
 
-var knownRegions = []string{"us-west-1", "us-west-2", "us-east-1", "us-east-2"}
+var knownRegions = map[string][]string{
+	"AWS": []string{"us-west-1", "us-west-2", "us-east-1", "us-east-2"},
+	"GCP": []string{"us-west1", "us-west2", "us-central1", "us-east1", "us-east4"},
+}
 
 func ValidateAll(errs ...error) error {
 	nonNilErrs := []error{}
@@ -43,23 +51,23 @@ func Platform(platform string) error {
 		return errors.New("--platform should be one of AWS or GCP")
 	}
 	if !platforms[platform] {
-		return errors.New("--platform=GCP is not yet supported")
+		return errors.New(fmt.Sprintf("--platform=%s is not yet supported", platform))
 	}
 	return nil
 }
 
-func Region(region string) error {
+func Region(platform string, region string) error {
 	if region == "" {
 		return errors.New("Must set --region")
 	}
 	found := false
-	for _, r := range knownRegions {
+	for _, r := range knownRegions[platform] {
 		if r == region {
 			found = true
 		}
 	}
 	if !found {
-		return errors.New("--region was not recognized")
+		return errors.New(fmt.Sprintf("--region was not recognized for --platform=%s", platform))
 	}
 	return nil
 }
@@ -101,7 +109,7 @@ func ProjectDoesNotExist(project string, em *ecology_manifest.EcologyManifest) e
     return nil
   }
 	if projectExists(project, em) {
-		return errors.New(fmt.Sprintf("--project=%s doesn't exist", project))
+		return errors.New(fmt.Sprintf("--project=%s already exists", project))
 	}
 	return nil
 }
@@ -111,6 +119,17 @@ func projectExists(project string, em *ecology_manifest.EcologyManifest) bool {
 	return err == nil
 }
 
+func NewProject(newProject string) error {
+	if newProject == "" {
+		return errors.New("Must set --new_project")
+	}
+	match, _ := regexp.MatchString(alphanumericRegex, newProject)
+	if !match {
+		return errors.New("--new_project can only contain alphanumeric characters")
+	}
+	return nil
+}
+
 func Lambda(lambda string) error {
 	if lambda == "" {
 		return errors.New("Must set --lambda")
@@ -122,6 +141,17 @@ func Lambda(lambda string) error {
 	return nil
 }
 
+func NewLambda(newLambda string) error {
+	if newLambda == "" {
+		return errors.New("Must set --new_lambda")
+	}
+	match, _ := regexp.MatchString(alphanumericRegex, newLambda)
+	if !match {
+		return errors.New("--new_lambda can only contain alphanumeric characters")
+	}
+	return nil
+}
+
 func LambdaExists(lambda string, pm *project_manifest.ProjectManifest) error {
 if     Lambda(lambda) != nil {
     return nil
@@ -152,3 +182,86 @@ func lambdaExists(lambda string, pm *project_manifest.ProjectManifest) bool {
 	_, err := pm.GetLambdaManifest(lambda)
 	return err == nil
 }
+
+func Domain(domain string) error {
+	if domain == "" {
+		return errors.New("Must set --domain")
+	}
+	match, _ := regexp.MatchString(domainRegex, domain)
+	if !match {
+		return errors.New("--domain should be a domain name like api.example.com")
+	}
+	return nil
+}
+
+func Registrar(registrar string) error {
+	if registrar == "" {
+		return errors.New("Must set --registrar")
+	}
+	for _, r := range dns_provider_factory.Registrars {
+		if r == registrar {
+			return nil
+		}
+	}
+	return errors.New(fmt.Sprintf("--registrar should be one of %s", strings.Join(dns_provider_factory.Registrars, ", ")))
+}
+
+func Snapshot(snapshot string) error {
+	if snapshot == "" {
+		return errors.New("Must set --snapshot, list them with `ecology history`")
+	}
+	return nil
+}
+
+func Parallelism(parallelism int) error {
+	if parallelism < 0 {
+		return errors.New("--parallelism can't be negative")
+	}
+	return nil
+}
+
+func MaxAgeDays(maxAgeDays int) error {
+	if maxAgeDays < 0 {
+		return errors.New("--max_age_days can't be negative")
+	}
+	return nil
+}
+
+func MaxSizeMb(maxSizeMb int) error {
+	if maxSizeMb < 0 {
+		return errors.New("--max_size_mb can't be negative")
+	}
+	return nil
+}
+
+func ToVersion(toVersion int) error {
+	if toVersion < 0 {
+		return errors.New("--to_version can't be negative")
+	}
+	return nil
+}
+
+func Runtime(runtime string) error {
+	for _, r := range lambda_manifest.Runtimes {
+		if r == runtime {
+			return nil
+		}
+	}
+	return errors.New(fmt.Sprintf("--runtime should be one of %s", strings.Join(lambda_manifest.Runtimes, ", ")))
+}
+
+func Architecture(architecture string) error {
+	for _, a := range lambda_manifest.Architectures {
+		if a == architecture {
+			return nil
+		}
+	}
+	return errors.New(fmt.Sprintf("--architecture should be one of %s", strings.Join(lambda_manifest.Architectures, ", ")))
+}
+
+func SecretName(name string) error {
+	if name == "" {
+		return errors.New("Must give the name of the secret, e.g. `ecology secret get --project=P NAME`")
+	}
+	return secret_store.ValidateName(name)
+}
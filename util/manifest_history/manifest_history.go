@@ -0,0 +1,78 @@
+package manifest_history
+
+import (
+	"errors"
+	"fmt"
+	"github.com/gbdubs/ecology/util/atomic_file"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Keep is how many snapshots are kept in each history directory.
+const Keep = 20
+
+const snapshotIdFormat = "20060102T150405.000000000Z"
+const snapshotSuffix = ".json"
+
+type Snapshot struct {
+	Id   string
+	Time time.Time
+	Size int64
+}
+
+// Record saves the contents of a manifest as a new snapshot, and prunes the
+// oldest snapshots beyond Keep.
+func Record(historyDir string, data []byte) error {
+	id := time.Now().UTC().Format(snapshotIdFormat)
+	if err := atomic_file.WriteFile(filepath.Join(historyDir, id+snapshotSuffix), data, 0644); err != nil {
+		return err
+	}
+	snapshots, err := List(historyDir)
+	if err != nil {
+		return err
+	}
+	for i := Keep; i < len(snapshots); i++ {
+		if err = os.Remove(filepath.Join(historyDir, snapshots[i].Id+snapshotSuffix)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// List returns the snapshots in the history directory, newest first.
+func List(historyDir string) (snapshots []Snapshot, err error) {
+	files, err := ioutil.ReadDir(historyDir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return
+	}
+	for _, f := range files {
+		id := strings.TrimSuffix(f.Name(), snapshotSuffix)
+		t, parseErr := time.Parse(snapshotIdFormat, id)
+		if f.IsDir() || !strings.HasSuffix(f.Name(), snapshotSuffix) || parseErr != nil {
+			continue
+		}
+		snapshots = append(snapshots, Snapshot{Id: id, Time: t, Size: f.Size()})
+	}
+	sort.Slice(snapshots, func(i, j int) bool {
+		return snapshots[i].Id > snapshots[j].Id
+	})
+	return
+}
+
+// Read returns the contents of the snapshot with the given id.
+func Read(historyDir string, id string) ([]byte, error) {
+	if _, err := time.Parse(snapshotIdFormat, id); err != nil {
+		return nil, errors.New(fmt.Sprintf("%q is not a snapshot id, list them with `ecology history`", id))
+	}
+	data, err := ioutil.ReadFile(filepath.Join(historyDir, id+snapshotSuffix))
+	if os.IsNotExist(err) {
+		return nil, errors.New(fmt.Sprintf("No snapshot %s in %s", id, historyDir))
+	}
+	return data, err
+}
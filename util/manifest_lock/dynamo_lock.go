@@ -0,0 +1,93 @@
+package manifest_lock
+
+import (
+	"errors"
+	"fmt"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+	"strconv"
+	"time"
+)
+
+// DynamoLocker keeps locks as items in a DynamoDB table with a string hash
+// key named LockKey, so that a team sharing manifests can share locks too.
+// Locks expire after StaleAfter, in case their holder dies.
+type DynamoLocker struct {
+	Table        string
+	Timeout      time.Duration
+	StaleAfter   time.Duration
+	PollInterval time.Duration
+	svc          dynamodbiface.DynamoDBAPI
+}
+
+type dynamoLock struct {
+	locker *DynamoLocker
+	key    string
+	owner  string
+}
+
+// NewDynamoLocker builds a locker on the given table. The endpoint can point
+// at a local stand-in such as DynamoDB Local; leave it blank to use AWS.
+func NewDynamoLocker(table string, region string, endpoint string) *DynamoLocker {
+	config := aws.NewConfig().WithRegion(region)
+	if endpoint != "" {
+		config = config.WithEndpoint(endpoint)
+	}
+	return &DynamoLocker{
+		Table:        table,
+		Timeout:      defaultTimeout,
+		StaleAfter:   defaultStaleAfter,
+		PollInterval: defaultPollInterval,
+		svc:          dynamodb.New(session.New(), config),
+	}
+}
+
+func (dl *DynamoLocker) Lock(key string) (Lock, error) {
+	lockOwner := owner()
+	deadline := time.Now().Add(dl.Timeout)
+	for {
+		now := time.Now()
+		_, err := dl.svc.PutItem(&dynamodb.PutItemInput{
+			TableName: aws.String(dl.Table),
+			Item: map[string]*dynamodb.AttributeValue{
+				"LockKey":     {S: aws.String(key)},
+				"LockOwner":   {S: aws.String(lockOwner)},
+				"LockExpires": {N: aws.String(strconv.FormatInt(now.Add(dl.StaleAfter).Unix(), 10))},
+			},
+			ConditionExpression: aws.String("attribute_not_exists(LockKey) OR LockExpires < :now"),
+			ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+				":now": {N: aws.String(strconv.FormatInt(now.Unix(), 10))},
+			},
+		})
+		if err == nil {
+			return &dynamoLock{locker: dl, key: key, owner: lockOwner}, nil
+		}
+		if aerr, ok := err.(awserr.Error); !ok || aerr.Code() != dynamodb.ErrCodeConditionalCheckFailedException {
+			return nil, err
+		}
+		if time.Now().After(deadline) {
+			return nil, errors.New(fmt.Sprintf("Timed out waiting for lock %s in table %s", key, dl.Table))
+		}
+		time.Sleep(dl.PollInterval)
+	}
+}
+
+func (l *dynamoLock) Unlock() error {
+	_, err := l.locker.svc.DeleteItem(&dynamodb.DeleteItemInput{
+		TableName: aws.String(l.locker.Table),
+		Key: map[string]*dynamodb.AttributeValue{
+			"LockKey": {S: aws.String(l.key)},
+		},
+		ConditionExpression: aws.String("LockOwner = :owner"),
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":owner": {S: aws.String(l.owner)},
+		},
+	})
+	if aerr, ok := err.(awserr.Error); ok && aerr.Code() == dynamodb.ErrCodeConditionalCheckFailedException {
+		return errors.New(fmt.Sprintf("Lock %s expired and was taken over by another process", l.key))
+	}
+	return err
+}
@@ -0,0 +1,124 @@
+package manifest_lock
+
+import (
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+)
+
+// fakeDynamo stands in for DynamoDB, evaluating the two condition expressions
+// that DynamoLocker uses.
+type fakeDynamo struct {
+	dynamodbiface.DynamoDBAPI
+	mu    sync.Mutex
+	items map[string]map[string]*dynamodb.AttributeValue
+}
+
+func newFakeDynamo() *fakeDynamo {
+	return &fakeDynamo{items: make(map[string]map[string]*dynamodb.AttributeValue)}
+}
+
+func conditionFailed() error {
+	return awserr.New(dynamodb.ErrCodeConditionalCheckFailedException, "The conditional request failed", nil)
+}
+
+func (fd *fakeDynamo) PutItem(input *dynamodb.PutItemInput) (*dynamodb.PutItemOutput, error) {
+	fd.mu.Lock()
+	defer fd.mu.Unlock()
+	key := aws.StringValue(input.Item["LockKey"].S)
+	if existing, ok := fd.items[key]; ok {
+		expires, _ := strconv.ParseInt(aws.StringValue(existing["LockExpires"].N), 10, 64)
+		now, _ := strconv.ParseInt(aws.StringValue(input.ExpressionAttributeValues[":now"].N), 10, 64)
+		if expires >= now {
+			return nil, conditionFailed()
+		}
+	}
+	fd.items[key] = input.Item
+	return &dynamodb.PutItemOutput{}, nil
+}
+
+func (fd *fakeDynamo) DeleteItem(input *dynamodb.DeleteItemInput) (*dynamodb.DeleteItemOutput, error) {
+	fd.mu.Lock()
+	defer fd.mu.Unlock()
+	key := aws.StringValue(input.Key["LockKey"].S)
+	existing, ok := fd.items[key]
+	if !ok || aws.StringValue(existing["LockOwner"].S) != aws.StringValue(input.ExpressionAttributeValues[":owner"].S) {
+		return nil, conditionFailed()
+	}
+	delete(fd.items, key)
+	return &dynamodb.DeleteItemOutput{}, nil
+}
+
+func testDynamoLocker(svc dynamodbiface.DynamoDBAPI) *DynamoLocker {
+	return &DynamoLocker{
+		Table:        "locks",
+		Timeout:      100 * time.Millisecond,
+		StaleAfter:   time.Hour,
+		PollInterval: 5 * time.Millisecond,
+		svc:          svc,
+	}
+}
+
+func TestDynamoLockTimesOutWhileHeld(t *testing.T) {
+	dl := testDynamoLocker(newFakeDynamo())
+	held, err := dl.Lock("project")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = dl.Lock("project")
+	if err == nil || !strings.Contains(err.Error(), "Timed out waiting for lock project in table locks") {
+		t.Fatalf("Lock() = %v, want a timeout", err)
+	}
+	if err = held.Unlock(); err != nil {
+		t.Fatal(err)
+	}
+	again, err := dl.Lock("project")
+	if err != nil {
+		t.Fatalf("Lock() after Unlock() = %v", err)
+	}
+	again.Unlock()
+}
+
+func TestDynamoLockTakesOverExpiredLocks(t *testing.T) {
+	svc := newFakeDynamo()
+	stale := testDynamoLocker(svc)
+	stale.StaleAfter = -time.Minute
+	expired, err := stale.Lock("project")
+	if err != nil {
+		t.Fatal(err)
+	}
+	lock, err := testDynamoLocker(svc).Lock("project")
+	if err != nil {
+		t.Fatalf("Lock() of an expired lock = %v", err)
+	}
+	err = expired.Unlock()
+	if err == nil || !strings.Contains(err.Error(), "taken over") {
+		t.Fatalf("Unlock() of a lock taken over = %v, want a takeover error", err)
+	}
+	if err = lock.Unlock(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestDynamoLockReturnsOtherErrors(t *testing.T) {
+	dl := testDynamoLocker(&failingDynamo{})
+	_, err := dl.Lock("project")
+	if err == nil || !strings.Contains(err.Error(), dynamodb.ErrCodeResourceNotFoundException) {
+		t.Fatalf("Lock() = %v, want the table's error", err)
+	}
+}
+
+type failingDynamo struct {
+	dynamodbiface.DynamoDBAPI
+}
+
+func (fd *failingDynamo) PutItem(input *dynamodb.PutItemInput) (*dynamodb.PutItemOutput, error) {
+	return nil, awserr.New(dynamodb.ErrCodeResourceNotFoundException, "Requested resource not found", nil)
+}
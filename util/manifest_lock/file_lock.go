@@ -0,0 +1,176 @@
+package manifest_lock
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"syscall"
+	"time"
+)
+
+var unsafeLockNameChars = regexp.MustCompile("[^a-zA-Z0-9_-]+")
+
+// FileLocker keeps locks as files in a directory. A lock is considered stale,
+// and is broken, once its owning process has died or it is older than
+// StaleAfter. Lock files that can't be read, because their holder crashed
+// before writing them, are stale once they are older than StaleAfter.
+type FileLocker struct {
+	Dir          string
+	Timeout      time.Duration
+	StaleAfter   time.Duration
+	PollInterval time.Duration
+}
+
+type fileLockInfo struct {
+	Owner    string
+	Host     string
+	Pid      int
+	Acquired time.Time
+}
+
+type fileLock struct {
+	path  string
+	owner string
+}
+
+func NewFileLocker(dir string) *FileLocker {
+	return &FileLocker{
+		Dir:          dir,
+		Timeout:      defaultTimeout,
+		StaleAfter:   defaultStaleAfter,
+		PollInterval: defaultPollInterval,
+	}
+}
+
+func (fl *FileLocker) Lock(key string) (Lock, error) {
+	if err := os.MkdirAll(fl.Dir, 0755); err != nil {
+		return nil, err
+	}
+	path := filepath.Join(fl.Dir, unsafeLockNameChars.ReplaceAllString(key, "_")+".lock")
+	host, _ := os.Hostname()
+	info := fileLockInfo{
+		Owner:    owner(),
+		Host:     host,
+		Pid:      os.Getpid(),
+		Acquired: time.Now(),
+	}
+	contents, _ := json.Marshal(info)
+	deadline := time.Now().Add(fl.Timeout)
+	for {
+		file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+		if err == nil {
+			_, err = file.Write(contents)
+			file.Close()
+			if err != nil {
+				os.Remove(path)
+				return nil, err
+			}
+			return &fileLock{path: path, owner: info.Owner}, nil
+		}
+		if !os.IsExist(err) {
+			return nil, err
+		}
+		holder, stale, err := fl.checkStale(path)
+		if os.IsNotExist(err) {
+			// Released since we tried to take it.
+			continue
+		}
+		if stale {
+			if err = fl.breakStale(path, info.Owner); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		if time.Now().After(deadline) {
+			if err != nil {
+				return nil, errors.New(fmt.Sprintf("Timed out waiting for lock %s", path))
+			}
+			return nil, errors.New(fmt.Sprintf("Timed out waiting for lock %s, held by pid %d on %s since %s",
+				path, holder.Pid, holder.Host, holder.Acquired.Format(time.RFC3339)))
+		}
+		time.Sleep(fl.PollInterval)
+	}
+}
+
+// checkStale reads the lock at path, and whether it is stale.
+func (fl *FileLocker) checkStale(path string) (holder *fileLockInfo, stale bool, err error) {
+	holder, err = readFileLockInfo(path)
+	if err == nil {
+		return holder, fl.isStale(holder), nil
+	}
+	if os.IsNotExist(err) {
+		return nil, false, err
+	}
+	stat, statErr := os.Stat(path)
+	if statErr != nil {
+		return nil, false, statErr
+	}
+	return nil, time.Since(stat.ModTime()) > fl.StaleAfter, err
+}
+
+// breakStale removes a stale lock. It is first renamed out of the way, so that
+// of several waiters only one breaks it, and then checked again, since it may
+// have been released and taken afresh after it was found stale. A fresh lock
+// is put back, unless the lock has been taken once more in the meantime.
+func (fl *FileLocker) breakStale(path string, lockOwner string) error {
+	broken := path + "." + unsafeLockNameChars.ReplaceAllString(lockOwner, "_") + ".broken"
+	if err := os.Rename(path, broken); err != nil {
+		if os.IsNotExist(err) {
+			// Another waiter broke it first.
+			return nil
+		}
+		return err
+	}
+	if _, stale, _ := fl.checkStale(broken); !stale {
+		err := os.Link(broken, path)
+		os.Remove(broken)
+		if err != nil && !os.IsExist(err) {
+			return err
+		}
+		return nil
+	}
+	return os.Remove(broken)
+}
+
+func (fl *FileLocker) isStale(holder *fileLockInfo) bool {
+	if time.Since(holder.Acquired) > fl.StaleAfter {
+		return true
+	}
+	host, _ := os.Hostname()
+	return holder.Host == host && !processAlive(holder.Pid)
+}
+
+func (l *fileLock) Unlock() error {
+	// Don't remove a lock that was broken as stale and taken by someone else.
+	holder, err := readFileLockInfo(l.path)
+	if err != nil {
+		return err
+	}
+	if holder.Owner != l.owner {
+		return errors.New(fmt.Sprintf("Lock %s was taken over by pid %d on %s", l.path, holder.Pid, holder.Host))
+	}
+	return os.Remove(l.path)
+}
+
+func readFileLockInfo(path string) (*fileLockInfo, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	info := &fileLockInfo{}
+	err = json.Unmarshal(data, info)
+	return info, err
+}
+
+func processAlive(pid int) bool {
+	process, err := os.FindProcess(pid)
+	if err != nil {
+		return false
+	}
+	err = process.Signal(syscall.Signal(0))
+	return err == nil || errors.Is(err, syscall.EPERM)
+}
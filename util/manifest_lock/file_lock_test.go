@@ -0,0 +1,175 @@
+package manifest_lock
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func testFileLocker(t *testing.T) *FileLocker {
+	fl := NewFileLocker(t.TempDir())
+	fl.Timeout = 100 * time.Millisecond
+	fl.PollInterval = 5 * time.Millisecond
+	return fl
+}
+
+func writeLockFile(t *testing.T, fl *FileLocker, key string, info fileLockInfo) string {
+	path := filepath.Join(fl.Dir, key+".lock")
+	contents, err := json.Marshal(info)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = ioutil.WriteFile(path, contents, 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestFileLockTimesOutWhileHeld(t *testing.T) {
+	fl := testFileLocker(t)
+	held, err := fl.Lock("project")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = fl.Lock("project")
+	if err == nil || !strings.Contains(err.Error(), "Timed out waiting for lock") {
+		t.Fatalf("Lock() = %v, want a timeout", err)
+	}
+	if err = held.Unlock(); err != nil {
+		t.Fatal(err)
+	}
+	again, err := fl.Lock("project")
+	if err != nil {
+		t.Fatalf("Lock() after Unlock() = %v", err)
+	}
+	again.Unlock()
+}
+
+func TestFileLockBreaksStaleLocks(t *testing.T) {
+	host, _ := os.Hostname()
+	tests := []struct {
+		name string
+		info fileLockInfo
+	}{
+		{"expired", fileLockInfo{Owner: "other", Host: "elsewhere", Pid: 1, Acquired: time.Now().Add(-2 * time.Hour)}},
+		{"dead holder", fileLockInfo{Owner: "other", Host: host, Pid: 1 << 22, Acquired: time.Now()}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			fl := testFileLocker(t)
+			writeLockFile(t, fl, "project", test.info)
+			lock, err := fl.Lock("project")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err = lock.Unlock(); err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
+
+func TestFileLockKeepsLiveLocks(t *testing.T) {
+	fl := testFileLocker(t)
+	host, _ := os.Hostname()
+	writeLockFile(t, fl, "project", fileLockInfo{Owner: "other", Host: host, Pid: os.Getpid(), Acquired: time.Now()})
+	_, err := fl.Lock("project")
+	if err == nil || !strings.Contains(err.Error(), "held by pid") {
+		t.Fatalf("Lock() = %v, want a timeout naming the holder", err)
+	}
+}
+
+func TestFileLockBreaksOldUnreadableLocks(t *testing.T) {
+	fl := testFileLocker(t)
+	path := filepath.Join(fl.Dir, "project.lock")
+	if err := ioutil.WriteFile(path, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	// A fresh empty lock file may be about to be written by its holder.
+	if _, err := fl.Lock("project"); err == nil {
+		t.Fatal("Lock() broke a fresh unreadable lock")
+	}
+	old := time.Now().Add(-2 * fl.StaleAfter)
+	if err := os.Chtimes(path, old, old); err != nil {
+		t.Fatal(err)
+	}
+	lock, err := fl.Lock("project")
+	if err != nil {
+		t.Fatal(err)
+	}
+	lock.Unlock()
+}
+
+func TestFileLockStaleLockIsBrokenOnce(t *testing.T) {
+	fl := testFileLocker(t)
+	fl.Timeout = 10 * time.Second
+	writeLockFile(t, fl, "project", fileLockInfo{Owner: "other", Host: "elsewhere", Pid: 1, Acquired: time.Now().Add(-2 * time.Hour)})
+	var holders int32
+	var wg sync.WaitGroup
+	for i := 0; i < 32; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			lock, err := fl.Lock("project")
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			if n := atomic.AddInt32(&holders, 1); n != 1 {
+				t.Errorf("%d processes hold the lock at once", n)
+			}
+			time.Sleep(10 * time.Millisecond)
+			atomic.AddInt32(&holders, -1)
+			if err := lock.Unlock(); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+	files, _ := ioutil.ReadDir(fl.Dir)
+	if len(files) != 0 {
+		t.Fatalf("%d files left in the lock directory", len(files))
+	}
+}
+
+func TestFileLockLateBreakKeepsFreshLock(t *testing.T) {
+	fl := testFileLocker(t)
+	// Another waiter already broke the stale lock and took it afresh, after
+	// this one found it stale.
+	lock, err := fl.Lock("project")
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(fl.Dir, "project.lock")
+	if err = fl.breakStale(path, "late"); err != nil {
+		t.Fatal(err)
+	}
+	if err = lock.Unlock(); err != nil {
+		t.Fatalf("Unlock() of the fresh lock = %v", err)
+	}
+	if err = fl.breakStale(path, "late"); err != nil {
+		t.Fatalf("breakStale() of a released lock = %v", err)
+	}
+}
+
+func TestFileLockUnlockAfterTakeover(t *testing.T) {
+	fl := testFileLocker(t)
+	lock, err := fl.Lock("project")
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeLockFile(t, fl, "project", fileLockInfo{Owner: "other", Host: "elsewhere", Pid: 1, Acquired: time.Now()})
+	err = lock.Unlock()
+	if err == nil || !strings.Contains(err.Error(), "taken over") {
+		t.Fatalf("Unlock() = %v, want a takeover error", err)
+	}
+	if _, err = os.Stat(filepath.Join(fl.Dir, "project.lock")); err != nil {
+		t.Fatalf("Unlock() removed the new holder's lock: %v", err)
+	}
+}
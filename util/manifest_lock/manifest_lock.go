@@ -0,0 +1,31 @@
+package manifest_lock
+
+import (
+	"crypto/rand"
+	"fmt"
+	"os"
+	"time"
+)
+
+const defaultTimeout = 30 * time.Second
+const defaultStaleAfter = time.Hour
+const defaultPollInterval = 250 * time.Millisecond
+
+// Locker hands out advisory locks on manifests, so that concurrent ecology
+// processes don't silently overwrite each other's changes.
+type Locker interface {
+	// Lock blocks until the named lock is acquired, or the locker times out.
+	Lock(key string) (Lock, error)
+}
+
+type Lock interface {
+	Unlock() error
+}
+
+// owner identifies this process as the holder of a lock.
+func owner() string {
+	host, _ := os.Hostname()
+	nonce := make([]byte, 4)
+	rand.Read(nonce)
+	return fmt.Sprintf("%s:%d:%x", host, os.Getpid(), nonce)
+}
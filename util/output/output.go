@@ -1,31 +1,165 @@
 package output
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
 	"github.com/fatih/color"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
 )
 
+const TextFormat = "text"
+const JsonFormat = "json"
+const NdjsonFormat = "ndjson"
+
+var Formats = []string{TextFormat, JsonFormat, NdjsonFormat}
+
+// Levels, from most to least verbose. Lines below the output's level are
+// dropped, except from the log file, which gets everything.
+const (
+	DebugLevel = iota
+	InfoLevel
+	WarnLevel
+	ErrorLevel
+)
+
+var severities = map[string]int{
+	"debug":   DebugLevel,
+	"info":    InfoLevel,
+	"success": InfoLevel,
+	"warning": WarnLevel,
+	"failure": ErrorLevel,
+}
+
+// Event is a single line of output, as written in the JSON formats.
+type Event struct {
+	Time    time.Time
+	Level   string
+	Depth   int
+	Task    string `json:",omitempty"`
+	Message string
+	// The values that were formatted into the message.
+	Fields []interface{} `json:",omitempty"`
+}
+
+// Result is the final object written by a command in the JSON formats.
+type Result struct {
+	Time    time.Time
+	Level   string
+	Command string
+	Success bool
+	Error   string                 `json:",omitempty"`
+	Result  map[string]interface{} `json:",omitempty"`
+}
+
 type Output struct {
 	indentation int
 	testOnly    bool
+	// Set on the output of a task that runs concurrently with others.
+	task string
+	// Shared with every task's output.
+	sink *sink
+}
+
+// sink is where output ends up, guarded so that concurrent tasks write whole
+// lines and events.
+type sink struct {
+	mu      sync.Mutex
+	format  string
+	level   int
+	writer  io.Writer
+	logFile *os.File
+	events  []Event
+	result  map[string]interface{}
 }
 
 func New() *Output {
+	output, _ := NewWithFormat(TextFormat)
+	return output
+}
+
+// NewWithFormat returns an output that writes to stdout in one of Formats.
+// The JSON format holds every event until Finish writes them out together.
+func NewWithFormat(format string) (*Output, error) {
 	output := Output{
 		indentation: 0,
 		testOnly:    false,
+		sink:        newSink(TextFormat),
 	}
-	return &output
+	for _, f := range Formats {
+		if f == format {
+			output.sink.format = format
+			return &output, nil
+		}
+	}
+	return &output, errors.New(fmt.Sprintf("--output should be one of %s", strings.Join(Formats, ", ")))
 }
 
+// NewForTesting returns an output that writes nothing, but keeps every event
+// and result for tests to check.
 func NewForTesting() *Output {
 	output := Output{
 		indentation: 0,
 		testOnly:    true,
+		sink:        newSink(TextFormat),
 	}
 	return &output
 }
 
+func newSink(format string) *sink {
+	return &sink{
+		format: format,
+		level:  InfoLevel,
+		writer: os.Stdout,
+		events: make([]Event, 0),
+		result: make(map[string]interface{}),
+	}
+}
+
+// SetLevel drops lines below the level, such as WarnLevel for --quiet or
+// DebugLevel for --verbose.
+func (o *Output) SetLevel(level int) {
+	o.sink.level = level
+}
+
+// SetLogFile appends every line, whatever the level, to the file, so that
+// failures can be diagnosed after the fact. Finish closes it.
+func (o *Output) SetLogFile(path string) (err error) {
+	o.sink.logFile, err = os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+	return
+}
+
+// DisableColorUnlessTerminal turns off colors when NO_COLOR is set, or when
+// stdout isn't a terminal.
+func DisableColorUnlessTerminal() {
+	if os.Getenv("NO_COLOR") != "" {
+		color.NoColor = true
+		return
+	}
+	if info, err := os.Stdout.Stat(); err != nil || info.Mode()&os.ModeCharDevice == 0 {
+		color.NoColor = true
+	}
+}
+
+// Task returns an output for a task that runs concurrently with others, which
+// starts at the current indentation and labels each line with the task name.
+func (o *Output) Task(name string) *Output {
+	task := name
+	if o.task != "" {
+		task = o.task + "/" + name
+	}
+	return &Output{
+		indentation: o.indentation,
+		testOnly:    o.testOnly,
+		task:        task,
+		sink:        o.sink,
+	}
+}
+
 func (o *Output) Indent() *Output {
 	o.indentation = o.indentation + 1
 	return o
@@ -41,23 +175,22 @@ func (o *Output) Error(err error) {
 }
 
 func (o *Output) Failure(format string, a ...interface{}) *Output {
-	if !o.testOnly {
-		color.Red(o.indentFmt(format, a...))
-	}
+	o.print("failure", color.Red, format, a...)
 	return o
 }
 
 func (o *Output) Warning(format string, a ...interface{}) *Output {
-	if !o.testOnly {
-		color.Yellow(o.indentFmt(format, a...))
-	}
+	o.print("warning", color.Yellow, format, a...)
+	return o
+}
+
+func (o *Output) Debug(format string, a ...interface{}) *Output {
+	o.print("debug", color.White, format, a...)
 	return o
 }
 
 func (o *Output) Info(format string, a ...interface{}) *Output {
-	if !o.testOnly {
-		color.Cyan(o.indentFmt(format, a...))
-	}
+	o.print("info", color.Cyan, format, a...)
 	return o
 }
 
@@ -66,19 +199,164 @@ func (o *Output) Done() *Output {
 }
 
 func (o *Output) Success(format string, a ...interface{}) *Output {
-	if !o.testOnly {
-		color.Green(o.indentFmt(format, a...))
-	}
+	o.print("success", color.Green, format, a...)
 	return o
 }
 
+// Result adds a field to the final result object of the command, such as the
+// ARNs and hashes of what it deployed.
+func (o *Output) Result(key string, value interface{}) {
+	o.sink.mu.Lock()
+	defer o.sink.mu.Unlock()
+	o.sink.result[key] = value
+}
+
+// Reveal adds a value that mustn't be logged, such as a secret, to the result.
+// The text format, which has no result, writes it out on its own line.
+func (o *Output) Reveal(key string, value string) {
+	o.Result(key, value)
+	if o.testOnly {
+		return
+	}
+	o.sink.mu.Lock()
+	defer o.sink.mu.Unlock()
+	if o.sink.format == TextFormat {
+		fmt.Fprintln(o.sink.writer, value)
+	}
+}
+
+// Events returns what was written to an output from NewForTesting.
+func (o *Output) Events() []Event {
+	o.sink.mu.Lock()
+	defer o.sink.mu.Unlock()
+	return append([]Event{}, o.sink.events...)
+}
+
+// Results returns the fields of the final result object so far.
+func (o *Output) Results() map[string]interface{} {
+	o.sink.mu.Lock()
+	defer o.sink.mu.Unlock()
+	results := make(map[string]interface{})
+	for k, v := range o.sink.result {
+		results[k] = v
+	}
+	return results
+}
+
+// Finish writes the final result object of the command, in the JSON formats,
+// and closes the log file.
+func (o *Output) Finish(command string, err error) {
+	o.sink.mu.Lock()
+	defer o.sink.mu.Unlock()
+	if o.sink.logFile != nil {
+		status := "succeeded"
+		if err != nil {
+			status = "failed: " + err.Error()
+		}
+		o.log("result", fmt.Sprintf("%s %s", command, status))
+		o.sink.logFile.Close()
+		o.sink.logFile = nil
+	}
+	if o.testOnly || o.sink.format == TextFormat {
+		return
+	}
+	result := Result{
+		Time:    time.Now(),
+		Level:   "result",
+		Command: command,
+		Success: err == nil,
+		Result:  o.sink.result,
+	}
+	if err != nil {
+		result.Error = err.Error()
+	}
+	encoder := json.NewEncoder(o.sink.writer)
+	if o.sink.format == JsonFormat {
+		encoder.SetIndent("", "  ")
+		encoder.Encode(struct {
+			Events []Event
+			Result Result
+		}{o.sink.events, result})
+		return
+	}
+	encoder.Encode(result)
+}
+
+func (o *Output) print(level string, colorPrint func(format string, a ...interface{}), format string, a ...interface{}) {
+	o.sink.mu.Lock()
+	defer o.sink.mu.Unlock()
+	if o.testOnly {
+		o.sink.events = append(o.sink.events, Event{
+			Level:   level,
+			Depth:   o.indentation,
+			Task:    o.task,
+			Message: o.sprintf(format, a...),
+		})
+		return
+	}
+	if o.sink.logFile != nil {
+		o.log(level, o.indentFmt(format, a...))
+	}
+	if severities[level] < o.sink.level {
+		return
+	}
+	if o.sink.format == TextFormat {
+		colorPrint("%s", o.indentFmt(format, a...))
+		return
+	}
+	event := Event{
+		Time:    time.Now(),
+		Level:   level,
+		Depth:   o.indentation,
+		Task:    o.task,
+		Message: o.sprintf(format, a...),
+		Fields:  fields(a),
+	}
+	if o.sink.format == JsonFormat {
+		o.sink.events = append(o.sink.events, event)
+	} else {
+		json.NewEncoder(o.sink.writer).Encode(event)
+	}
+}
+
+// log writes a line to the log file, which the caller must have locked.
+func (o *Output) log(level string, line string) {
+	fmt.Fprintf(o.sink.logFile, "%s %-7s %s\n", time.Now().UTC().Format(time.RFC3339Nano), strings.ToUpper(level), line)
+}
+
 func (o *Output) indentFmt(format string, a ...interface{}) string {
 	indent := ""
 	for i := 0; i < o.indentation; i++ {
 		indent = indent + "  "
 	}
+	prefix := ""
+	if o.task != "" {
+		prefix = "[" + o.task + "] "
+	}
+	return prefix + indent + o.sprintf(format, a...)
+}
+
+func (o *Output) sprintf(format string, a ...interface{}) string {
 	if len(a) > 0 {
-		return indent + fmt.Sprintf(format, a...)
+		return fmt.Sprintf(format, a...)
+	}
+	return format
+}
+
+// fields makes the formatted values safe to encode as JSON.
+func fields(a []interface{}) []interface{} {
+	if len(a) == 0 {
+		return nil
+	}
+	values := make([]interface{}, len(a))
+	for i, v := range a {
+		if err, ok := v.(error); ok {
+			values[i] = err.Error()
+		} else if _, marshalErr := json.Marshal(v); marshalErr != nil {
+			values[i] = fmt.Sprint(v)
+		} else {
+			values[i] = v
+		}
 	}
-	return indent + format
+	return values
 }
@@ -0,0 +1,200 @@
+package output
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"github.com/fatih/color"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// newBuffered returns an output in the format that writes to a buffer.
+func newBuffered(t *testing.T, format string) (*Output, *bytes.Buffer) {
+	t.Helper()
+	o, err := NewWithFormat(format)
+	if err != nil {
+		t.Fatal(err)
+	}
+	buffer := &bytes.Buffer{}
+	o.sink.writer = buffer
+	return o, buffer
+}
+
+// writeCommand writes what a typical command would, including from a task.
+func writeCommand(o *Output) {
+	o.Info("Pushing %s", "P").Indent()
+	o.Task("A").Success("Pushed %s", "P-A")
+	o.Debug("Hidden at the default level")
+	o.Result("Arn", "arn:P-A")
+	o.Dedent().Done()
+	o.Finish("push_project", errors.New("1 of 2 lambdas failed to push"))
+}
+
+func checkEvents(t *testing.T, events []Event) {
+	t.Helper()
+	if len(events) != 3 {
+		t.Fatalf("got %d events, want 3: %+v", len(events), events)
+	}
+	pushing, pushed := events[0], events[1]
+	if pushing.Level != "info" || pushing.Message != "Pushing P" || pushing.Depth != 0 || len(pushing.Fields) != 1 || pushing.Fields[0] != "P" {
+		t.Errorf("first event = %+v", pushing)
+	}
+	if pushed.Level != "success" || pushed.Message != "Pushed P-A" || pushed.Depth != 1 || pushed.Task != "A" || pushed.Time.IsZero() {
+		t.Errorf("second event = %+v", pushed)
+	}
+}
+
+func checkResult(t *testing.T, result Result) {
+	t.Helper()
+	if result.Level != "result" || result.Command != "push_project" || result.Success || result.Error != "1 of 2 lambdas failed to push" {
+		t.Errorf("result = %+v", result)
+	}
+	if result.Result["Arn"] != "arn:P-A" {
+		t.Errorf("result fields = %v", result.Result)
+	}
+}
+
+func TestJsonFormat(t *testing.T) {
+	o, buffer := newBuffered(t, JsonFormat)
+	o.Info("Nothing is written until Finish")
+	if buffer.Len() != 0 {
+		t.Fatalf("wrote %q before Finish", buffer.String())
+	}
+	o, buffer = newBuffered(t, JsonFormat)
+	writeCommand(o)
+
+	written := struct {
+		Events []Event
+		Result Result
+	}{}
+	decoder := json.NewDecoder(buffer)
+	if err := decoder.Decode(&written); err != nil {
+		t.Fatal(err)
+	}
+	if decoder.More() {
+		t.Error("wrote more than one JSON object")
+	}
+	checkEvents(t, written.Events)
+	checkResult(t, written.Result)
+}
+
+func TestNdjsonFormat(t *testing.T) {
+	o, buffer := newBuffered(t, NdjsonFormat)
+	writeCommand(o)
+
+	lines := make([][]byte, 0)
+	scanner := bufio.NewScanner(buffer)
+	for scanner.Scan() {
+		lines = append(lines, append([]byte{}, scanner.Bytes()...))
+	}
+	if len(lines) != 4 {
+		t.Fatalf("wrote %d lines, want 3 events and a result", len(lines))
+	}
+	events := make([]Event, 3)
+	for i := range events {
+		if err := json.Unmarshal(lines[i], &events[i]); err != nil {
+			t.Fatalf("line %d: %v", i, err)
+		}
+	}
+	checkEvents(t, events)
+	result := Result{}
+	if err := json.Unmarshal(lines[3], &result); err != nil {
+		t.Fatal(err)
+	}
+	checkResult(t, result)
+}
+
+func TestSuccessfulResult(t *testing.T) {
+	o, buffer := newBuffered(t, NdjsonFormat)
+	o.Finish("list_project", nil)
+	result := Result{}
+	if err := json.Unmarshal(buffer.Bytes(), &result); err != nil {
+		t.Fatal(err)
+	}
+	if !result.Success || result.Error != "" {
+		t.Errorf("result = %+v, want success", result)
+	}
+}
+
+func TestUnknownFormat(t *testing.T) {
+	if _, err := NewWithFormat("yaml"); err == nil {
+		t.Error("accepted --output=yaml")
+	}
+}
+
+// captureText redirects the text format, which prints through color, to a
+// buffer for the rest of the test.
+func captureText(t *testing.T) *bytes.Buffer {
+	t.Helper()
+	buffer := &bytes.Buffer{}
+	stdout, noColor := color.Output, color.NoColor
+	color.Output = buffer
+	t.Cleanup(func() {
+		color.Output, color.NoColor = stdout, noColor
+	})
+	return buffer
+}
+
+func TestQuietStillLogsEverything(t *testing.T) {
+	text := captureText(t)
+	logFile := filepath.Join(t.TempDir(), "ecology.log")
+	o := New()
+	o.SetLevel(WarnLevel)
+	if err := o.SetLogFile(logFile); err != nil {
+		t.Fatal(err)
+	}
+	o.Info("Pushing P").Indent()
+	o.Debug("go build")
+	o.Warning("Couldn't cache the build")
+	o.Dedent().Done()
+	o.Finish("push_project", nil)
+
+	if printed := text.String(); strings.Contains(printed, "Pushing P") || strings.Contains(printed, "Done.") || !strings.Contains(printed, "Couldn't cache the build") {
+		t.Errorf("--quiet printed %q, want only the warning", printed)
+	}
+	data, err := ioutil.ReadFile(logFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	logged := string(data)
+	for _, line := range []string{"INFO    Pushing P", "DEBUG     go build", "WARNING   Couldn't cache the build", "RESULT  push_project succeeded"} {
+		if !strings.Contains(logged, line) {
+			t.Errorf("log file is missing %q:\n%s", line, logged)
+		}
+	}
+}
+
+func TestVerbosePrintsDebugLines(t *testing.T) {
+	text := captureText(t)
+	o := New()
+	o.Debug("go build")
+	if text.Len() != 0 {
+		t.Errorf("printed %q by default", text.String())
+	}
+	o.SetLevel(DebugLevel)
+	o.Debug("go build")
+	if !strings.Contains(text.String(), "go build") {
+		t.Error("--verbose didn't print the debug line")
+	}
+}
+
+func TestNoColor(t *testing.T) {
+	text := captureText(t)
+	color.NoColor = false
+	New().Success("Pushed")
+	if !strings.Contains(text.String(), "\x1b[") {
+		t.Fatalf("printed %q, want it colored before NO_COLOR is set", text.String())
+	}
+
+	text.Reset()
+	t.Setenv("NO_COLOR", "1")
+	DisableColorUnlessTerminal()
+	New().Success("Pushed")
+	if text.String() != "Pushed\n" {
+		t.Errorf("printed %q with NO_COLOR set, want no color", text.String())
+	}
+}
@@ -0,0 +1,25 @@
+package parallel
+
+// Run calls work once for each of count tasks, with at most parallelism of
+// them running at once. Every task is run, even once some have failed, and
+// each task's error is returned at its index.
+func Run(count int, parallelism int, work func(i int) error) []error {
+	if parallelism < 1 {
+		parallelism = 1
+	}
+	errs := make([]error, count)
+	slots := make(chan bool, parallelism)
+	done := make(chan bool)
+	for i := 0; i < count; i++ {
+		slots <- true
+		go func(i int) {
+			errs[i] = work(i)
+			<-slots
+			done <- true
+		}(i)
+	}
+	for i := 0; i < count; i++ {
+		<-done
+	}
+	return errs
+}
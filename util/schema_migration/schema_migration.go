@@ -0,0 +1,80 @@
+package schema_migration
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Document is a manifest decoded as plain JSON, so that migrations can reshape
+// it without depending on the current structs.
+type Document map[string]interface{}
+
+type Migration struct {
+	Description string
+	Apply       func(doc Document) error
+}
+
+// Migrations are applied in order, Migrations[i] upgrading a manifest from
+// schema version i to i+1. Manifests written before versioning are version 0.
+type Migrations []Migration
+
+// Current is the schema version that manifests end up at after every
+// migration has been applied.
+func (ms Migrations) Current() int {
+	return len(ms)
+}
+
+// Upgrade applies every migration that the document needs, and stamps it
+// with the current schema version.
+func (ms Migrations) Upgrade(kind string, doc Document) error {
+	version := Version(doc)
+	if version > ms.Current() {
+		return errors.New(fmt.Sprintf("%s manifest has schema version %d, but this ecology only understands up to %d, upgrade ecology",
+			kind, version, ms.Current()))
+	}
+	for ; version < ms.Current(); version++ {
+		if err := ms[version].Apply(doc); err != nil {
+			return errors.New(fmt.Sprintf("Couldn't migrate %s manifest from schema version %d (%s): %v",
+				kind, version, ms[version].Description, err))
+		}
+	}
+	doc["SchemaVersion"] = ms.Current()
+	return nil
+}
+
+// Version returns the schema version recorded in the document.
+func Version(doc Document) int {
+	if v, ok := doc["SchemaVersion"].(float64); ok {
+		return int(v)
+	}
+	if v, ok := doc["SchemaVersion"].(int); ok {
+		return v
+	}
+	return 0
+}
+
+// Child returns the nested manifest under the key, or nil.
+func Child(doc Document, key string) Document {
+	if child, ok := doc[key].(map[string]interface{}); ok {
+		return child
+	}
+	return nil
+}
+
+// Children returns the nested manifests in the list under the key.
+func Children(doc Document, key string) (children []Document) {
+	list, _ := doc[key].([]interface{})
+	for _, item := range list {
+		if child, ok := item.(map[string]interface{}); ok {
+			children = append(children, child)
+		}
+	}
+	return
+}
+
+// Unversioned is the first migration of every manifest, from before schema
+// versions were recorded. Those manifests need no changes.
+var Unversioned = Migration{
+	Description: "record the schema version",
+	Apply:       func(doc Document) error { return nil },
+}
@@ -0,0 +1,262 @@
+package secret_store
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/gbdubs/ecology/util/atomic_file"
+	"io/ioutil"
+	"os"
+	"regexp"
+	"sort"
+	"sync"
+)
+
+// PassphraseVariable holds the passphrase that secrets are encrypted with.
+const PassphraseVariable = "ECOLOGY_SECRETS_PASSPHRASE"
+
+const formatVersion = 1
+const iterations = 600000
+
+var nameRegex = regexp.MustCompile("^[a-zA-Z0-9_-]+$")
+
+// storeFile is how secrets are kept on disk. Names are in the clear, so that
+// secrets can be listed and removed without the passphrase. Each value is
+// sealed with AES-256-GCM, bound to its name, under a key derived from the
+// passphrase with PBKDF2.
+type storeFile struct {
+	Version    int
+	Iterations int
+	Salt       string
+	Secrets    map[string]string
+}
+
+// Store is a file of encrypted secrets, such as API keys, that is safe to
+// commit alongside the project.
+type Store struct {
+	path string
+	file storeFile
+	// Deriving the key is slow on purpose, so it's done once per passphrase.
+	aead           cipher.AEAD
+	aeadPassphrase string
+}
+
+// Open reads the store at path, or starts an empty one if there isn't one.
+func Open(path string) (*Store, error) {
+	s := &Store{path: path}
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		salt := make([]byte, 16)
+		if _, err = rand.Read(salt); err != nil {
+			return nil, err
+		}
+		s.file = storeFile{
+			Version:    formatVersion,
+			Iterations: iterations,
+			Salt:       base64.StdEncoding.EncodeToString(salt),
+			Secrets:    make(map[string]string),
+		}
+		return s, nil
+	} else if err != nil {
+		return nil, err
+	}
+	if err = json.Unmarshal(data, &s.file); err != nil {
+		return nil, errors.New(fmt.Sprintf("Couldn't read secrets file %s: %v", path, err))
+	}
+	if s.file.Version > formatVersion {
+		return nil, errors.New(fmt.Sprintf("Secrets file %s has version %d, but this ecology only understands up to %d, upgrade ecology", path, s.file.Version, formatVersion))
+	}
+	if s.file.Secrets == nil {
+		s.file.Secrets = make(map[string]string)
+	}
+	return s, nil
+}
+
+// Passphrase reads the passphrase from the environment.
+func Passphrase() (string, error) {
+	passphrase := os.Getenv(PassphraseVariable)
+	if passphrase == "" {
+		return "", errors.New(fmt.Sprintf("Set %s to the passphrase that the project's secrets are encrypted with", PassphraseVariable))
+	}
+	return passphrase, nil
+}
+
+func ValidateName(name string) error {
+	if !nameRegex.MatchString(name) {
+		return errors.New(fmt.Sprintf("Secret name %q should be letters, digits, dashes and underscores", name))
+	}
+	return nil
+}
+
+func (s *Store) Names() []string {
+	names := make([]string, 0, len(s.file.Secrets))
+	for name := range s.file.Secrets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (s *Store) Has(name string) bool {
+	_, ok := s.file.Secrets[name]
+	return ok
+}
+
+// Fingerprint changes whenever the secret is set, even to the same value,
+// without revealing anything about the value.
+func (s *Store) Fingerprint(name string) (string, error) {
+	sealed, ok := s.file.Secrets[name]
+	if !ok {
+		return "", s.notFound(name)
+	}
+	sum := sha256.Sum256([]byte(sealed))
+	return hex.EncodeToString(sum[:]), nil
+}
+
+func (s *Store) Get(name string, passphrase string) (value string, err error) {
+	sealed, ok := s.file.Secrets[name]
+	if !ok {
+		return "", s.notFound(name)
+	}
+	aead, err := s.cipher(passphrase)
+	if err != nil {
+		return
+	}
+	data, err := base64.StdEncoding.DecodeString(sealed)
+	if err != nil || len(data) < aead.NonceSize() {
+		return "", errors.New(fmt.Sprintf("Secret %s in %s is corrupt", name, s.path))
+	}
+	plain, err := aead.Open(nil, data[:aead.NonceSize()], data[aead.NonceSize():], []byte(name))
+	if err != nil {
+		return "", errors.New(fmt.Sprintf("Couldn't decrypt secret %s, is %s right?", name, PassphraseVariable))
+	}
+	return string(plain), nil
+}
+
+// Set encrypts the value under the name. Every secret in a store must share
+// a passphrase, so it fails if the passphrase can't decrypt the others.
+func (s *Store) Set(name string, value string, passphrase string) (err error) {
+	if err = ValidateName(name); err != nil {
+		return
+	}
+	if names := s.Names(); len(names) > 0 {
+		if _, err = s.Get(names[0], passphrase); err != nil {
+			return
+		}
+	}
+	aead, err := s.cipher(passphrase)
+	if err != nil {
+		return
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err = rand.Read(nonce); err != nil {
+		return
+	}
+	sealed := aead.Seal(nonce, nonce, []byte(value), []byte(name))
+	s.file.Secrets[name] = base64.StdEncoding.EncodeToString(sealed)
+	return nil
+}
+
+func (s *Store) Remove(name string) error {
+	if !s.Has(name) {
+		return s.notFound(name)
+	}
+	delete(s.file.Secrets, name)
+	return nil
+}
+
+// Save writes the store, readable only by its owner.
+func (s *Store) Save() error {
+	data, err := json.MarshalIndent(s.file, "", "  ")
+	if err != nil {
+		return err
+	}
+	return atomic_file.WriteFile(s.path, data, 0600)
+}
+
+func (s *Store) cipher(passphrase string) (cipher.AEAD, error) {
+	if s.aead != nil && s.aeadPassphrase == passphrase {
+		return s.aead, nil
+	}
+	salt, err := base64.StdEncoding.DecodeString(s.file.Salt)
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("Secrets file %s has a corrupt salt", s.path))
+	}
+	key, err := pbkdf2.Key(sha256.New, passphrase, salt, s.file.Iterations, 32)
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	if s.aead, err = cipher.NewGCM(block); err != nil {
+		return nil, err
+	}
+	s.aeadPassphrase = passphrase
+	return s.aead, nil
+}
+
+func (s *Store) notFound(name string) error {
+	return errors.New(fmt.Sprintf("No secret named %s in %s", name, s.path))
+}
+
+// Resolver looks up secrets for pushes. It only reads the passphrase and
+// derives the key when a secret's value is first needed, so that pushes
+// and plans that don't need values work without it.
+type Resolver struct {
+	Path string
+
+	mu         sync.Mutex
+	store      *Store
+	passphrase string
+	values     map[string]string
+}
+
+func NewResolver(path string) *Resolver {
+	return &Resolver{Path: path, values: make(map[string]string)}
+}
+
+func (r *Resolver) open() (err error) {
+	if r.store == nil {
+		r.store, err = Open(r.Path)
+	}
+	return
+}
+
+func (r *Resolver) Fingerprint(name string) (string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if err := r.open(); err != nil {
+		return "", err
+	}
+	return r.store.Fingerprint(name)
+}
+
+func (r *Resolver) Value(name string) (value string, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if value, ok := r.values[name]; ok {
+		return value, nil
+	}
+	if err = r.open(); err != nil {
+		return
+	}
+	if r.passphrase == "" {
+		if r.passphrase, err = Passphrase(); err != nil {
+			return
+		}
+	}
+	if value, err = r.store.Get(name, r.passphrase); err != nil {
+		return
+	}
+	r.values[name] = value
+	return
+}